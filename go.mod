@@ -8,3 +8,5 @@ require (
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	github.com/pkg/errors v0.9.1
 )
+
+require github.com/godbus/dbus/v5 v5.1.0
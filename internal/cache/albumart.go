@@ -0,0 +1,39 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"path"
+	"sync"
+)
+
+// Album art cache singleton
+var albumArtCache *Cache
+var albumArtOnce sync.Once
+
+// GetAlbumArtCache returns a global album art Cache instance, or nil if it couldn't be initialised
+func GetAlbumArtCache() *Cache {
+	albumArtOnce.Do(func() {
+		var err error
+		dir := path.Join(glib.GetUserCacheDir(), "ymuse", "albumart")
+		if albumArtCache, err = New(dir, int64(config.GetConfig().AlbumArtCacheSize)*1024*1024); errCheck(err, "New() failed") {
+			albumArtCache = nil
+		}
+	})
+	return albumArtCache
+}
@@ -0,0 +1,287 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// indexFileName is the name of the file storing the cache index
+const indexFileName = "index.json"
+
+// indexSaveInterval is the minimum interval between two automatic index saves
+const indexSaveInterval = 5 * time.Second
+
+// dirEntry describes a picture associated with an album directory
+type dirEntry struct {
+	Hash  string // Hash of the original picture, empty if the album has no picture
+	Stale bool   // Whether the entry has been invalidated and must be refreshed
+}
+
+// fileEntry describes a cached image file
+type fileEntry struct {
+	Size     int64 // File size in bytes
+	Accessed int64 // Last access time, as a UNIX timestamp
+}
+
+// index stores the cache metadata
+type index struct {
+	Dirs  map[string]*dirEntry  // Album directory entries, keyed by directory
+	Files map[string]*fileEntry // Cached files, keyed by file name
+}
+
+// Cache is an on-disk store of resized album art images. Images are keyed by the album directory and the hash of the
+// original picture, so that albums sharing the same picture also share the cached images
+type Cache struct {
+	dir      string           // Directory where cached files are stored
+	maxSize  int64            // Maximum total size of cached files in bytes, 0 for unlimited
+	now      func() time.Time // Returns the current time, replaceable for testing
+	mutex    sync.Mutex       // Index access mutex
+	idx      index            // Cache index
+	dirty    bool             // Whether the index has unsaved changes
+	lastSave time.Time        // Time the index was last saved
+}
+
+// New creates and returns a new Cache instance that stores files in the given directory and keeps their total size
+// within maxSize bytes (0 means no limit)
+func New(dir string, maxSize int64) (*Cache, error) {
+	// Make sure the directory exists
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{dir: dir, maxSize: maxSize, now: time.Now}
+	c.loadIndex()
+	return c, nil
+}
+
+// Get returns the cached image for the given album directory and size. The second return value indicates whether the
+// cache has a valid entry for the directory; it's true with a nil image if the album is known to have no picture
+func (c *Cache) Get(albumDir string, size int) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Look up the directory
+	de, ok := c.idx.Dirs[albumDir]
+	if !ok || de.Stale {
+		return nil, false
+	}
+
+	// The album is known to have no picture
+	if de.Hash == "" {
+		return nil, true
+	}
+
+	// Read the image file
+	name := fileName(de.Hash, size)
+	fe, ok := c.idx.Files[name]
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path.Join(c.dir, name))
+	if errCheck(err, "ReadFile() failed") {
+		// Forget about the missing file
+		delete(c.idx.Files, name)
+		c.dirty = true
+		return nil, false
+	}
+
+	// Update the access time
+	fe.Accessed = c.now().Unix()
+	c.dirty = true
+	return data, true
+}
+
+// Put stores the given original picture for the album directory, resized to fit the given size, and returns the
+// resized image. A nil or empty picture marks the album as having no picture. If the picture cannot be decoded, it's
+// cached as is
+func (c *Cache) Put(albumDir string, size int, picture []byte) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer c.saveIndexIfDue()
+
+	// Album without a picture
+	if len(picture) == 0 {
+		c.idx.Dirs[albumDir] = &dirEntry{}
+		c.dirty = true
+		return nil, nil
+	}
+
+	// Update the directory entry
+	sum := sha256.Sum256(picture)
+	hash := hex.EncodeToString(sum[:16])
+	c.idx.Dirs[albumDir] = &dirEntry{Hash: hash}
+	c.dirty = true
+
+	// If the image of that size is already there, reuse it
+	name := fileName(hash, size)
+	file := path.Join(c.dir, name)
+	if fe, ok := c.idx.Files[name]; ok {
+		if data, err := os.ReadFile(file); err == nil {
+			fe.Accessed = c.now().Unix()
+			return data, nil
+		}
+	}
+
+	// Resize the image, falling back to the original data
+	data, err := resizeImage(picture, size)
+	if errCheck(err, "resizeImage() failed") {
+		data = picture
+	}
+
+	// Write the file out
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return data, err
+	}
+	c.idx.Files[name] = &fileEntry{Size: int64(len(data)), Accessed: c.now().Unix()}
+
+	// Enforce the size limit
+	c.evict()
+	return data, nil
+}
+
+// Invalidate marks all album directory entries as stale, so that their pictures get refreshed on next access. Cached
+// files are retained and reused if the pictures turn out unchanged
+func (c *Cache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, de := range c.idx.Dirs {
+		de.Stale = true
+	}
+	c.dirty = true
+}
+
+// Flush saves the cache index if it has unsaved changes
+func (c *Cache) Flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.dirty {
+		c.saveIndex()
+	}
+}
+
+// Size returns the total size of the cached files in bytes
+func (c *Cache) Size() (size int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, fe := range c.idx.Files {
+		size += fe.Size
+	}
+	return
+}
+
+// evict removes least recently used files until the total size fits into the limit. Must be called with the mutex
+// locked
+func (c *Cache) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+
+	// Calculate the total size
+	var total int64
+	names := make([]string, 0, len(c.idx.Files))
+	for name, fe := range c.idx.Files {
+		total += fe.Size
+		names = append(names, name)
+	}
+	if total <= c.maxSize {
+		return
+	}
+
+	// Sort the files by access time, oldest first
+	sort.Slice(names, func(i, j int) bool {
+		fi, fj := c.idx.Files[names[i]], c.idx.Files[names[j]]
+		if fi.Accessed != fj.Accessed {
+			return fi.Accessed < fj.Accessed
+		}
+		return names[i] < names[j]
+	})
+
+	// Remove files until the limit is met
+	for _, name := range names {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.Remove(path.Join(c.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errCheck(err, "Remove() failed")
+			continue
+		}
+		total -= c.idx.Files[name].Size
+		delete(c.idx.Files, name)
+		c.dirty = true
+	}
+}
+
+// loadIndex reads the cache index from its file, dropping entries for missing files
+func (c *Cache) loadIndex() {
+	c.idx = index{Dirs: make(map[string]*dirEntry), Files: make(map[string]*fileEntry)}
+	data, err := os.ReadFile(path.Join(c.dir, indexFileName))
+	if errors.Is(err, os.ErrNotExist) || errCheck(err, "Couldn't read cache index") {
+		return
+	}
+	var idx index
+	if errCheck(json.Unmarshal(data, &idx), "json.Unmarshal() failed") {
+		return
+	}
+
+	// Only keep the files that actually exist
+	for name, fe := range idx.Files {
+		if fe != nil {
+			if _, err := os.Stat(path.Join(c.dir, name)); err == nil {
+				c.idx.Files[name] = fe
+			}
+		}
+	}
+	for dir, de := range idx.Dirs {
+		if de != nil {
+			c.idx.Dirs[dir] = de
+		}
+	}
+}
+
+// saveIndex writes out the cache index. Must be called with the mutex locked
+func (c *Cache) saveIndex() {
+	data, err := json.Marshal(&c.idx)
+	if errCheck(err, "json.Marshal() failed") {
+		return
+	}
+	if !errCheck(os.WriteFile(path.Join(c.dir, indexFileName), data, 0644), "WriteFile() failed") {
+		c.dirty = false
+		c.lastSave = c.now()
+	}
+}
+
+// saveIndexIfDue saves the index if it has unsaved changes and wasn't saved recently. Must be called with the mutex
+// locked
+func (c *Cache) saveIndexIfDue() {
+	if c.dirty && c.now().Sub(c.lastSave) >= indexSaveInterval {
+		c.saveIndex()
+	}
+}
+
+// fileName returns the name of the cached file for the given picture hash and size
+func fileName(hash string, size int) string {
+	return fmt.Sprintf("%s-%d.png", hash, size)
+}
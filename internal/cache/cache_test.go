@@ -0,0 +1,260 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// newTestPicture returns a PNG image of the given dimensions filled with the given colour
+func newTestPicture(t *testing.T, w, h int, c color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestCache returns a cache in a temporary directory, using a fake clock
+func newTestCache(t *testing.T, maxSize int64) (*Cache, *time.Time) {
+	c, err := New(t.TempDir(), maxSize)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func Test_resizeImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{"square down", 400, 400, 100, 100, 100},
+		{"landscape down", 400, 200, 100, 100, 50},
+		{"portrait down", 200, 400, 100, 50, 100},
+		{"small kept", 60, 40, 100, 60, 40},
+		{"thin strip", 1000, 2, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resizeImage(newTestPicture(t, tt.width, tt.height, color.NRGBA{R: 200, G: 100, B: 50, A: 255}), tt.size)
+			if err != nil {
+				t.Fatalf("resizeImage() error = %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("png.Decode() error = %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("resizeImage() dimensions = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+
+			// The colour must be preserved
+			if r, g, b, a := img.At(0, 0).RGBA(); r>>8 != 200 || g>>8 != 100 || b>>8 != 50 || a>>8 != 255 {
+				t.Errorf("resizeImage() colour = (%d, %d, %d, %d), want (200, 100, 50, 255)", r>>8, g>>8, b>>8, a>>8)
+			}
+		})
+	}
+}
+
+func Test_resizeImage_invalid(t *testing.T) {
+	if _, err := resizeImage([]byte("not an image"), 100); err == nil {
+		t.Errorf("resizeImage() error = nil, want non-nil")
+	}
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	pic := newTestPicture(t, 300, 300, color.White)
+
+	// Initially empty
+	if _, ok := c.Get("Artist/Album", 100); ok {
+		t.Errorf("Get() ok = true for an empty cache, want false")
+	}
+
+	// Store a picture
+	data, err := c.Put("Artist/Album", 100, pic)
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if bytes.Equal(data, pic) {
+		t.Errorf("Put() returned the original picture, want a resized one")
+	}
+
+	// Fetch it back
+	got, ok := c.Get("Artist/Album", 100)
+	if !ok || !bytes.Equal(got, data) {
+		t.Errorf("Get() = (%d bytes, %v), want (%d bytes, true)", len(got), ok, len(data))
+	}
+
+	// Other sizes aren't there
+	if _, ok := c.Get("Artist/Album", 200); ok {
+		t.Errorf("Get() ok = true for a different size, want false")
+	}
+
+	// Another directory with the same picture shares the file
+	if _, err := c.Put("Artist/Album (Deluxe)", 100, pic); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if n := len(c.idx.Files); n != 1 {
+		t.Errorf("number of cached files = %d, want 1", n)
+	}
+}
+
+func TestCache_NoPicture(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	if _, err := c.Put("Artist/Album", 100, nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, ok := c.Get("Artist/Album", 100); !ok || got != nil {
+		t.Errorf("Get() = (%v, %v), want (nil, true)", got, ok)
+	}
+}
+
+func TestCache_UndecodablePicture(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	pic := []byte("some unsupported image format")
+	data, err := c.Put("Artist/Album", 100, pic)
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !bytes.Equal(data, pic) {
+		t.Errorf("Put() = %q, want the original data", data)
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	pic := newTestPicture(t, 300, 300, color.White)
+	if _, err := c.Put("Artist/Album", 100, pic); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// After invalidation the entry must be refreshed
+	c.Invalidate()
+	if _, ok := c.Get("Artist/Album", 100); ok {
+		t.Errorf("Get() ok = true after Invalidate(), want false")
+	}
+
+	// The file is retained, and storing the same picture makes the entry valid again
+	if n := len(c.idx.Files); n != 1 {
+		t.Errorf("number of cached files = %d, want 1", n)
+	}
+	if _, err := c.Put("Artist/Album", 100, pic); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := c.Get("Artist/Album", 100); !ok {
+		t.Errorf("Get() ok = false after Put(), want true")
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	c, now := newTestCache(t, 0)
+	pics := []struct {
+		dir  string
+		data []byte
+	}{
+		{"A", newTestPicture(t, 300, 300, color.White)},
+		{"B", newTestPicture(t, 300, 300, color.Black)},
+		{"C", newTestPicture(t, 300, 300, color.NRGBA{R: 255, A: 255})},
+	}
+
+	// Store all pictures, one second apart
+	sizes := make(map[string]int64)
+	for _, p := range pics {
+		*now = now.Add(time.Second)
+		data, err := c.Put(p.dir, 100, p.data)
+		if err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		sizes[p.dir] = int64(len(data))
+	}
+
+	// Access A, so that B becomes the least recently used
+	*now = now.Add(time.Second)
+	if _, ok := c.Get("A", 100); !ok {
+		t.Fatalf("Get() ok = false, want true")
+	}
+
+	// Limit the size to fit A and a fourth picture, then add the latter: B and C must be evicted
+	picD := newTestPicture(t, 300, 300, color.NRGBA{G: 255, A: 255})
+	resizedD, err := resizeImage(picD, 100)
+	if err != nil {
+		t.Fatalf("resizeImage() error = %v", err)
+	}
+	c.maxSize = sizes["A"] + int64(len(resizedD))
+	*now = now.Add(time.Second)
+	if _, err := c.Put("D", 100, picD); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	for dir, want := range map[string]bool{"A": true, "B": false, "C": false, "D": true} {
+		if _, ok := c.Get(dir, 100); ok != want {
+			t.Errorf("Get(%q) ok = %v, want %v", dir, ok, want)
+		}
+	}
+	if got := c.Size(); got > c.maxSize {
+		t.Errorf("Size() = %d, want <= %d", got, c.maxSize)
+	}
+}
+
+func TestCache_Persistence(t *testing.T) {
+	c, _ := newTestCache(t, 0)
+	data, err := c.Put("Artist/Album", 100, newTestPicture(t, 300, 300, color.White))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	c.Flush()
+
+	// Reopen the cache
+	c2, err := New(c.dir, 0)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if got, ok := c2.Get("Artist/Album", 100); !ok || !bytes.Equal(got, data) {
+		t.Errorf("Get() after reopening = (%d bytes, %v), want (%d bytes, true)", len(got), ok, len(data))
+	}
+
+	// Entries for removed files are dropped
+	for name := range c2.idx.Files {
+		if err := os.Remove(path.Join(c.dir, name)); err != nil {
+			t.Fatalf("Remove() failed: %v", err)
+		}
+	}
+	c3, err := New(c.dir, 0)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, ok := c3.Get("Artist/Album", 100); ok {
+		t.Errorf("Get() ok = true for a removed file, want false")
+	}
+}
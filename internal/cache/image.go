@@ -0,0 +1,81 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+)
+
+// resizeImage decodes the given image data, scales it down (keeping the aspect ratio) so that it fits into a square of
+// the given size, and returns it encoded as PNG. Images already fitting into the square aren't scaled
+func resizeImage(data []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the target dimensions
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > size || sh > size {
+		if sw >= sh {
+			dw, dh = size, max(sh*size/sw, 1)
+		} else {
+			dw, dh = max(sw*size/sh, 1), size
+		}
+	}
+
+	// Scale the image and encode it
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleBox(src, dw, dh)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleBox scales the image down to the given dimensions by averaging the source pixels covered by each target pixel
+func scaleBox(src image.Image, dw, dh int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*sh/dh, b.Min.Y+max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*sw/dw, b.Min.X+max((x+1)*sw/dw, x*sw/dw+1)
+
+			// Sum up premultiplied components of the covered area
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// Store the average, which is converted to non-premultiplied colour by the destination image
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
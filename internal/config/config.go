@@ -16,6 +16,7 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ import (
 	"github.com/yktoo/ymuse/internal/util"
 	"os"
 	"path"
+	"path/filepath"
 	"sync"
 )
 
@@ -69,39 +71,140 @@ type ColumnSpec struct {
 
 // StreamSpec describes settings for an Internet stream
 type StreamSpec struct {
-	Name string // Stream name
-	URI  string // Stream URI
+	Name      string   // Stream name
+	URI       string   // Stream URI
+	Group     string   // Name of the group the stream is displayed in, empty if none
+	Tags      []string // Tags the stream can be found by
+	Logo      string   // Path to a local image file used as the stream's logo, empty if none
+	Favourite bool     // Whether the stream is pinned on top of the list
 }
 
+// SmartFolderSpec describes a saved library search
+type SmartFolderSpec struct {
+	Name    string   // Smart folder name
+	Filters []string // MPD filter expressions whose results are combined
+}
+
+// BookmarkSpec describes a named library location
+type BookmarkSpec struct {
+	Name string // Bookmark name
+	Path string // Marshalled library path
+}
+
+// Album grouping rules, which determine what tells two albums apart
+const (
+	AlbumGroupingArtist = "artist" // Album artist (falling back to artist) and album title
+	AlbumGroupingDate   = "date"   // Album artist (falling back to artist), album title and date
+	AlbumGroupingMBID   = "mbid"   // MusicBrainz album ID, if available, otherwise same as AlbumGroupingArtist
+)
+
+// Scrobbling services
+const (
+	ScrobblerServiceNone           = ""               // Scrobbling is disabled
+	ScrobblerServiceListenBrainz   = "listenbrainz"   // ListenBrainz-compatible API
+	ScrobblerServiceAudioscrobbler = "audioscrobbler" // Audioscrobbler 2.0 API (Last.fm, Libre.fm)
+)
+
+// Periods of the listening statistics, matching playhistory.Period* values
+const (
+	HistoryStatsPeriodToday = "today" // Since midnight
+	HistoryStatsPeriodWeek  = "week"  // Last 7 days
+	HistoryStatsPeriodMonth = "month" // Last 30 days
+	HistoryStatsPeriodYear  = "year"  // Last 365 days
+	HistoryStatsPeriodAll   = "all"   // Entire history
+)
+
+// Groupings of the listening statistics, matching playhistory.Category* values
+const (
+	HistoryStatsCategoryArtist = "artist" // By artist
+	HistoryStatsCategoryAlbum  = "album"  // By album
+	HistoryStatsCategoryGenre  = "genre"  // By genre
+)
+
+// Sleep timer modes, matching sleep.Mode* values
+const (
+	SleepModeMinutes  = "minutes" // After a number of minutes
+	SleepModeTrackEnd = "track"   // At the end of the current track
+	SleepModeTracks   = "tracks"  // After a number of tracks, the current one included
+)
+
 // Config represents (storable) application configuration
 type Config struct {
-	MpdNetwork             string       // Network to use to connect to MPD, either 'tcp' or 'unix'
-	MpdSocketPath          string       // Path to the MPD's Unix socket (only if MpdNetwork == 'unix')
-	MpdHost                string       // MPD's IP address or hostname (only if MpdNetwork == 'tcp')
-	MpdPort                int          // MPD's port number (only if MpdNetwork == 'tcp')
-	MpdPassword            string       // MPD's password (optional)
-	MpdAutoConnect         bool         // Whether to automatically connect to MPD on startup
-	MpdAutoReconnect       bool         // Whether to automatically reconnect to MPD after connection is lost
-	QueueColumns           []ColumnSpec // Displayed queue columns
-	QueueToolbar           bool         // Whether the queue toolbar is visible
-	DefaultSortAttrID      int          // ID of MPD attribute used as a default for queue sorting
-	TrackDefaultReplace    bool         // Whether the default action for double-clicking a track is replace rather than append
-	PlaylistDefaultReplace bool         // Whether the default action for double-clicking a playlist is replace rather than append
-	StreamDefaultReplace   bool         // Whether the default action for double-clicking a stream is replace rather than append
-	PlayerSeekDuration     int          // Number of seconds to seek back/forward at a time, while playing
-	PlayerTitleTemplate    string       // Track's title formatting template for the player
-	PlayerAlbumArtTracks   bool         // Whether to display the current track's album art in the player
-	PlayerAlbumArtStreams  bool         // Whether to display the current stream's album art in the player
-	PlayerAlbumArtSize     int          // Size of the album art image in the player, in pixels
-	SwitchToOnQueueReplace bool         // Whether to switch to the Queue tab after the queue has been replaced
-	PlayOnQueueReplace     bool         // Whether to start playback after the queue has been replaced
-	MaxSearchResults       int          // Maximum number of displayed search results
-	Streams                []StreamSpec // Registered stream specifications
-	LibraryPath            string       // Last selected library path
+	Version                int               // Config schema version
+	MpdNetwork             string            // Network to use to connect to MPD, either 'tcp' or 'unix'
+	MpdSocketPath          string            // Path to the MPD's Unix socket (only if MpdNetwork == 'unix')
+	MpdHost                string            // MPD's IP address or hostname (only if MpdNetwork == 'tcp')
+	MpdPort                int               // MPD's port number (only if MpdNetwork == 'tcp')
+	MpdPassword            string            // MPD's password (optional), only used if it can't be stored in the keyring
+	MpdAutoConnect         bool              // Whether to automatically connect to MPD on startup
+	MpdAutoReconnect       bool              // Whether to automatically reconnect to MPD after connection is lost
+	QueueColumns           []ColumnSpec      // Displayed queue columns
+	QueueToolbar           bool              // Whether the queue toolbar is visible
+	DefaultSortAttrID      int               // ID of MPD attribute used as a default for queue sorting
+	TrackDefaultReplace    bool              // Whether the default action for double-clicking a track is replace rather than append
+	PlaylistDefaultReplace bool              // Whether the default action for double-clicking a playlist is replace rather than append
+	AlbumGrouping          string            // Rule telling albums apart, one of the AlbumGrouping* constants
+	StreamDefaultReplace   bool              // Whether the default action for double-clicking a stream is replace rather than append
+	TrayIcon               bool              // Whether to show an icon in the system tray, closing the window hiding it there
+	PlayerSeekDuration     int               // Number of seconds to seek back/forward at a time, while playing
+	PlayerTitleTemplate    string            // Track's title formatting template for the player
+	PlayerAlbumArtTracks   bool              // Whether to display the current track's album art in the player
+	PlayerAlbumArtStreams  bool              // Whether to display the current stream's album art in the player
+	PlayerAlbumArtSize     int               // Size of the album art image in the player, in pixels
+	PlayerLyrics           bool              // Whether the lyrics panel is shown
+	PlayerNotify           bool              // Whether to show a desktop notification when the track changes
+	PlayerNotifyTemplate   string            // Track's formatting template for the desktop notification
+	PlayerFadeOut          int               // Duration of the volume fade-out before pausing or stopping, in milliseconds, 0 for none
+	PlayerFadeIn           int               // Duration of the volume fade-in on resuming playback, in milliseconds, 0 for none
+	MiniPlayerTemplate     string            // Track's title formatting template for the mini player
+	AlbumArtCacheSize      int               // Maximum size of the on-disk album art cache, in megabytes
+	ScrobblerService       string            // Scrobbling service, one of the ScrobblerService* constants
+	ScrobblerURL           string            // Scrobbling service API URL, empty for the service's default
+	ScrobblerToken         string            // ListenBrainz user token or Audioscrobbler session key
+	ScrobblerAPIKey        string            // Audioscrobbler API key
+	ScrobblerAPISecret     string            // Audioscrobbler API shared secret
+	SwitchToOnQueueReplace bool              // Whether to switch to the Queue tab after the queue has been replaced
+	PlayOnQueueReplace     bool              // Whether to start playback after the queue has been replaced
+	MaxSearchResults       int               // Maximum number of displayed search results
+	Streams                []StreamSpec      // Registered stream specifications
+	StreamsCollapsedGroups map[string]bool   // Stream groups (by ID) displayed collapsed on the Streams page
+	StreamsHistory         bool              // Whether the stream title history panel is shown
+	HistoryStatsPeriod     string            // Period of the listening statistics, one of the HistoryStatsPeriod* constants
+	HistoryStatsCategory   string            // Grouping of the listening statistics, one of the HistoryStatsCategory* constants
+	SleepMode              string            // Sleep timer mode, one of the SleepMode* constants
+	SleepMinutes           int               // Number of minutes the sleep timer runs for in the minutes mode
+	SleepTracks            int               // Number of tracks the sleep timer runs for in the tracks mode
+	SleepPause             bool              // Whether the sleep timer pauses rather than stops the playback
+	SleepFade              bool              // Whether the sleep timer fades the volume out before stopping
+	SleepFadeDuration      int               // Duration of the sleep timer's fade-out, in seconds
+	AlarmEnabled           bool              // Whether the wake-up alarm is set
+	AlarmHour              int               // Hour the alarm goes off at
+	AlarmMinute            int               // Minute the alarm goes off at
+	AlarmSource            string            // What the alarm plays: "playlist:" followed by a playlist name, or "stream:" followed by a stream URI
+	AlarmFade              bool              // Whether the alarm fades the volume in
+	AlarmFadeDuration      int               // Duration of the alarm's fade-in, in seconds
+	SmartFolders           []SmartFolderSpec // Saved library searches, displayed as folders in the library root
+	LibraryPath            string            // Last selected library path
+	LibraryBookmarks       []BookmarkSpec    // Named library locations
+	LibraryLastVisit       int64             // UNIX time the application was last closed, used by recent library views
+	LibraryGridLevels      map[string]bool   // Library levels (by element prefix) displayed as a grid rather than a list
 
 	MainWindowDimensions Dimensions // Main window dimensions
+	MiniPlayerDimensions Dimensions // Mini player window dimensions
+
+	loadWarning string // Message describing a failure to load the settings, to be shown to the user
+	readOnly    bool   // Whether saving is disabled so that a config file that failed to load isn't overwritten
+
+	fileData []byte                     // Content of the config file as of the last load or save
+	base     map[string]json.RawMessage // Encoded settings as of the last load or save, used for merging changes
 }
 
+// Suffixes appended to the config file name
+const (
+	backupSuffix  = ".bak"     // Backup copy of the config file as of the previous save
+	damagedSuffix = ".damaged" // Config file that failed to load
+)
+
 // Config singleton with all settings
 var config *Config
 var once sync.Once
@@ -114,6 +217,8 @@ func GetConfig() *Config {
 		config = newConfig()
 		// Load the config from the default file, if any
 		config.Load()
+		// Keep the MPD password out of the file, if possible
+		config.migrateMpdPassword()
 	})
 	return config
 }
@@ -121,6 +226,7 @@ func GetConfig() *Config {
 // newConfig initialises and returns a config instance with all the defaults
 func newConfig() *Config {
 	return &Config{
+		Version:          currentVersion,
 		MpdNetwork:       "tcp",
 		MpdSocketPath:    os.Getenv("XDG_RUNTIME_DIR") + "/mpd/socket",
 		MpdHost:          os.Getenv("MPD_HOST"),
@@ -142,6 +248,7 @@ func newConfig() *Config {
 		DefaultSortAttrID:      MTAttrPath,
 		TrackDefaultReplace:    false,
 		PlaylistDefaultReplace: true,
+		AlbumGrouping:          AlbumGroupingArtist,
 		StreamDefaultReplace:   true,
 		PlayerSeekDuration:     5,
 		PlayerTitleTemplate: glib.Local(
@@ -156,40 +263,78 @@ func newConfig() *Config {
 				"{{- else -}}\n" +
 				"<i>(no track)</i>\n" +
 				"{{- end -}}\n"),
-		PlayerAlbumArtTracks:   true,
-		PlayerAlbumArtStreams:  false,
-		PlayerAlbumArtSize:     80,
+		PlayerAlbumArtTracks:  true,
+		PlayerAlbumArtStreams: false,
+		PlayerAlbumArtSize:    80,
+		PlayerNotifyTemplate: glib.Local(
+			"{{- if or .Title .Album | or .Artist -}}\n" +
+				"{{ .Title | default \"(unknown title)\" }}\n" +
+				"by <b>{{ .Artist | default \"(unknown artist)\" }}</b>\n" +
+				"from <i>{{ .Album | default \"(unknown album)\" }}</i>\n" +
+				"{{- else if .Name -}}\n" +
+				"{{ .Name }}\n" +
+				"{{- else if .file -}}\n" +
+				"{{ .file | basename }}\n" +
+				"from <i>{{ .file | dirname }}</i>\n" +
+				"{{- end -}}\n"),
+		MiniPlayerTemplate: glib.Local(
+			"{{- if or .Title .Album | or .Artist -}}\n" +
+				"<b>{{ .Title | default \"(unknown title)\" }}</b>\n" +
+				"{{ .Artist | default \"(unknown artist)\" }}\n" +
+				"{{- else if .Name -}}\n" +
+				"<b>{{ .Name }}</b>\n" +
+				"{{- else if .file -}}\n" +
+				"<b>{{ .file | basename }}</b>\n" +
+				"{{- else -}}\n" +
+				"<i>(no track)</i>\n" +
+				"{{- end -}}\n"),
+		AlbumArtCacheSize:      100,
 		SwitchToOnQueueReplace: true,
 		PlayOnQueueReplace:     false,
 		MaxSearchResults:       500,
 		Streams: []StreamSpec{
 			{Name: "BBC World News", URI: "http://stream.live.vc.bbcmedia.co.uk/bbc_world_service"},
 		},
-		MainWindowDimensions: Dimensions{-1, -1, -1, -1},
+		StreamsCollapsedGroups: map[string]bool{},
+		HistoryStatsPeriod:     HistoryStatsPeriodMonth,
+		HistoryStatsCategory:   HistoryStatsCategoryArtist,
+		SleepMode:              SleepModeMinutes,
+		SleepMinutes:           30,
+		SleepTracks:            3,
+		SleepFade:              true,
+		SleepFadeDuration:      30,
+		AlarmHour:              7,
+		AlarmFade:              true,
+		AlarmFadeDuration:      60,
+		LibraryGridLevels:      map[string]bool{},
+		MainWindowDimensions:   Dimensions{-1, -1, -1, -1},
+		MiniPlayerDimensions:   Dimensions{-1, -1, -1, -1},
 	}
 }
 
-// Load reads the config from the default file
+// Load reads the config from the default file. If the file is missing or damaged, its backup copy is loaded instead;
+// failures the user should know about are reported by LoadWarning()
 func (c *Config) Load() {
-	// Try to read the file
-	file := c.getConfigFile()
-	data, err := os.ReadFile(file)
-
-	// Ignore if the file isn't there
-	if errors.Is(err, os.ErrNotExist) {
-		return
+	c.loadWarning = c.loadFrom(c.getConfigFile())
+	if c.loadWarning != "" {
+		log.Warning(c.loadWarning)
 	}
 
-	// Warn on any other error
-	if errCheck(err, "Couldn't read file") {
-		return
+	// If nothing has been loaded, changes made to the file later are merged into the defaults
+	if c.base == nil {
+		c.base = c.fields()
 	}
+}
 
-	// Unmarshal the config
-	if errCheck(json.Unmarshal(data, &c), "json.Unmarshal() failed") {
-		return
-	}
-	log.Debugf("Loaded configuration from %s", file)
+// File returns the full path of the default config file
+func (c *Config) File() string {
+	return c.getConfigFile()
+}
+
+// LoadWarning returns a message describing why the settings couldn't be loaded, or an empty string if they loaded
+// fine
+func (c *Config) LoadWarning() string {
+	return c.loadWarning
 }
 
 // MpdNetworkAddress returns the MPD network and the address string
@@ -200,22 +345,42 @@ func (c *Config) MpdNetworkAddress() (string, string) {
 	return "tcp", fmt.Sprintf("%s:%d", c.MpdHost, c.MpdPort)
 }
 
+// Reload merges changes made to the default config file by someone else since it was last loaded or saved into the
+// config. Settings changed in the config meanwhile take precedence. Returns whether the config has changed
+func (c *Config) Reload() (bool, error) {
+	return c.merge(c.getConfigFile())
+}
+
 // Save writes out the config to the default file
 func (c *Config) Save() {
+	// Don't overwrite a file the settings couldn't be loaded from
+	if c.readOnly {
+		log.Warning("Not saving configuration as it failed to load")
+		return
+	}
+
 	// Create the config directory if it doesn't exist
 	if errCheck(os.MkdirAll(c.getConfigDir(), 0755), "MkdirAll() failed") {
 		return
 	}
 
+	// Don't clobber changes made to the file by someone else
+	file := c.getConfigFile()
+	if _, err := c.merge(file); err != nil {
+		log.Warningf("Failed to merge changes made to %s, overwriting them: %v", file, err)
+	}
+
 	// Serialise the config
+	c.Version = currentVersion
 	data, err := json.MarshalIndent(c, "", "    ")
 	if errCheck(err, "json.MarshalIndent() failed") {
 		return
 	}
 
-	// Save the config
-	file := c.getConfigFile()
-	if !errCheck(os.WriteFile(file, data, 0600), "WriteFile() failed") {
+	// Keep the previous config as a backup, then replace it
+	errCheck(backupFile(file), "Failed to back up the config file")
+	if !errCheck(writeFileAtomic(file, data, 0600), "Failed to write the config file") {
+		c.fileData, c.base = data, c.fields()
 		log.Debugf("Saved configuration to %s", file)
 	}
 }
@@ -229,3 +394,235 @@ func (c *Config) getConfigDir() string {
 func (c *Config) getConfigFile() string {
 	return path.Join(c.getConfigDir(), "config.json")
 }
+
+// loadFrom reads the config from the given file, falling back to its backup copy, and returns a message for the user if
+// the settings couldn't be loaded
+func (c *Config) loadFrom(file string) string {
+	version, err := c.loadFile(file)
+	switch {
+	case err == nil:
+		log.Debugf("Loaded configuration from %s", file)
+		if version <= currentVersion {
+			return ""
+		}
+		// The file comes from a newer application version: keep a copy as settings unknown to this version won't be
+		// saved
+		cp := fmt.Sprintf("%s.v%d", file, version)
+		if errCheck(copyFile(file, cp), "Failed to copy the config file") {
+			c.readOnly = true
+			return fmt.Sprintf(
+				glib.Local("Settings in %s were saved by a newer version of Ymuse. They won't be saved in this session."),
+				file)
+		}
+		return fmt.Sprintf(
+			glib.Local("Settings in %s were saved by a newer version of Ymuse. Some of them may be lost; a copy of the file has been kept as %s."),
+			file, cp)
+
+	case errors.Is(err, os.ErrNotExist):
+		// The last save may have been interrupted after the backup had been made
+		if _, err := c.loadFile(file + backupSuffix); err == nil {
+			log.Infof("Restored configuration from %s", file+backupSuffix)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			log.Warningf("Failed to load configuration from %s: %v", file+backupSuffix, err)
+		}
+		return ""
+	}
+
+	// Move the damaged file out of the way so that it isn't overwritten by the next save
+	msg := fmt.Sprintf(glib.Local("Failed to load settings from %s: %v."), file, err)
+	damaged := file + damagedSuffix
+	if errCheck(os.Rename(file, damaged), "Failed to rename the damaged config file") {
+		c.readOnly = true
+	}
+
+	// Resort to the backup
+	if _, err := c.loadFile(file + backupSuffix); err == nil {
+		msg += " " + glib.Local("Settings have been restored from the backup copy.")
+	} else {
+		msg += " " + glib.Local("Default settings are used.")
+	}
+	if c.readOnly {
+		return msg + " " + glib.Local("Settings won't be saved in this session.")
+	}
+	return msg + " " + fmt.Sprintf(glib.Local("The damaged file has been kept as %s."), damaged)
+}
+
+// loadFile reads the config from the given file, upgrading it to the current schema version, and returns the version
+// the file had. The config is only updated if the file has been loaded successfully
+func (c *Config) loadFile(file string) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	cfg, version, err := parseConfig(file, data)
+	if err != nil {
+		return version, err
+	}
+	c.replace(cfg, data)
+	return version, nil
+}
+
+// merge merges changes made to the given file by someone else since the config was last loaded or saved into the
+// config. Settings changed in the config meanwhile take precedence. Returns whether the config has changed
+func (c *Config) merge(file string) (bool, error) {
+	// Check whether the file has changed at all. A deleted file has nothing to merge
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	} else if bytes.Equal(data, c.fileData) {
+		return false, nil
+	}
+	disk, _, err := parseConfig(file, data)
+	if err != nil {
+		return false, err
+	}
+
+	// Take the file's value of every setting that hasn't changed in the config
+	ours, theirs := c.fields(), disk.fields()
+	changed := false
+	for name, value := range ours {
+		if bytes.Equal(value, c.base[name]) && !bytes.Equal(value, theirs[name]) {
+			ours[name] = theirs[name]
+			changed = true
+		}
+	}
+	if !changed {
+		c.fileData, c.base = data, theirs
+		return false, nil
+	}
+
+	// Apply the merged settings over the defaults
+	merged, err := json.Marshal(ours)
+	if err != nil {
+		return false, err
+	}
+	cfg := newConfig()
+	if err := json.Unmarshal(merged, cfg); err != nil {
+		return false, err
+	}
+	c.replace(cfg, data)
+	// Changes remaining in the config are relative to the file
+	c.base = theirs
+	return true, nil
+}
+
+// fields returns JSON-encoded settings by their names
+func (c *Config) fields() map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = json.Unmarshal(data, &m)
+	}
+	errCheck(err, "Failed to encode config")
+	return m
+}
+
+// replace replaces the settings with those of cfg, which has been read from a file with the given content, keeping the
+// session state
+func (c *Config) replace(cfg *Config, fileData []byte) {
+	cfg.loadWarning, cfg.readOnly = c.loadWarning, c.readOnly
+	cfg.fileData, cfg.base = fileData, cfg.fields()
+	*c = *cfg
+}
+
+// parseConfig parses the given content of the config file, upgrading it to the current schema version. Returns the
+// config along with the version the content had
+func parseConfig(file string, data []byte) (*Config, int, error) {
+	// Parse and migrate the raw data
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, err
+	}
+	if raw == nil {
+		return nil, 0, errors.New("no settings found")
+	}
+	version, err := migrate(raw)
+	if err != nil {
+		return nil, version, err
+	}
+
+	// Apply the settings over the defaults
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, version, err
+	}
+	cfg := newConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, version, err
+	}
+	for _, s := range cfg.validate() {
+		log.Warningf("Invalid setting in %s: %s", file, s)
+	}
+	return cfg, version, nil
+}
+
+// backupFile replaces the backup copy of the given file with the file, if it exists
+func backupFile(file string) error {
+	file = resolveSymlinks(file)
+	backup := file + backupSuffix
+	if err := os.Remove(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// Prefer a hard link, which is atomic, falling back to a copy
+	err := os.Link(file, backup)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return copyFile(file, backup)
+	}
+	return nil
+}
+
+// resolveSymlinks returns the path the given file's symlinks point to, so that the file can be replaced without
+// replacing the symlink itself. The path is returned as is if it can't be resolved, for instance if there's no file yet
+func resolveSymlinks(file string) string {
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		return resolved
+	}
+	return file
+}
+
+// copyFile copies the given file under another name, replacing the target atomically
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, data, 0600)
+}
+
+// writeFileAtomic writes data to the given file via a temporary one, which is renamed over the file once fully written,
+// so that the file never ends up only partially written
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	file = resolveSymlinks(file)
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = f.Chmod(perm)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// Make the rename durable as well. Not all filesystems support syncing a directory, hence no error checking
+	if d, err := os.Open(filepath.Dir(file)); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
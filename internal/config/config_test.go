@@ -0,0 +1,334 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_migrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        map[string]interface{}
+		wantVersion int
+		wantErr     bool
+	}{
+		{"unversioned", map[string]interface{}{}, 0, false},
+		{"current", map[string]interface{}{"Version": float64(currentVersion)}, currentVersion, false},
+		{"newer", map[string]interface{}{"Version": float64(currentVersion + 1)}, currentVersion + 1, false},
+		{"negative", map[string]interface{}{"Version": float64(-1)}, 0, true},
+		{"fractional", map[string]interface{}{"Version": 1.5}, 0, true},
+		{"string", map[string]interface{}{"Version": "1"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantVersion {
+				t.Errorf("migrate() = %v, want %v", got, tt.wantVersion)
+			}
+			if v, _ := json.Marshal(tt.data["Version"]); !tt.wantErr && tt.wantVersion <= currentVersion &&
+				string(v) != strconv.Itoa(currentVersion) {
+				t.Errorf("migrated Version = %s, want %v", v, currentVersion)
+			}
+		})
+	}
+}
+
+func TestConfig_validate(t *testing.T) {
+	c := newConfig()
+	if got := c.validate(); len(got) > 0 {
+		t.Errorf("validate() of defaults = %v, want no corrections", got)
+	}
+
+	c.MpdNetwork = "udp"
+	c.MpdPort = 70000
+	c.QueueColumns = []ColumnSpec{{ID: MTAttrAlbum, Width: -5}, {ID: 999}, {ID: MTAttrAlbum}, {ID: MTAttrTrack, Width: 100}}
+	c.DefaultSortAttrID = QueueColumnIcon
+	c.SleepMode = "forever"
+	c.AlarmHour = 24
+	c.AlarmMinute = -1
+	if got := c.validate(); len(got) != 9 {
+		t.Errorf("validate() made %d corrections, want 9: %v", len(got), got)
+	}
+	if c.MpdNetwork != "tcp" {
+		t.Errorf("MpdNetwork = %v, want %v", c.MpdNetwork, "tcp")
+	}
+	if c.MpdPort != 65535 {
+		t.Errorf("MpdPort = %v, want %v", c.MpdPort, 65535)
+	}
+	if want := []ColumnSpec{{ID: MTAttrAlbum}, {ID: MTAttrTrack, Width: 100}}; !reflect.DeepEqual(c.QueueColumns, want) {
+		t.Errorf("QueueColumns = %v, want %v", c.QueueColumns, want)
+	}
+	if c.DefaultSortAttrID != MTAttrPath {
+		t.Errorf("DefaultSortAttrID = %v, want %v", c.DefaultSortAttrID, MTAttrPath)
+	}
+	if c.SleepMode != SleepModeMinutes {
+		t.Errorf("SleepMode = %v, want %v", c.SleepMode, SleepModeMinutes)
+	}
+	if c.AlarmHour != 23 || c.AlarmMinute != 0 {
+		t.Errorf("Alarm = %v:%v, want 23:0", c.AlarmHour, c.AlarmMinute)
+	}
+
+	// Without valid columns the defaults are restored
+	c.QueueColumns = []ColumnSpec{{ID: -1}}
+	c.validate()
+	if want := newConfig().QueueColumns; !reflect.DeepEqual(c.QueueColumns, want) {
+		t.Errorf("QueueColumns = %v, want %v", c.QueueColumns, want)
+	}
+}
+
+func writeTestFile(t *testing.T, file, data string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestConfig_loadFrom(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		c := newConfig()
+		if got := c.loadFrom(filepath.Join(t.TempDir(), "config.json")); got != "" {
+			t.Errorf("loadFrom() = %q, want no warning", got)
+		}
+		if !reflect.DeepEqual(c, newConfig()) {
+			t.Errorf("loadFrom() changed the defaults: %+v", c)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		writeTestFile(t, file, `{"MpdHost": "music", "MpdPort": 0}`)
+		c := newConfig()
+		if got := c.loadFrom(file); got != "" {
+			t.Errorf("loadFrom() = %q, want no warning", got)
+		}
+		if c.MpdHost != "music" || c.MpdPort != 1 || c.Version != currentVersion {
+			t.Errorf("loaded MpdHost = %v, MpdPort = %v, Version = %v", c.MpdHost, c.MpdPort, c.Version)
+		}
+	})
+
+	t.Run("restored from backup", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		writeTestFile(t, file+backupSuffix, `{"MpdHost": "backup"}`)
+		c := newConfig()
+		if got := c.loadFrom(file); got != "" {
+			t.Errorf("loadFrom() = %q, want no warning", got)
+		}
+		if c.MpdHost != "backup" {
+			t.Errorf("MpdHost = %v, want %v", c.MpdHost, "backup")
+		}
+	})
+
+	t.Run("damaged", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		writeTestFile(t, file, `{"MpdHost": "dam`)
+		writeTestFile(t, file+backupSuffix, `{"MpdHost": "backup"}`)
+		c := newConfig()
+		if got := c.loadFrom(file); !strings.Contains(got, file+damagedSuffix) {
+			t.Errorf("loadFrom() = %q, want a warning mentioning the damaged file", got)
+		}
+		if c.MpdHost != "backup" || c.readOnly {
+			t.Errorf("MpdHost = %v, readOnly = %v, want backup, false", c.MpdHost, c.readOnly)
+		}
+		if got := readTestFile(t, file+damagedSuffix); got != `{"MpdHost": "dam` {
+			t.Errorf("damaged file = %q", got)
+		}
+	})
+
+	t.Run("damaged without backup", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		writeTestFile(t, file, `{"MpdPort": "x"}`)
+		c := newConfig()
+		if got := c.loadFrom(file); got == "" {
+			t.Error("loadFrom() = no warning, want one")
+		}
+		if !reflect.DeepEqual(c, newConfig()) {
+			t.Errorf("loadFrom() partially applied the damaged file: %+v", c)
+		}
+	})
+
+	t.Run("newer", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		data := `{"Version": 1000, "MpdHost": "future", "Unknown": true}`
+		writeTestFile(t, file, data)
+		c := newConfig()
+		if got := c.loadFrom(file); !strings.Contains(got, file+".v1000") {
+			t.Errorf("loadFrom() = %q, want a warning mentioning the copy", got)
+		}
+		if c.MpdHost != "future" {
+			t.Errorf("MpdHost = %v, want %v", c.MpdHost, "future")
+		}
+		if got := readTestFile(t, file+".v1000"); got != data {
+			t.Errorf("copy = %q, want %q", got, data)
+		}
+	})
+}
+
+func TestConfig_merge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	writeTestFile(t, file, `{"MpdHost": "a", "MpdPort": 6600, "QueueToolbar": true}`)
+	c := newConfig()
+	if got := c.loadFrom(file); got != "" {
+		t.Fatalf("loadFrom() = %q, want no warning", got)
+	}
+
+	// Nothing has changed yet
+	if got, err := c.merge(file); got || err != nil {
+		t.Errorf("merge() = %v, %v, want false, nil", got, err)
+	}
+
+	// Change the port in the config and both the host and the port in the file
+	c.MpdPort = 6601
+	writeTestFile(t, file, `{"MpdHost": "b", "MpdPort": 6602, "QueueToolbar": true}`)
+	if got, err := c.merge(file); !got || err != nil {
+		t.Errorf("merge() = %v, %v, want true, nil", got, err)
+	}
+	if c.MpdHost != "b" || c.MpdPort != 6601 {
+		t.Errorf("merged MpdHost = %v, MpdPort = %v, want b, 6601", c.MpdHost, c.MpdPort)
+	}
+
+	// The file is now the base: the port change is still the config's own
+	writeTestFile(t, file, `{"MpdHost": "b", "MpdPort": 6603, "QueueToolbar": false}`)
+	if got, err := c.merge(file); !got || err != nil {
+		t.Errorf("merge() = %v, %v, want true, nil", got, err)
+	}
+	if c.MpdPort != 6601 || c.QueueToolbar {
+		t.Errorf("merged MpdPort = %v, QueueToolbar = %v, want 6601, false", c.MpdPort, c.QueueToolbar)
+	}
+
+	// A damaged file isn't merged
+	writeTestFile(t, file, `{"MpdHost": `)
+	if got, err := c.merge(file); got || err == nil {
+		t.Errorf("merge() = %v, %v, want false, error", got, err)
+	}
+
+	// Neither is a deleted one
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.merge(file); got || err != nil {
+		t.Errorf("merge() = %v, %v, want false, nil", got, err)
+	}
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+
+	// There's nothing to back up yet
+	if err := backupFile(file); err != nil {
+		t.Fatalf("backupFile() error = %v", err)
+	}
+	if err := writeFileAtomic(file, []byte(`{"A": 1}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+
+	// Overwrite, keeping a backup
+	if err := backupFile(file); err != nil {
+		t.Fatalf("backupFile() error = %v", err)
+	}
+	if err := writeFileAtomic(file, []byte(`{"A": 2}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if got := readTestFile(t, file); got != `{"A": 2}` {
+		t.Errorf("file = %q, want %q", got, `{"A": 2}`)
+	}
+	if got := readTestFile(t, file+backupSuffix); got != `{"A": 1}` {
+		t.Errorf("backup = %q, want %q", got, `{"A": 1}`)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, error = %v, want 0600", fi.Mode().Perm(), err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory has %d entries, want 2", len(entries))
+	}
+
+	// The saved config round-trips
+	c := newConfig()
+	c.MpdHost = "roundtrip"
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(file, data, 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	loaded := newConfig()
+	if got := loaded.loadFrom(file); got != "" || !reflect.DeepEqual(loaded.fields(), c.fields()) {
+		t.Errorf("loadFrom() = %q, loaded %+v, want %+v", got, loaded, c)
+	}
+}
+
+func Test_writeFileAtomic_symlink(t *testing.T) {
+	// The config file is a symlink to a file in another directory, as with managed dotfiles
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "config.json")
+	file := filepath.Join(dir, "ymuse", "config.json")
+	for _, d := range []string{filepath.Dir(target), filepath.Dir(file)} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(target, []byte(`{"A": 1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite via the symlink, keeping a backup
+	if err := backupFile(file); err != nil {
+		t.Fatalf("backupFile() error = %v", err)
+	}
+	if err := writeFileAtomic(file, []byte(`{"A": 2}`), 0600); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+
+	// The symlink is kept and the file it points to is updated
+	if fi, err := os.Lstat(file); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("file mode = %v, error = %v, want a symlink", fi.Mode(), err)
+	}
+	if got := readTestFile(t, target); got != `{"A": 2}` {
+		t.Errorf("target = %q, want %q", got, `{"A": 2}`)
+	}
+	if got := readTestFile(t, target+backupSuffix); got != `{"A": 1}` {
+		t.Errorf("backup = %q, want %q", got, `{"A": 1}`)
+	}
+}
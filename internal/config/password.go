@@ -0,0 +1,121 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/secret"
+	"strconv"
+	"sync"
+)
+
+// Keyring singleton, nil if there's no Secret Service available
+var keyring *secret.Keyring
+var keyringOnce sync.Once
+
+// getKeyring returns the keyring, connecting to it on the first call, or nil if it isn't available
+func getKeyring() *secret.Keyring {
+	keyringOnce.Do(func() {
+		k, err := secret.New()
+		if err != nil {
+			log.Infof("Keyring isn't available, passwords will be stored in the config file: %v", err)
+			return
+		}
+		keyring = k
+	})
+	return keyring
+}
+
+// MpdPasswordLookup returns a function looking up the password for the configured MPD server. The password in the
+// config, if any, takes precedence over the keyring, since it's only there if the keyring couldn't be used. The
+// function may block while the keyring prompts the user, so it must be called outside the GTK main loop
+func (c *Config) MpdPasswordLookup() func() (string, error) {
+	password, attrs := c.MpdPassword, c.mpdPasswordAttrs()
+	return func() (string, error) {
+		k := getKeyring()
+		if password != "" || k == nil {
+			return password, nil
+		}
+		value, _, err := k.Lookup(attrs)
+		return value, err
+	}
+}
+
+// StoreMpdPassword saves the password for the configured MPD server in the keyring in the background, an empty
+// password deleting it. If the keyring isn't available or fails, the password is stored in the config instead. done,
+// if not nil, is called on the GTK main loop once the password is stored
+func (c *Config) StoreMpdPassword(password string, done func(err error)) {
+	_, addr := c.MpdNetworkAddress()
+	attrs := c.mpdPasswordAttrs()
+	go func() {
+		// Update the keyring, which may prompt the user
+		var err error
+		k := getKeyring()
+		switch {
+		case k == nil:
+		case password == "":
+			err = k.Delete(attrs)
+		default:
+			err = k.Store(fmt.Sprintf(glib.Local("MPD password for %s"), addr), attrs, password)
+		}
+
+		// Update the config on the main loop, falling back to storing the password there
+		glib.IdleAdd(func() {
+			if k == nil || err != nil {
+				c.MpdPassword = password
+			} else {
+				c.MpdPassword = ""
+			}
+			if done != nil {
+				done(err)
+			}
+		})
+	}()
+}
+
+// migrateMpdPassword moves the plain-text MPD password from the config file into the keyring in the background, if
+// the keyring is available
+func (c *Config) migrateMpdPassword() {
+	if c.MpdPassword == "" {
+		return
+	}
+	c.StoreMpdPassword(c.MpdPassword, func(err error) {
+		// The password stays in the config if there's no keyring
+		if errCheck(err, "Failed to move MPD password to keyring") || c.MpdPassword != "" {
+			return
+		}
+		log.Info("Moved MPD password from the config file to the keyring")
+
+		// Remove the password from the file straight away
+		c.Save()
+	})
+}
+
+// mpdPasswordAttrs returns the keyring attributes identifying the password for the configured MPD server
+func (c *Config) mpdPasswordAttrs() map[string]string {
+	attrs := map[string]string{
+		"xdg:schema": "org.gnome.keyring.NetworkPassword",
+		"protocol":   "mpd",
+	}
+	if c.MpdNetwork == "unix" {
+		attrs["server"] = c.MpdSocketPath
+	} else {
+		attrs["server"] = c.MpdHost
+		attrs["port"] = strconv.Itoa(c.MpdPort)
+	}
+	return attrs
+}
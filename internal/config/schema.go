@@ -0,0 +1,147 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// migration upgrades raw config data from the schema version equal to its index in migrations to the next one
+type migration func(data map[string]interface{}) error
+
+// migrations lists the config schema upgrades, in order. Never change or remove an existing entry: to change the
+// schema, append a migration instead
+var migrations = []migration{
+	// 0 → 1: version 0 is the unversioned schema, which is identical to version 1
+	func(map[string]interface{}) error { return nil },
+}
+
+// currentVersion is the config schema version written by this application version
+var currentVersion = len(migrations)
+
+// migrate upgrades the given raw config data to the current schema version in place, and returns the version the data
+// originally had. Data of a newer version is left alone
+func migrate(data map[string]interface{}) (int, error) {
+	// A missing version means version 0
+	version := 0
+	if v, ok := data["Version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+			return 0, fmt.Errorf("invalid config version: %v", v)
+		}
+		version = int(f)
+	}
+
+	// Apply all migrations the data hasn't been through yet
+	for v := version; v < currentVersion; v++ {
+		if err := migrations[v](data); err != nil {
+			return version, fmt.Errorf("failed to migrate config from version %d to %d: %w", v, v+1, err)
+		}
+		data["Version"] = v + 1
+	}
+	return version, nil
+}
+
+// validator corrects invalid setting values, keeping track of the corrections made
+type validator []string
+
+// fix records a correction
+func (v *validator) fix(name string, was, is interface{}) {
+	*v = append(*v, fmt.Sprintf("%s: %v replaced with %v", name, was, is))
+}
+
+// clamp brings the given value within [lo, hi]
+func (v *validator) clamp(name string, value *int, lo, hi int) {
+	if i := max(lo, min(*value, hi)); i != *value {
+		v.fix(name, *value, i)
+		*value = i
+	}
+}
+
+// oneOf makes sure the given value is one of allowed, replacing it with the first allowed value otherwise
+func (v *validator) oneOf(name string, value *string, allowed ...string) {
+	for _, s := range allowed {
+		if *value == s {
+			return
+		}
+	}
+	v.fix(name, fmt.Sprintf("%q", *value), fmt.Sprintf("%q", allowed[0]))
+	*value = allowed[0]
+}
+
+// validate brings invalid settings, for instance left by manual editing of the config file, back to acceptable values,
+// and returns descriptions of the corrections made
+func (c *Config) validate() []string {
+	var v validator
+
+	// MPD connection
+	v.oneOf("MpdNetwork", &c.MpdNetwork, "tcp", "unix")
+	v.clamp("MpdPort", &c.MpdPort, 1, 65535)
+
+	// Drop queue columns that are unknown or duplicate
+	var cols []ColumnSpec
+	seen := make(map[int]bool)
+	for _, col := range c.QueueColumns {
+		if _, ok := MpdTrackAttributes[col.ID]; !ok || seen[col.ID] {
+			v = append(v, fmt.Sprintf("QueueColumns: dropped unknown or duplicate column %d", col.ID))
+			continue
+		}
+		seen[col.ID] = true
+		v.clamp(fmt.Sprintf("QueueColumns[%d].Width", col.ID), &col.Width, 0, math.MaxInt32)
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		v = append(v, "QueueColumns: no columns left, reverted to the defaults")
+		cols = newConfig().QueueColumns
+	}
+	c.QueueColumns = cols
+	if _, ok := MpdTrackAttributes[c.DefaultSortAttrID]; !ok {
+		v.fix("DefaultSortAttrID", c.DefaultSortAttrID, MTAttrPath)
+		c.DefaultSortAttrID = MTAttrPath
+	}
+
+	// Choices
+	v.oneOf("AlbumGrouping", &c.AlbumGrouping, AlbumGroupingArtist, AlbumGroupingDate, AlbumGroupingMBID)
+	v.oneOf(
+		"ScrobblerService",
+		&c.ScrobblerService,
+		ScrobblerServiceNone, ScrobblerServiceListenBrainz, ScrobblerServiceAudioscrobbler)
+	v.oneOf(
+		"HistoryStatsPeriod",
+		&c.HistoryStatsPeriod,
+		HistoryStatsPeriodMonth, HistoryStatsPeriodToday, HistoryStatsPeriodWeek, HistoryStatsPeriodYear, HistoryStatsPeriodAll)
+	v.oneOf(
+		"HistoryStatsCategory",
+		&c.HistoryStatsCategory,
+		HistoryStatsCategoryArtist, HistoryStatsCategoryAlbum, HistoryStatsCategoryGenre)
+	v.oneOf("SleepMode", &c.SleepMode, SleepModeMinutes, SleepModeTrackEnd, SleepModeTracks)
+
+	// Numbers
+	v.clamp("PlayerSeekDuration", &c.PlayerSeekDuration, 1, 600)
+	v.clamp("PlayerAlbumArtSize", &c.PlayerAlbumArtSize, 50, 500)
+	v.clamp("PlayerFadeOut", &c.PlayerFadeOut, 0, 10000)
+	v.clamp("PlayerFadeIn", &c.PlayerFadeIn, 0, 10000)
+	v.clamp("AlbumArtCacheSize", &c.AlbumArtCacheSize, 0, 1024*1024)
+	v.clamp("MaxSearchResults", &c.MaxSearchResults, 1, 100000)
+	v.clamp("SleepMinutes", &c.SleepMinutes, 1, 999)
+	v.clamp("SleepTracks", &c.SleepTracks, 1, 999)
+	v.clamp("SleepFadeDuration", &c.SleepFadeDuration, 0, 600)
+	v.clamp("AlarmHour", &c.AlarmHour, 0, 23)
+	v.clamp("AlarmMinute", &c.AlarmMinute, 0, 59)
+	v.clamp("AlarmFadeDuration", &c.AlarmFadeDuration, 0, 600)
+	return v
+}
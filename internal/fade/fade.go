@@ -0,0 +1,141 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fade gradually changes the MPD volume
+package fade
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Interval between volume changes during a fade
+const stepInterval = 100 * time.Millisecond
+
+// Clock is the source of time for a Fader
+type Clock interface {
+	Now() time.Time                         // Returns the current time
+	After(d time.Duration) <-chan time.Time // Returns a channel receiving the time once the given duration has elapsed
+}
+
+// realClock is a Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Fader changes the volume in steps from a separate goroutine. At most one fade is running at a time
+type Fader struct {
+	clock     Clock               // Source of time
+	setVolume func(vol int) error // Function applying a volume value, 0 to 100
+	mutex     sync.Mutex          // Protects the fields below
+	cancel    chan struct{}       // Channel closed to cancel the running fade, nil if there's none
+	finished  chan struct{}       // Channel closed once the running fade's goroutine stops changing the volume
+}
+
+// New creates and returns a new Fader instance applying the volume using the given function
+func New(setVolume func(vol int) error) *Fader {
+	return newFader(realClock{}, setVolume)
+}
+
+func newFader(clock Clock, setVolume func(vol int) error) *Fader {
+	return &Fader{clock: clock, setVolume: setVolume}
+}
+
+// Active returns whether a fade is running
+func (f *Fader) Active() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.cancel != nil
+}
+
+// Cancel stops the running fade, if any, leaving the volume where it is. Once Cancel returns, the volume isn't changed
+// by the fade anymore
+func (f *Fader) Cancel() {
+	f.mutex.Lock()
+	cancel, finished := f.cancel, f.finished
+	f.cancel, f.finished = nil, nil
+	f.mutex.Unlock()
+	if cancel != nil {
+		close(cancel)
+		<-finished
+	}
+}
+
+// Fade cancels the running fade, if any, and starts changing the volume from one value to another linearly over the
+// given duration. done, if not nil, is called from the fading goroutine once the fade is over; completed tells whether
+// it's reached the target volume, as opposed to having been cancelled or failed
+func (f *Fader) Fade(from, to int, duration time.Duration, done func(completed bool)) {
+	f.Cancel()
+	cancel, finished := make(chan struct{}), make(chan struct{})
+	f.mutex.Lock()
+	f.cancel, f.finished = cancel, finished
+	f.mutex.Unlock()
+	go f.run(from, to, duration, cancel, finished, done)
+}
+
+// run performs a fade until it's complete or a value is received via the cancel channel
+func (f *Fader) run(from, to int, duration time.Duration, cancel, finished chan struct{}, done func(completed bool)) {
+	completed := f.step(from, to, duration, cancel)
+	close(finished)
+
+	// Forget the fade unless it's already been replaced or cancelled
+	f.mutex.Lock()
+	if f.cancel == cancel {
+		f.cancel, f.finished = nil, nil
+	}
+	f.mutex.Unlock()
+	if done != nil {
+		done(completed)
+	}
+}
+
+// step changes the volume in steps, and returns whether the target volume's been reached
+func (f *Fader) step(from, to int, duration time.Duration, cancel chan struct{}) bool {
+	start := f.clock.Now()
+	last := -1
+	for {
+		// Find out the volume for this moment
+		elapsed := f.clock.Now().Sub(start)
+		vol := to
+		if elapsed < duration {
+			vol = from + int(math.Round(float64(to-from)*float64(elapsed)/float64(duration)))
+		}
+
+		// Don't touch the volume after a cancellation
+		select {
+		case <-cancel:
+			return false
+		default:
+		}
+		if vol != last {
+			if errCheck(f.setVolume(vol), "Failed to set volume") {
+				return false
+			}
+			last = vol
+		}
+		if vol == to {
+			return true
+		}
+
+		// Wait for the next step
+		select {
+		case <-cancel:
+			return false
+		case <-f.clock.After(stepInterval):
+		}
+	}
+}
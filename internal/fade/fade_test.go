@@ -0,0 +1,204 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fade
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when told to
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []waiter
+	waiting chan struct{} // Receives a value every time a waiter is added
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), waiting: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{c.now.Add(d), ch})
+	c.waiting <- struct{}{}
+	return ch
+}
+
+// wait blocks until somebody is waiting on the clock
+func (c *fakeClock) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Nobody is waiting on the clock")
+	}
+}
+
+// advance moves the time forward, firing due waiters
+func (c *fakeClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	var rest []waiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			rest = append(rest, w)
+		} else {
+			w.ch <- c.now
+		}
+	}
+	c.waiters = rest
+}
+
+// volumes records the applied volume values
+type volumes struct {
+	mutex  sync.Mutex
+	values []int
+	err    error
+}
+
+func (v *volumes) set(vol int) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.err != nil {
+		return v.err
+	}
+	v.values = append(v.values, vol)
+	return nil
+}
+
+func (v *volumes) get() []int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return append([]int(nil), v.values...)
+}
+
+func TestFader_Fade(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		duration time.Duration
+		want     []int
+	}{
+		{"down", 50, 0, 500 * time.Millisecond, []int{50, 40, 30, 20, 10, 0}},
+		{"up", 0, 80, 400 * time.Millisecond, []int{0, 20, 40, 60, 80}},
+		{"rounding", 10, 7, 300 * time.Millisecond, []int{10, 9, 8, 7}},
+		{"same", 30, 30, time.Second, []int{30}},
+		{"instant", 100, 0, 0, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, vols := newFakeClock(), &volumes{}
+			f := newFader(clock, vols.set)
+			done := make(chan bool, 1)
+			f.Fade(tt.from, tt.to, tt.duration, func(completed bool) { done <- completed })
+			for i := 1; i < len(tt.want); i++ {
+				clock.wait(t)
+				clock.advance(stepInterval)
+			}
+			if completed := <-done; !completed {
+				t.Errorf("Fade() completed = %v, want true", completed)
+			}
+			if got := vols.get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fade() volumes = %v, want %v", got, tt.want)
+			}
+			if f.Active() {
+				t.Error("Active() = true after the fade, want false")
+			}
+		})
+	}
+}
+
+func TestFader_Cancel(t *testing.T) {
+	clock, vols := newFakeClock(), &volumes{}
+	f := newFader(clock, vols.set)
+	done := make(chan bool, 1)
+	f.Fade(100, 0, time.Second, func(completed bool) { done <- completed })
+	clock.wait(t)
+	clock.advance(stepInterval)
+	clock.wait(t)
+	if !f.Active() {
+		t.Error("Active() = false during the fade, want true")
+	}
+
+	// Once cancelled, the volume must stay put
+	f.Cancel()
+	if completed := <-done; completed {
+		t.Error("Fade() completed = true after Cancel(), want false")
+	}
+	clock.advance(time.Second)
+	if got, want := vols.get(), []int{100, 90}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fade() volumes = %v, want %v", got, want)
+	}
+	if f.Active() {
+		t.Error("Active() = true after Cancel(), want false")
+	}
+}
+
+func TestFader_Replace(t *testing.T) {
+	clock, vols := newFakeClock(), &volumes{}
+	f := newFader(clock, vols.set)
+	done1, done2 := make(chan bool, 1), make(chan bool, 1)
+	f.Fade(100, 0, time.Second, func(completed bool) { done1 <- completed })
+	clock.wait(t)
+
+	// A new fade cancels the running one
+	f.Fade(0, 20, 200*time.Millisecond, func(completed bool) { done2 <- completed })
+	if completed := <-done1; completed {
+		t.Error("first Fade() completed = true, want false")
+	}
+	clock.wait(t)
+	clock.advance(stepInterval)
+	clock.wait(t)
+	clock.advance(stepInterval)
+	if completed := <-done2; !completed {
+		t.Error("second Fade() completed = false, want true")
+	}
+	if got, want := vols.get(), []int{100, 0, 10, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fade() volumes = %v, want %v", got, want)
+	}
+}
+
+func TestFader_Error(t *testing.T) {
+	clock, vols := newFakeClock(), &volumes{err: errors.New("no mixer")}
+	f := newFader(clock, vols.set)
+	done := make(chan bool, 1)
+	f.Fade(100, 0, time.Second, func(completed bool) { done <- completed })
+	if completed := <-done; completed {
+		t.Error("Fade() completed = true on error, want false")
+	}
+	if f.Active() {
+		t.Error("Active() = true after an error, want false")
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lyrics
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is a single line of song lyrics
+type Line struct {
+	Time time.Duration // Time the line starts at, only meaningful for synced lyrics
+	Text string        // Line text
+}
+
+// Lyrics holds the lines of song lyrics
+type Lyrics struct {
+	Lines  []Line // Lyrics lines, ordered by time if synced
+	Synced bool   // Whether the lines are timestamped
+}
+
+var (
+	// Leading tag in square brackets, such as a timestamp ("[01:02.34]") or metadata ("[ar:Artist]")
+	lrcTagRegex = regexp.MustCompile(`^\[([^\]]*)\]`)
+	// Timestamp inside a tag: minutes, seconds and an optional fraction, separated by either a dot or a colon
+	lrcTimeRegex = regexp.MustCompile(`^(\d+):(\d{1,2})(?:[.:](\d{1,3}))?$`)
+	// Word timestamp of the enhanced LRC format ("<01:02.34>")
+	lrcWordTimeRegex = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// Parse parses the given lyrics text, which is either in the LRC format or plain text
+func Parse(text string) *Lyrics {
+	var synced, plain []Line
+	var offset time.Duration
+	for _, s := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		// Collect all leading tags
+		var times []time.Duration
+		isTag := false
+		for {
+			m := lrcTagRegex.FindStringSubmatch(s)
+			if m == nil {
+				break
+			}
+			s = s[len(m[0]):]
+			if t, ok := parseLRCTime(m[1]); ok {
+				times = append(times, t)
+			} else if key, value, ok := strings.Cut(m[1], ":"); ok {
+				// Metadata tag: only the offset is of interest
+				isTag = true
+				if strings.EqualFold(strings.TrimSpace(key), "offset") {
+					if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+						offset = time.Duration(ms) * time.Millisecond
+					}
+				}
+			}
+		}
+
+		// Drop enhanced LRC word timestamps
+		s = strings.TrimSpace(lrcWordTimeRegex.ReplaceAllString(s, ""))
+
+		// A line can have multiple timestamps, meaning it's repeated
+		switch {
+		case len(times) > 0:
+			for _, t := range times {
+				synced = append(synced, Line{Time: t, Text: s})
+			}
+		case !isTag || s != "":
+			plain = append(plain, Line{Text: s})
+		}
+	}
+
+	// Lyrics without a single timestamp are plain text
+	if len(synced) == 0 {
+		return &Lyrics{Lines: trimEmptyLines(plain)}
+	}
+
+	// A positive offset makes the lyrics appear sooner
+	for i := range synced {
+		if synced[i].Time -= offset; synced[i].Time < 0 {
+			synced[i].Time = 0
+		}
+	}
+	sort.SliceStable(synced, func(i, j int) bool { return synced[i].Time < synced[j].Time })
+	return &Lyrics{Lines: synced, Synced: true}
+}
+
+// LineAt returns the index of the line being sung at the given playback position, or -1 if there's none (the position
+// precedes the first line or the lyrics aren't synced)
+func (l *Lyrics) LineAt(pos time.Duration) int {
+	if !l.Synced {
+		return -1
+	}
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > pos }) - 1
+}
+
+// parseLRCTime parses the content of an LRC timestamp tag, such as "01:02.34"
+func parseLRCTime(s string) (time.Duration, bool) {
+	m := lrcTimeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	minutes, _ := strconv.Atoi(m[1])
+	seconds, _ := strconv.Atoi(m[2])
+	if seconds >= 60 {
+		return 0, false
+	}
+	t := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+
+	// The fraction is hundredths of a second when two digits long, tenths or milliseconds otherwise
+	if frac := m[3]; frac != "" {
+		f, _ := strconv.Atoi(frac)
+		switch len(frac) {
+		case 1:
+			t += time.Duration(f) * 100 * time.Millisecond
+		case 2:
+			t += time.Duration(f) * 10 * time.Millisecond
+		default:
+			t += time.Duration(f) * time.Millisecond
+		}
+	}
+	return t, true
+}
+
+// trimEmptyLines removes leading and trailing empty lines, returning nil if there's nothing left
+func trimEmptyLines(lines []Line) []Line {
+	for len(lines) > 0 && lines[0].Text == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1].Text == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines
+}
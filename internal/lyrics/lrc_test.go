@@ -0,0 +1,158 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lyrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *Lyrics
+	}{
+		{"empty", "", &Lyrics{}},
+		{"plain", "\nHello\n\nWorld\n\n", &Lyrics{Lines: []Line{{Text: "Hello"}, {Text: ""}, {Text: "World"}}}},
+		{"plain, metadata only", "[ar:Someone]\n[ti:Song]\nHello", &Lyrics{Lines: []Line{{Text: "Hello"}}}},
+		{"hundredths", "[00:01.50]One\n[01:02.03]Two", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1500), Text: "One"},
+			{Time: ms(62030), Text: "Two"},
+		}}},
+		{"timestamp formats", "[00:01]A\n[00:02.5]B\n[00:03.250]C\n[00:04:75]D", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "A"},
+			{Time: ms(2500), Text: "B"},
+			{Time: ms(3250), Text: "C"},
+			{Time: ms(4750), Text: "D"},
+		}}},
+		{"windows line endings", "[00:01.00]One\r\n[00:02.00]Two\r\n", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "One"},
+			{Time: ms(2000), Text: "Two"},
+		}}},
+		{"unordered", "[00:05.00]Late\n[00:01.00]Early", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "Early"},
+			{Time: ms(5000), Text: "Late"},
+		}}},
+		{"multiple timestamps", "[00:01.00]Verse\n[00:02.00][00:04.00]Chorus\n[00:03.00]Bridge", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "Verse"},
+			{Time: ms(2000), Text: "Chorus"},
+			{Time: ms(3000), Text: "Bridge"},
+			{Time: ms(4000), Text: "Chorus"},
+		}}},
+		{"metadata and untimed lines", "[ar:Someone]\n[length:03:20]\nCredits\n[00:01.00]One\n[00:02.00]", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "One"},
+			{Time: ms(2000), Text: ""},
+		}}},
+		{"positive offset", "[offset:+500]\n[00:01.00]One\n[00:00.20]Zero", &Lyrics{Synced: true, Lines: []Line{
+			{Time: 0, Text: "Zero"},
+			{Time: ms(500), Text: "One"},
+		}}},
+		{"negative offset", "[00:01.00]One\n[offset:-250]", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1250), Text: "One"},
+		}}},
+		{"invalid offset", "[offset:soon]\n[00:01.00]One", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "One"},
+		}}},
+		{"word timestamps", "[00:01.00]<00:01.00>Hello <00:01.50>world", &Lyrics{Synced: true, Lines: []Line{
+			{Time: ms(1000), Text: "Hello world"},
+		}}},
+		{"invalid timestamp", "[00:75.00]One", &Lyrics{Lines: []Line{{Text: "One"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLyrics_LineAt(t *testing.T) {
+	synced := Parse("[00:01.00]One\n[00:02.00][00:04.00]Two\n[00:03.00]Three")
+	plain := Parse("One\nTwo")
+	tests := []struct {
+		name   string
+		lyrics *Lyrics
+		pos    time.Duration
+		want   int
+	}{
+		{"before first line", synced, ms(500), -1},
+		{"at first line", synced, ms(1000), 0},
+		{"within line", synced, ms(2500), 1},
+		{"repeated line", synced, ms(4000), 3},
+		{"after last line", synced, time.Hour, 3},
+		{"plain", plain, ms(1500), -1},
+		{"empty", &Lyrics{Synced: true}, ms(1500), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lyrics.LineAt(tt.pos); got != tt.want {
+				t.Errorf("LineAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments map[string]string
+		want     string
+	}{
+		{"none", map[string]string{"TITLE": "Song"}, ""},
+		{"lyrics", map[string]string{"LYRICS": "Hello"}, "Hello"},
+		{"unsynced lyrics", map[string]string{"UNSYNCEDLYRICS": "Hello"}, "Hello"},
+		{"preference", map[string]string{"UNSYNCEDLYRICS": "Plain", "LYRICS": "[00:01.00]Synced"}, "[00:01.00]Synced"},
+		{"case", map[string]string{"Lyrics": "Hello"}, "Hello"},
+		{"blank", map[string]string{"LYRICS": " ", "UNSYNCEDLYRICS": "Hello"}, "Hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromComments(tt.comments); got != tt.want {
+				t.Errorf("FromComments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSidecarPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		musicDir string
+		uri      string
+		want     string
+	}{
+		{"no music dir", "", "a/b.flac", ""},
+		{"no uri", "/music", "", ""},
+		{"stream", "/music", "http://radio/stream.mp3", ""},
+		{"file", "/music", "a/b.flac", "/music/a/b.lrc"},
+		{"no extension", "/music/", "a/b", "/music/a/b.lrc"},
+		{"dotted dir", "/music", "a.b/c d.mp3", "/music/a.b/c d.lrc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SidecarPath(tt.musicDir, tt.uri); got != tt.want {
+				t.Errorf("SidecarPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lyrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commentKeys lists the song comments (as returned by MPD's readcomments) that can hold lyrics, in order of preference
+var commentKeys = []string{"LYRICS", "UNSYNCEDLYRICS"}
+
+// FromComments returns the lyrics text found in the given song comments, or an empty string if there's none. Comment
+// names are matched case-insensitively
+func FromComments(comments map[string]string) string {
+	for _, key := range commentKeys {
+		for k, v := range comments {
+			if strings.EqualFold(k, key) && strings.TrimSpace(v) != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// SidecarPath returns the path of the .lrc file accompanying the song with the given URI in the given music directory,
+// or an empty string if the music directory is unknown or the URI doesn't refer to a local file
+func SidecarPath(musicDir, uri string) string {
+	if musicDir == "" || uri == "" || strings.Contains(uri, "://") {
+		return ""
+	}
+	p := filepath.Join(musicDir, filepath.FromSlash(uri))
+	return strings.TrimSuffix(p, filepath.Ext(p)) + ".lrc"
+}
+
+// FromSidecar reads the lyrics from the .lrc file accompanying the song with the given URI. Returns an empty string if
+// there's no such file
+func FromSidecar(musicDir, uri string) (string, error) {
+	p := SidecarPath(musicDir, uri)
+	if p == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
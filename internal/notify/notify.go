@@ -0,0 +1,248 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notify shows desktop notifications using the org.freedesktop.Notifications D-Bus service
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	busName    = "org.freedesktop.Notifications"
+	objectPath = dbus.ObjectPath("/org/freedesktop/Notifications")
+	iface      = "org.freedesktop.Notifications"
+
+	// ActionDefault is the key of the action invoked by clicking the notification itself
+	ActionDefault = "default"
+)
+
+// Action is a button shown in a notification
+type Action struct {
+	Key   string // Key passed to the action callback
+	Label string // Button label
+}
+
+// Image is raw image data in the format of GdkPixbuf
+type Image struct {
+	Width         int    // Image width in pixels
+	Height        int    // Image height in pixels
+	RowStride     int    // Distance between row starts in bytes
+	HasAlpha      bool   // Whether there's an alpha channel
+	BitsPerSample int    // Number of bits per colour sample, always 8
+	Channels      int    // Number of channels, 3 for RGB or 4 for RGBA
+	Data          []byte // Pixel data
+}
+
+// Notification describes a notification to show
+type Notification struct {
+	Summary   string        // Single-line summary, plain text
+	Body      string        // Body text, which may contain simple markup
+	Icon      string        // Icon name or file URI, optional
+	Image     *Image        // Image to show, takes precedence over the icon, optional
+	Actions   []Action      // Actions to offer, ActionDefault included
+	Transient bool          // Whether the notification shouldn't be kept in the notification history
+	Timeout   time.Duration // Expiration timeout, zero for the server's default
+}
+
+// Notifier shows notifications, each one replacing the previous
+type Notifier struct {
+	conn     *dbus.Conn       // Session bus connection
+	obj      dbus.BusObject   // Notifications service object
+	appName  string           // Name of the application sending notifications
+	onAction func(key string) // Callback for action invocations, called from a separate goroutine
+	markup   bool             // Whether the server supports markup in the body
+	actions  bool             // Whether the server supports actions
+
+	showMutex sync.Mutex        // Serialises Show() calls so that each notification replaces the previous one
+	mutex     sync.Mutex        // Protects lastID
+	lastID    uint32            // ID of the last shown notification, 0 if none
+	signals   chan *dbus.Signal // Channel receiving the service's signals
+}
+
+// New connects to the session bus and returns a new Notifier instance. onAction is invoked, from a separate goroutine,
+// whenever the user activates an action of the last shown notification
+func New(appName string, onAction func(key string)) (*Notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	n, err := newNotifier(conn, appName, onAction)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+func newNotifier(conn *dbus.Conn, appName string, onAction func(key string)) (*Notifier, error) {
+	n := &Notifier{
+		conn:     conn,
+		obj:      conn.Object(busName, objectPath),
+		appName:  appName,
+		onAction: onAction,
+		signals:  make(chan *dbus.Signal, 10),
+	}
+
+	// Find out what the server is capable of
+	var caps []string
+	if err := n.obj.Call(iface+".GetCapabilities", 0).Store(&caps); err != nil {
+		return nil, err
+	}
+	for _, c := range caps {
+		switch c {
+		case "body-markup":
+			n.markup = true
+		case "actions":
+			n.actions = true
+		}
+	}
+
+	// Subscribe to action invocations and closures
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(objectPath), dbus.WithMatchInterface(iface)); err != nil {
+		return nil, err
+	}
+	conn.Signal(n.signals)
+	go n.listen()
+	return n, nil
+}
+
+// Close withdraws the last shown notification, if any, and disconnects from the bus
+func (n *Notifier) Close() error {
+	n.mutex.Lock()
+	id := n.lastID
+	n.lastID = 0
+	n.mutex.Unlock()
+	if id != 0 {
+		errCheck(n.obj.Call(iface+".CloseNotification", 0, id).Err, "CloseNotification() failed")
+	}
+	return n.conn.Close()
+}
+
+// Show shows the given notification, replacing the previously shown one, if it's still there
+func (n *Notifier) Show(notification Notification) error {
+	body := notification.Body
+	if !n.markup {
+		body = StripMarkup(body)
+	}
+	var actions []string
+	if n.actions {
+		for _, a := range notification.Actions {
+			actions = append(actions, a.Key, a.Label)
+		}
+	}
+	timeout := int32(-1)
+	if notification.Timeout > 0 {
+		timeout = int32(notification.Timeout.Milliseconds())
+	}
+
+	n.showMutex.Lock()
+	defer n.showMutex.Unlock()
+	n.mutex.Lock()
+	replacesID := n.lastID
+	n.mutex.Unlock()
+	var id uint32
+	err := n.obj.Call(
+		iface+".Notify",
+		0,
+		n.appName,
+		replacesID,
+		notification.Icon,
+		StripMarkup(notification.Summary),
+		body,
+		actions,
+		hints(notification),
+		timeout,
+	).Store(&id)
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	n.lastID = id
+	n.mutex.Unlock()
+	return nil
+}
+
+// listen processes the service's signals until the connection is closed
+func (n *Notifier) listen() {
+	for sig := range n.signals {
+		if len(sig.Body) < 2 {
+			continue
+		}
+		id, _ := sig.Body[0].(uint32)
+		n.mutex.Lock()
+		last := id != 0 && id == n.lastID
+		n.mutex.Unlock()
+		if !last {
+			continue
+		}
+
+		switch sig.Name {
+		case iface + ".ActionInvoked":
+			if key, ok := sig.Body[1].(string); ok && n.onAction != nil {
+				n.onAction(key)
+			}
+		case iface + ".NotificationClosed":
+			// There's nothing to replace anymore
+			n.mutex.Lock()
+			if n.lastID == id {
+				n.lastID = 0
+			}
+			n.mutex.Unlock()
+		}
+	}
+}
+
+// hints returns the D-Bus hints for the given notification
+func hints(notification Notification) map[string]dbus.Variant {
+	h := map[string]dbus.Variant{}
+	if notification.Transient {
+		h["transient"] = dbus.MakeVariant(true)
+	}
+	if img := notification.Image; img != nil {
+		h["image-data"] = dbus.MakeVariant(struct {
+			Width, Height, RowStride int32
+			HasAlpha                 bool
+			BitsPerSample, Channels  int32
+			Data                     []byte
+		}{
+			int32(img.Width), int32(img.Height), int32(img.RowStride),
+			img.HasAlpha,
+			int32(img.BitsPerSample), int32(img.Channels),
+			img.Data,
+		})
+	}
+	return h
+}
+
+var reTag = regexp.MustCompile(`<[^>]*>`)
+
+// SplitText splits the given text into the summary, which is its first non-blank line, and the body, which is the rest
+// of the text
+func SplitText(text string) (summary, body string) {
+	text = strings.TrimSpace(text)
+	summary, body, _ = strings.Cut(text, "\n")
+	return strings.TrimSpace(summary), strings.TrimSpace(body)
+}
+
+// StripMarkup removes markup tags from the given text and unescapes entities in it
+func StripMarkup(s string) string {
+	return html.UnescapeString(reTag.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,208 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/yktoo/ymuse/internal/dbustest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		text        string
+		wantSummary string
+		wantBody    string
+	}{
+		{"", "", ""},
+		{"Title", "Title", ""},
+		{"\n  Title  \n\nby Artist\nfrom Album\n", "Title", "by Artist\nfrom Album"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if summary, body := SplitText(tt.text); summary != tt.wantSummary || body != tt.wantBody {
+				t.Errorf("SplitText() = (%q, %q), want (%q, %q)", summary, body, tt.wantSummary, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestStripMarkup(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"by <b>Simon &amp; Garfunkel</b>", "by Simon & Garfunkel"},
+		{`<span foreground="red">&lt;error&gt;</span>`, "<error>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := StripMarkup(tt.s); got != tt.want {
+				t.Errorf("StripMarkup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// notification is a call to the stand-in notification server's Notify method
+type notification struct {
+	replacesID    uint32
+	icon          string
+	summary, body string
+	actions       []string
+	hints         map[string]dbus.Variant
+	timeout       int32
+}
+
+// server is a stand-in for a notification server
+type server struct {
+	conn   *dbus.Conn
+	caps   []string
+	mutex  sync.Mutex
+	nextID uint32
+	calls  []notification
+	closed []uint32
+}
+
+func (s *server) GetCapabilities() ([]string, *dbus.Error) {
+	return s.caps, nil
+}
+
+func (s *server) Notify(_ string, replacesID uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.calls = append(s.calls, notification{replacesID, icon, summary, body, actions, hints, timeout})
+	if replacesID != 0 {
+		return replacesID, nil
+	}
+	s.nextID++
+	return s.nextID, nil
+}
+
+func (s *server) CloseNotification(id uint32) *dbus.Error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = append(s.closed, id)
+	return nil
+}
+
+// startServer starts a private session bus with a stand-in notification server on it, and returns a client connection
+func startServer(t *testing.T, caps ...string) (*server, *dbus.Conn) {
+	t.Helper()
+	connect := dbustest.StartBus(t)
+	srv := &server{conn: connect(), caps: caps}
+	if err := srv.conn.Export(srv, objectPath, iface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := srv.conn.RequestName(busName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName() = %v, %v", reply, err)
+	}
+	return srv, connect()
+}
+
+func TestNotifier(t *testing.T) {
+	srv, conn := startServer(t, "body", "body-markup", "actions")
+	actions := make(chan string, 10)
+	n, err := newNotifier(conn, "Ymuse", func(key string) { actions <- key })
+	if err != nil {
+		t.Fatalf("newNotifier() error = %v", err)
+	}
+
+	// Show a notification, then another one, which must replace it
+	img := &Image{Width: 1, Height: 1, RowStride: 4, HasAlpha: true, BitsPerSample: 8, Channels: 4, Data: []byte{1, 2, 3, 4}}
+	notification1 := Notification{
+		Summary:   "<b>Song</b>",
+		Body:      "by <b>Artist</b>",
+		Icon:      "ymuse",
+		Image:     img,
+		Actions:   []Action{{"previous", "Previous"}, {"next", "Next"}},
+		Transient: true,
+	}
+	if err := n.Show(notification1); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	if err := n.Show(Notification{Summary: "Other song", Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+
+	srv.mutex.Lock()
+	calls := srv.calls
+	srv.mutex.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("Notify() calls = %d, want 2", len(calls))
+	}
+	c := calls[0]
+	if c.replacesID != 0 || c.icon != "ymuse" || c.summary != "Song" || c.body != "by <b>Artist</b>" || c.timeout != -1 ||
+		!reflect.DeepEqual(c.actions, []string{"previous", "Previous", "next", "Next"}) {
+		t.Errorf("first Notify() call = %+v", c)
+	}
+	if v, ok := c.hints["transient"]; !ok || v.Value() != true {
+		t.Errorf("transient hint = %v", v)
+	}
+	wantImage := []interface{}{int32(1), int32(1), int32(4), true, int32(8), int32(4), []byte{1, 2, 3, 4}}
+	if v, ok := c.hints["image-data"]; !ok || !reflect.DeepEqual(v.Value(), wantImage) {
+		t.Errorf("image-data hint = %v, want %v", v, wantImage)
+	}
+	if c = calls[1]; c.replacesID != 1 || c.timeout != 5000 || len(c.hints) != 0 {
+		t.Errorf("second Notify() call = %+v, want replacing 1", c)
+	}
+
+	// Actions of the last notification are passed on, others aren't
+	_ = srv.conn.Emit(objectPath, iface+".ActionInvoked", uint32(7), "previous")
+	_ = srv.conn.Emit(objectPath, iface+".ActionInvoked", uint32(1), "next")
+	select {
+	case key := <-actions:
+		if key != "next" {
+			t.Errorf("action = %q, want next", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("action not received")
+	}
+
+	// Closing withdraws the notification
+	if err := n.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if !reflect.DeepEqual(srv.closed, []uint32{1}) {
+		t.Errorf("CloseNotification() calls = %v, want [1]", srv.closed)
+	}
+}
+
+func TestNotifier_NoMarkup(t *testing.T) {
+	srv, conn := startServer(t, "body")
+	n, err := newNotifier(conn, "Ymuse", nil)
+	if err != nil {
+		t.Fatalf("newNotifier() error = %v", err)
+	}
+	defer n.Close()
+
+	// Without markup and actions support, the body is stripped and actions are omitted
+	if err := n.Show(Notification{Summary: "Song", Body: "by <b>Artist &amp; Co</b>", Actions: []Action{{"next", "Next"}}}); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if len(srv.calls) != 1 || srv.calls[0].body != "by Artist & Co" || len(srv.calls[0].actions) != 0 {
+		t.Errorf("Notify() calls = %+v", srv.calls)
+	}
+}
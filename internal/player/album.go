@@ -0,0 +1,113 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/config"
+	"sort"
+	"strings"
+)
+
+// MPD tag names used for telling albums apart
+const (
+	albumTagAlbum       = "Album"
+	albumTagAlbumArtist = "AlbumArtist"
+	albumTagArtist      = "Artist"
+	albumTagDate        = "Date"
+	albumTagMBID        = "MUSICBRAINZ_ALBUMID"
+)
+
+// AlbumIdentity tells an album apart from others with the same title
+type AlbumIdentity struct {
+	Album       string // Album title
+	AlbumArtist string // Album artist, empty if the tracks have none
+	Artist      string // Track artist, only used if there's no album artist
+	Date        string // Album date, only used with the date grouping rule
+	MBID        string // MusicBrainz album ID, only used with the MusicBrainz grouping rule
+}
+
+// NewAlbumIdentity returns the identity of the album the track with the given attributes belongs to, according to
+// the specified grouping rule (one of the config.AlbumGrouping* constants)
+func NewAlbumIdentity(attrs mpd.Attrs, grouping string) AlbumIdentity {
+	id := AlbumIdentity{Album: attrs[albumTagAlbum]}
+
+	// A MusicBrainz ID identifies the album on its own
+	if grouping == config.AlbumGroupingMBID {
+		if id.MBID = attrs[albumTagMBID]; id.MBID != "" {
+			return id
+		}
+	}
+
+	// Use the album artist, falling back to the track artist
+	if id.AlbumArtist = attrs[albumTagAlbumArtist]; id.AlbumArtist == "" {
+		id.Artist = attrs[albumTagArtist]
+	}
+	if grouping == config.AlbumGroupingDate {
+		id.Date = attrs[albumTagDate]
+	}
+	return id
+}
+
+// ArtistName returns the name of the artist the album is credited to
+func (id AlbumIdentity) ArtistName() string {
+	if id.AlbumArtist != "" {
+		return id.AlbumArtist
+	}
+	return id.Artist
+}
+
+// FilterArgs returns arguments for MPD's find command matching tracks of the album
+func (id AlbumIdentity) FilterArgs() []string {
+	args := []string{albumTagAlbum, id.Album}
+	switch {
+	case id.MBID != "":
+		return append(args, albumTagMBID, id.MBID)
+	case id.AlbumArtist != "":
+		args = append(args, albumTagAlbumArtist, id.AlbumArtist)
+	case id.Artist != "":
+		// Only match tracks with no album artist, otherwise they belong to another album
+		args = append(args, albumTagAlbumArtist, "", albumTagArtist, id.Artist)
+	}
+	if id.Date != "" {
+		args = append(args, albumTagDate, id.Date)
+	}
+	return args
+}
+
+// AlbumIdentities returns the unique identities of albums the given tracks belong to, sorted by title, artist and date
+func AlbumIdentities(attrs []mpd.Attrs, grouping string) []AlbumIdentity {
+	seen := make(map[AlbumIdentity]bool)
+	var result []AlbumIdentity
+	for _, a := range attrs {
+		if id := NewAlbumIdentity(a, grouping); !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	// Sort the albums case-insensitively
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		for _, p := range [][2]string{{a.Album, b.Album}, {a.ArtistName(), b.ArtistName()}, {a.Date, b.Date}} {
+			if c := strings.Compare(strings.ToLower(p[0]), strings.ToLower(p[1])); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return result
+}
@@ -0,0 +1,110 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/config"
+	"reflect"
+	"testing"
+)
+
+func TestNewAlbumIdentity(t *testing.T) {
+	full := mpd.Attrs{"Album": "Hits", "AlbumArtist": "Various", "Artist": "Queen", "Date": "1981", "MUSICBRAINZ_ALBUMID": "x-1"}
+	noAlbumArtist := mpd.Attrs{"Album": "Hits", "Artist": "Queen", "Date": "1981"}
+	tests := []struct {
+		name     string
+		attrs    mpd.Attrs
+		grouping string
+		want     AlbumIdentity
+	}{
+		{"empty", mpd.Attrs{}, config.AlbumGroupingArtist, AlbumIdentity{}},
+		{"artist rule", full, config.AlbumGroupingArtist, AlbumIdentity{Album: "Hits", AlbumArtist: "Various"}},
+		{"artist rule, fallback", noAlbumArtist, config.AlbumGroupingArtist, AlbumIdentity{Album: "Hits", Artist: "Queen"}},
+		{"date rule", full, config.AlbumGroupingDate, AlbumIdentity{Album: "Hits", AlbumArtist: "Various", Date: "1981"}},
+		{"date rule, fallback", noAlbumArtist, config.AlbumGroupingDate, AlbumIdentity{Album: "Hits", Artist: "Queen", Date: "1981"}},
+		{"mbid rule", full, config.AlbumGroupingMBID, AlbumIdentity{Album: "Hits", MBID: "x-1"}},
+		{"mbid rule, no mbid", noAlbumArtist, config.AlbumGroupingMBID, AlbumIdentity{Album: "Hits", Artist: "Queen"}},
+		{"unknown rule", full, "", AlbumIdentity{Album: "Hits", AlbumArtist: "Various"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewAlbumIdentity(tt.attrs, tt.grouping); got != tt.want {
+				t.Errorf("NewAlbumIdentity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlbumIdentity_FilterArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		id   AlbumIdentity
+		want []string
+	}{
+		{"title only", AlbumIdentity{Album: "Hits"}, []string{"Album", "Hits"}},
+		{"album artist", AlbumIdentity{Album: "Hits", AlbumArtist: "Various"}, []string{"Album", "Hits", "AlbumArtist", "Various"}},
+		{"artist", AlbumIdentity{Album: "Hits", Artist: "Queen"}, []string{"Album", "Hits", "AlbumArtist", "", "Artist", "Queen"}},
+		{"date", AlbumIdentity{Album: "Hits", AlbumArtist: "Various", Date: "1981"}, []string{"Album", "Hits", "AlbumArtist", "Various", "Date", "1981"}},
+		{"mbid", AlbumIdentity{Album: "Hits", MBID: "x-1"}, []string{"Album", "Hits", "MUSICBRAINZ_ALBUMID", "x-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.FilterArgs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlbumIdentities(t *testing.T) {
+	attrs := []mpd.Attrs{
+		{"Album": "Hits", "Artist": "Queen", "Date": "1981"},
+		{"Album": "hits", "AlbumArtist": "ABBA", "Artist": "ABBA", "Date": "1992"},
+		{"Album": "Hits", "Artist": "Queen", "Date": "1991"},
+		{"Album": "Arrival", "AlbumArtist": "ABBA", "Date": "1976"},
+		{"Album": "Hits", "AlbumArtist": "ABBA", "Date": "1992"},
+	}
+	tests := []struct {
+		name     string
+		grouping string
+		want     []AlbumIdentity
+	}{
+		{"artist rule", config.AlbumGroupingArtist, []AlbumIdentity{
+			{Album: "Arrival", AlbumArtist: "ABBA"},
+			{Album: "hits", AlbumArtist: "ABBA"},
+			{Album: "Hits", AlbumArtist: "ABBA"},
+			{Album: "Hits", Artist: "Queen"},
+		}},
+		{"date rule", config.AlbumGroupingDate, []AlbumIdentity{
+			{Album: "Arrival", AlbumArtist: "ABBA", Date: "1976"},
+			{Album: "hits", AlbumArtist: "ABBA", Date: "1992"},
+			{Album: "Hits", AlbumArtist: "ABBA", Date: "1992"},
+			{Album: "Hits", Artist: "Queen", Date: "1981"},
+			{Album: "Hits", Artist: "Queen", Date: "1991"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlbumIdentities(attrs, tt.grouping); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AlbumIdentities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := AlbumIdentities(nil, config.AlbumGroupingArtist); got != nil {
+		t.Errorf("AlbumIdentities(nil) = %v, want nil", got)
+	}
+}
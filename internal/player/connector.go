@@ -18,19 +18,32 @@ package player
 import (
 	"github.com/fhs/gompd/v2/mpd"
 	"github.com/pkg/errors"
+	"github.com/yktoo/ymuse/internal/cache"
+	"github.com/yktoo/ymuse/internal/playhistory"
+	"github.com/yktoo/ymuse/internal/scrobbler"
+	"github.com/yktoo/ymuse/internal/util"
+	"maps"
+	"net"
+	"net/textproto"
+	"path"
+	"strings"
 	"sync"
 	"time"
 )
 
+// listGroupedTimeout is the time allowed for the whole of a grouped list command, including connecting to MPD
+const listGroupedTimeout = 30 * time.Second
+
 // Connector encapsulates functionality for connecting to MPD and watch for its changes
 type Connector struct {
-	mpdNetwork    string // MPD network
-	mpdAddress    string // MPD address
-	mpdPassword   string // MPD password
-	stayConnected bool   // Whether a connection is supposed to be kept alive
+	mpdNetwork        string                 // MPD network
+	mpdAddress        string                 // MPD address
+	mpdPasswordLookup func() (string, error) // Looks up the MPD password before the first connection attempt
+	stayConnected     bool                   // Whether a connection is supposed to be kept alive
 
 	mpdClient           *mpd.Client // MPD client instance
 	mpdClientConnecting bool        // Whether MPD connection is being established
+	mpdPassword         string      // MPD password, once looked up
 	mpdClientMutex      sync.RWMutex
 
 	mpdStatus      mpd.Attrs // Last reported MPD status
@@ -42,18 +55,26 @@ type Connector struct {
 	chWatcherStart chan bool // Watcher's start channel
 	chWatcherStop  chan bool // Watcher's suspend/quit channel
 
+	scrobbler     *scrobbler.Scrobbler // Scrobbler fed with the player state, nil if scrobbling is disabled
+	playTracker   *playhistory.Tracker // Play history tracker fed with the player state, nil if none
+	trackSongID   string               // ID of the song last passed to the scrobbler and the tracker
+	trackSong     mpd.Attrs            // Attributes of the song last passed to the scrobbler and the tracker
+	trackingMutex sync.Mutex
+
 	onStatusChange    func()                 // Callback for connection status change notifications
 	onHeartbeat       func()                 // Callback for periodic message notifications
 	onSubsystemChange func(subsystem string) // Callback for subsystem change notifications
+	onAuthFailure     func()                 // Callback for MPD rejecting the password or requiring one
 }
 
 // NewConnector creates and returns a new Connector instance
-func NewConnector(onStatusChange func(), onHeartbeat func(), onSubsystemChange func(subsystem string)) *Connector {
+func NewConnector(onStatusChange func(), onHeartbeat func(), onSubsystemChange func(subsystem string), onAuthFailure func()) *Connector {
 	return &Connector{
 		mpdStatus:          mpd.Attrs{},
 		onStatusChange:     onStatusChange,
 		onHeartbeat:        onHeartbeat,
 		onSubsystemChange:  onSubsystemChange,
+		onAuthFailure:      onAuthFailure,
 		chConnectorConnect: make(chan bool),
 		chConnectorQuit:    make(chan bool),
 		chWatcherStart:     make(chan bool),
@@ -62,11 +83,12 @@ func NewConnector(onStatusChange func(), onHeartbeat func(), onSubsystemChange f
 }
 
 // Start initialises the connector
+// mpdPasswordLookup: function returning the MPD password, called in the background as it may prompt the user
 // stayConnected: whether the connection must be automatically re-established when lost
-func (c *Connector) Start(mpdNetwork, mpdAddress, mpdPassword string, stayConnected bool) {
+func (c *Connector) Start(mpdNetwork, mpdAddress string, mpdPasswordLookup func() (string, error), stayConnected bool) {
 	c.mpdNetwork = mpdNetwork
 	c.mpdAddress = mpdAddress
-	c.mpdPassword = mpdPassword
+	c.mpdPasswordLookup = mpdPasswordLookup
 	c.stayConnected = stayConnected
 
 	// Start the connect goroutine
@@ -85,6 +107,32 @@ func (c *Connector) Status() mpd.Attrs {
 	return c.mpdStatus
 }
 
+// SetScrobbler replaces the scrobbler fed with the player state, closing the previous one, if any. nil disables
+// scrobbling
+func (c *Connector) SetScrobbler(s *scrobbler.Scrobbler) {
+	c.trackingMutex.Lock()
+	prev := c.scrobbler
+	c.scrobbler = s
+	c.trackSongID = ""
+	c.trackingMutex.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// SetPlayTracker replaces the play history tracker fed with the player state, finishing the current play of the
+// previous one, if any. nil disables play tracking
+func (c *Connector) SetPlayTracker(t *playhistory.Tracker) {
+	c.trackingMutex.Lock()
+	prev := c.playTracker
+	c.playTracker = t
+	c.trackSongID = ""
+	c.trackingMutex.Unlock()
+	if prev != nil {
+		prev.Finish()
+	}
+}
+
 // Stop signals the connector to shut down
 func (c *Connector) Stop() {
 	// Ignore if not connected/connecting
@@ -114,6 +162,33 @@ func (c *Connector) Stop() {
 	c.onStatusChange()
 }
 
+// GetAlbumArt returns album art for the track with the given URI, scaled to fit into a square of the given size. Album
+// art of local tracks is served from the album art cache whenever possible. Returns nil if no album art is available
+func (c *Connector) GetAlbumArt(uri string, size int) []byte {
+	// Streams aren't cached
+	ac := cache.GetAlbumArtCache()
+	if ac == nil || util.IsStreamURI(uri) {
+		albumArt, _ := c.fetchAlbumArt(uri)
+		return albumArt
+	}
+
+	// Try the cache first
+	dir := path.Dir(uri)
+	if albumArt, ok := ac.Get(dir, size); ok {
+		return albumArt
+	}
+
+	// Fetch the album art from MPD and cache it
+	albumArt, ok := c.fetchAlbumArt(uri)
+	if !ok {
+		return nil
+	}
+	if resized, err := ac.Put(dir, size, albumArt); !errCheck(err, "Put() failed") {
+		return resized
+	}
+	return albumArt
+}
+
 // GetPlaylists queries and returns a slice of playlist names available in MPD
 func (c *Connector) GetPlaylists() []string {
 	// Fetch the list of playlists
@@ -143,6 +218,55 @@ func (c *Connector) IfConnected(funcIfConnected func(client *mpd.Client)) {
 	}
 }
 
+// ListGrouped runs MPD's list command for the given tag with the given filter and group arguments, and returns an entry
+// for every listed value, also containing the values of the tags it's grouped by. The MPD client drops tag names from
+// the command's output, which makes grouped results ambiguous, so the command is run over a connection of its own.
+// Returns nil if there's no connection with MPD. The command may take a while, so it shouldn't be run on the GUI thread
+func (c *Connector) ListGrouped(tag string, args ...string) ([]mpd.Attrs, error) {
+	if connected, _ := c.ConnectStatus(); !connected {
+		return nil, nil
+	}
+
+	// Connect, limiting the time allowed for the entire exchange, and skip the greeting
+	nc, err := net.DialTimeout(c.mpdNetwork, c.mpdAddress, listGroupedTimeout)
+	if err != nil {
+		return nil, errors.Errorf("DialTimeout() failed: %v", err)
+	}
+	conn := textproto.NewConn(nc)
+	defer func() { errCheck(conn.Close(), "ListGrouped(): Close() failed") }()
+	if err := nc.SetDeadline(time.Now().Add(listGroupedTimeout)); err != nil {
+		return nil, err
+	}
+	if line, err := conn.ReadLine(); err != nil {
+		return nil, err
+	} else if !strings.HasPrefix(line, "OK MPD ") {
+		return nil, errors.Errorf("unexpected MPD greeting: %s", line)
+	}
+
+	// Authenticate, if needed
+	c.mpdClientMutex.RLock()
+	password := c.mpdPassword
+	c.mpdClientMutex.RUnlock()
+	if password != "" {
+		if err := conn.PrintfLine("password %s", FilterQuote(password)); err != nil {
+			return nil, err
+		}
+		if _, err := readGroupedList(&conn.Reader, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Run the command
+	cmd := "list " + FilterQuote(tag)
+	for _, a := range args {
+		cmd += " " + FilterQuote(a)
+	}
+	if err := conn.PrintfLine("%s", cmd); err != nil {
+		return nil, err
+	}
+	return readGroupedList(&conn.Reader, tag)
+}
+
 // IsConnected returns whether there's a connection with MPD and whether it's being established
 func (c *Connector) ConnectStatus() (bool, bool) {
 	c.mpdClientMutex.RLock()
@@ -150,6 +274,73 @@ func (c *Connector) ConnectStatus() (bool, bool) {
 	return c.mpdClient != nil, c.mpdClientConnecting
 }
 
+// fetchAlbumArt fetches and returns album art for the track with the given URI from MPD, preferring the embedded
+// picture over a cover file. The second return value is false if MPD isn't connected
+func (c *Connector) fetchAlbumArt(uri string) (albumArt []byte, fetched bool) {
+	log.Debugf("Fetching album art for %s", uri)
+	c.IfConnected(func(client *mpd.Client) {
+		var err error
+		fetched = true
+
+		// Try the embedded image first
+		if albumArt, err = client.ReadPicture(uri); err == nil && len(albumArt) > 0 {
+			log.Debugf("Fetched embedded album art: %d bytes", len(albumArt))
+			return
+		}
+		log.Debugf("Failed to obtain embedded album art: %v", err)
+
+		// Then image from a cover file
+		if albumArt, err = client.AlbumArt(uri); err == nil && len(albumArt) > 0 {
+			log.Debugf("Fetched album art from cover file: %d bytes", len(albumArt))
+			return
+		}
+		log.Debugf("Failed to obtain album art from cover.* file: %v", err)
+		albumArt = nil
+	})
+	return
+}
+
+// feedPlayState passes the player state described by the given MPD status to the scrobbler and the play history
+// tracker, if any
+func (c *Connector) feedPlayState(status mpd.Attrs) {
+	c.trackingMutex.Lock()
+	s, t := c.scrobbler, c.playTracker
+	if s == nil && t == nil {
+		c.trackingMutex.Unlock()
+		return
+	}
+
+	// Only fetch the current song when it changes
+	songID := status["songid"]
+	if songID != c.trackSongID {
+		c.trackSongID, c.trackSong = "", nil
+		c.IfConnected(func(client *mpd.Client) {
+			song, err := client.CurrentSong()
+			// The song might have changed again in the meantime, then try next time
+			if !errCheck(err, "feedPlayState(): CurrentSong() failed") && song["Id"] == songID {
+				c.trackSongID, c.trackSong = songID, song
+			}
+		})
+	}
+	trackSongID, trackSong := c.trackSongID, c.trackSong
+	c.trackingMutex.Unlock()
+
+	// Feed the state outside the lock as recording a play involves writing to disk
+	playing := status["state"] == "play"
+	elapsed := util.SecondsToDuration(status["elapsed"])
+	if s != nil {
+		s.Update(scrobbler.PlayerState{
+			SongID:  trackSongID,
+			Track:   scrobblerTrack(trackSong),
+			Playing: playing,
+			Elapsed: elapsed,
+		})
+	}
+	if t != nil {
+		t.Update(trackSongID, historyPlay(trackSong), playing, elapsed)
+	}
+}
+
 // setStatus sets the current MPD status, thread-safely
 func (c *Connector) setStatus(attrs mpd.Attrs) {
 	c.mpdStatusMutex.Lock()
@@ -189,7 +380,7 @@ func (c *Connector) connect() {
 func (c *Connector) doConnect(connect, heartbeat bool) {
 	var err error
 	var client *mpd.Client
-	var wasConnected bool
+	var wasConnected, authFailed bool
 	connected, _ := c.ConnectStatus()
 
 	// If there's a request to connect and not connected yet
@@ -202,11 +393,25 @@ func (c *Connector) doConnect(connect, heartbeat bool) {
 		// Notify the callback we're about to connect
 		c.onStatusChange()
 
+		// Look up the password before the first attempt
+		if c.mpdPasswordLookup != nil {
+			password, err := c.mpdPasswordLookup()
+			errCheck(err, "Failed to look up MPD password")
+			c.mpdClientMutex.Lock()
+			c.mpdPassword = password
+			c.mpdClientMutex.Unlock()
+			c.mpdPasswordLookup = nil
+		}
+
 		// Try to connect
 		log.Debugf("Connecting to MPD (network=%v, address=%v)", c.mpdNetwork, c.mpdAddress)
 		if client, err = mpd.DialAuthenticated(c.mpdNetwork, c.mpdAddress, c.mpdPassword); err == nil {
 			connected = true
 		} else {
+			authFailed = isAuthError(err)
+			if client != nil {
+				errCheck(client.Close(), "doConnect(): Close() failed")
+			}
 			err = errors.Errorf("DialAuthenticated() failed: %v", err)
 		}
 	}
@@ -226,6 +431,7 @@ func (c *Connector) doConnect(connect, heartbeat bool) {
 			go func() { c.chWatcherStart <- true }()
 		} else {
 			connected = false
+			authFailed = isAuthError(err)
 			err = errors.Errorf("Status() after dial failed: %v", err)
 			// Disconnect since we're not "fully connected"
 			errCheck(client.Close(), "doConnect(): Close() failed")
@@ -271,6 +477,17 @@ func (c *Connector) doConnect(connect, heartbeat bool) {
 		c.onStatusChange()
 	}
 
+	// Retrying with the same password is pointless: stop reconnecting and let the callback deal with it
+	if authFailed {
+		c.stayConnected = false
+		c.onAuthFailure()
+	}
+
+	// Let the scrobbler and the play tracker know of the elapsed time
+	if connected && heartbeat {
+		c.feedPlayState(status)
+	}
+
 	if heartbeat {
 		// No connection (anymore), re-attempt connection if needed, but not more frequently than once in a heartbeat
 		if !connected && c.stayConnected {
@@ -334,6 +551,11 @@ func (c *Connector) watch() {
 			// Update the MPD's status
 			c.setStatus(status)
 
+			// Let the scrobbler and the play tracker know of a song change
+			if subsystem == "player" {
+				c.feedPlayState(status)
+			}
+
 			// Notify the callback
 			c.onSubsystemChange(subsystem)
 
@@ -363,3 +585,84 @@ func (c *Connector) watch() {
 		}
 	}
 }
+
+// isAuthError returns whether the given error means MPD has rejected the password or requires one
+func isAuthError(err error) bool {
+	var mpdErr mpd.Error
+	return errors.As(err, &mpdErr) && (mpdErr.Code == mpd.ErrorPassword || mpdErr.Code == mpd.ErrorPermission)
+}
+
+// mpdCommandArgs converts the given strings into arguments for mpd.Client.Command(). The client runs the formatted
+// command through fmt once more, so percent signs in the arguments need to be doubled
+func mpdCommandArgs(args ...string) []interface{} {
+	result := make([]interface{}, len(args))
+	for i, a := range args {
+		result[i] = strings.ReplaceAll(a, "%", "%%")
+	}
+	return result
+}
+
+// readGroupedList reads a response to MPD's list command, returning an entry for every value of the given tag. Group
+// tag values are only output when they change, so every entry gets the values last seen
+func readGroupedList(r *textproto.Reader, tag string) ([]mpd.Attrs, error) {
+	var result []mpd.Attrs
+	groups := mpd.Attrs{}
+	for {
+		line, err := r.ReadLine()
+		switch {
+		case err != nil:
+			return nil, err
+		case line == "OK":
+			return result, nil
+		case strings.HasPrefix(line, "ACK "):
+			return nil, errors.Errorf("MPD error: %s", line[4:])
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, errors.Errorf("can't parse line: %s", line)
+		}
+		groups[key] = value
+		if key == tag {
+			result = append(result, maps.Clone(groups))
+		}
+	}
+}
+
+// historyPlay converts the given MPD song attributes into a play history template
+func historyPlay(song mpd.Attrs) playhistory.Play {
+	title := song["Title"]
+	if title == "" {
+		title = song["Name"]
+	}
+	return playhistory.Play{
+		URI:         song["file"],
+		Artist:      song["Artist"],
+		AlbumArtist: song["AlbumArtist"],
+		Album:       song["Album"],
+		Title:       title,
+		Genre:       song["Genre"],
+		Duration:    int(songDuration(song).Round(time.Second).Seconds()),
+	}
+}
+
+// scrobblerTrack converts the given MPD song attributes into a scrobbler track
+func scrobblerTrack(song mpd.Attrs) scrobbler.Track {
+	return scrobbler.Track{
+		Artist:      song["Artist"],
+		Title:       song["Title"],
+		Album:       song["Album"],
+		AlbumArtist: song["AlbumArtist"],
+		TrackNumber: song["Track"],
+		Duration:    songDuration(song),
+		MBID:        song["MUSICBRAINZ_TRACKID"],
+	}
+}
+
+// songDuration returns the duration of the song with the given MPD attributes, zero if unknown
+func songDuration(song mpd.Attrs) time.Duration {
+	duration := song["duration"]
+	if duration == "" {
+		duration = song["Time"]
+	}
+	return util.SecondsToDuration(duration)
+}
@@ -0,0 +1,80 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMpdCommandArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "count"},
+		{[]string{"Album", "Abba"}, "count Album Abba"},
+		{[]string{"Album", "100%"}, "count Album 100%"},
+		{[]string{"file", "music/%d%s%%.mp3"}, "count file music/%d%s%%.mp3"},
+	}
+	for _, tt := range tests {
+		// Format the command twice, the way the MPD client does
+		cmd := "count" + strings.Repeat(" %s", len(tt.args))
+		if got := fmt.Sprintf(fmt.Sprintf(cmd, mpdCommandArgs(tt.args...)...)); got != tt.want {
+			t.Errorf("mpdCommandArgs(%q) formats as %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestReadGroupedList(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    string
+		want    []mpd.Attrs
+		wantErr bool
+	}{
+		{"empty", "OK\n", nil, false},
+		{"ungrouped", "Album: A\nAlbum: B\nOK\n", []mpd.Attrs{{"Album": "A"}, {"Album": "B"}}, false},
+		{"grouped",
+			"AlbumArtist: X\nArtist: P\nAlbum: A\nAlbum: B\nArtist: Q\nAlbum: C\nAlbumArtist: \nArtist: R\nAlbum: A\nOK\n",
+			[]mpd.Attrs{
+				{"AlbumArtist": "X", "Artist": "P", "Album": "A"},
+				{"AlbumArtist": "X", "Artist": "P", "Album": "B"},
+				{"AlbumArtist": "X", "Artist": "Q", "Album": "C"},
+				{"AlbumArtist": "", "Artist": "R", "Album": "A"},
+			},
+			false},
+		{"error", "ACK [2@0] {list} unknown tag\n", nil, true},
+		{"garbage", "Album\nOK\n", nil, true},
+		{"truncated", "Album: A\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readGroupedList(textproto.NewReader(bufio.NewReader(strings.NewReader(tt.resp))), "Album")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readGroupedList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readGroupedList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
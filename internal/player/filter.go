@@ -0,0 +1,148 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"strings"
+)
+
+// FilterOp is a comparison operator used in an MPD filter expression
+type FilterOp string
+
+const (
+	FilterOpEqual      FilterOp = "=="          // Tag value equals the given value
+	FilterOpNotEqual   FilterOp = "!="          // Tag value differs from the given value
+	FilterOpContains   FilterOp = "contains"    // Tag value contains the given substring
+	FilterOpStartsWith FilterOp = "starts_with" // Tag value starts with the given string (MPD 0.24+)
+	FilterOpRegex      FilterOp = "=~"          // Tag value matches the given Perl-compatible regular expression
+)
+
+// FilterOps lists all supported tag comparison operators, in the display order
+var FilterOps = []FilterOp{FilterOpEqual, FilterOpNotEqual, FilterOpContains, FilterOpStartsWith, FilterOpRegex}
+
+const (
+	FilterAttrAny           = "any"            // Pseudo-attribute matching any tag
+	FilterAttrBase          = "base"           // Pseudo-attribute restricting the search to a directory
+	FilterAttrModifiedSince = "modified-since" // Pseudo-attribute restricting the search to files modified since a time
+	FilterAttrAddedSince    = "added-since"    // Pseudo-attribute restricting the search to files added since a time (MPD 0.24+)
+)
+
+// FilterQuote escapes the given value and encloses it in double quotes, so that it can be safely embedded into an MPD
+// filter expression. NB: the resulting expression must additionally be quoted on the protocol level, which gompd does
+// for command arguments
+func FilterQuote(value string) string {
+	var b strings.Builder
+	b.Grow(len(value) + 2)
+	b.WriteByte('"')
+	for _, c := range []byte(value) {
+		// Quotes and backslashes must be escaped with a backslash
+		switch c {
+		case '"', '\'', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// FilterTag returns an expression comparing the given tag with the value using the provided operator
+func FilterTag(tag string, op FilterOp, value string) string {
+	return "(" + tag + " " + string(op) + " " + FilterQuote(value) + ")"
+}
+
+// FilterBase returns an expression matching songs in the given directory (relative to the music directory)
+func FilterBase(uri string) string {
+	return "(" + FilterAttrBase + " " + FilterQuote(uri) + ")"
+}
+
+// FilterModifiedSince returns an expression matching songs modified since the given time, which is either an ISO 8601
+// time or a UNIX timestamp
+func FilterModifiedSince(since string) string {
+	return "(" + FilterAttrModifiedSince + " " + FilterQuote(since) + ")"
+}
+
+// FilterAddedSince returns an expression matching songs added to the database since the given time, which is either
+// an ISO 8601 time or a UNIX timestamp. Requires MPD 0.24+
+func FilterAddedSince(since string) string {
+	return "(" + FilterAttrAddedSince + " " + FilterQuote(since) + ")"
+}
+
+// FilterNot returns a negation of the given expression
+func FilterNot(expr string) string {
+	return "(!" + expr + ")"
+}
+
+// FilterAnd returns a conjunction of the given expressions. An empty string is returned if no expression is given
+func FilterAnd(exprs ...string) string {
+	switch len(exprs) {
+	case 0:
+		return ""
+	case 1:
+		return exprs[0]
+	}
+	return "(" + strings.Join(exprs, " AND ") + ")"
+}
+
+// FilterClause is a single condition of a FilterQuery
+type FilterClause struct {
+	Attr   string   // Name of the MPD attribute or one of the FilterAttr* pseudo-attributes
+	Op     FilterOp // Comparison operator, ignored for pseudo-attributes other than FilterAttrAny
+	Value  string   // Value to compare with
+	Negate bool     // Whether the condition must be negated
+	Or     bool     // Whether the clause is OR'ed (rather than AND'ed) with the preceding one
+}
+
+// Expression returns the clause as an MPD filter expression
+func (c *FilterClause) Expression() string {
+	var expr string
+	switch c.Attr {
+	case FilterAttrBase:
+		expr = FilterBase(c.Value)
+	case FilterAttrModifiedSince:
+		expr = FilterModifiedSince(c.Value)
+	case FilterAttrAddedSince:
+		expr = FilterAddedSince(c.Value)
+	default:
+		expr = FilterTag(c.Attr, c.Op, c.Value)
+	}
+	if c.Negate {
+		expr = FilterNot(expr)
+	}
+	return expr
+}
+
+// FilterQuery is a list of clauses combined with AND and OR, whereby AND takes precedence over OR
+type FilterQuery []FilterClause
+
+// Expressions converts the query into a list of MPD filter expressions. Since MPD filters don't support disjunction,
+// each returned expression represents an AND'ed group of clauses, and the query result is the union of the results for
+// all the expressions
+func (q FilterQuery) Expressions() []string {
+	var result, group []string
+	for i := range q {
+		// An OR clause starts a new group
+		if q[i].Or && len(group) > 0 {
+			result = append(result, FilterAnd(group...))
+			group = nil
+		}
+		group = append(group, q[i].Expression())
+	}
+	if len(group) > 0 {
+		result = append(result, FilterAnd(group...))
+	}
+	return result
+}
@@ -0,0 +1,143 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty string", "", `""`},
+		{"plain string", "Pink Floyd", `"Pink Floyd"`},
+		{"double quote", `The "Best" Of`, `"The \"Best\" Of"`},
+		{"single quote", "Rock'n'Roll", `"Rock\'n\'Roll"`},
+		{"backslash", `AC\DC`, `"AC\\DC"`},
+		{"trailing backslash", `foo\`, `"foo\\"`},
+		{"injection attempt", `x") OR (Artist == "y`, `"x\") OR (Artist == \"y"`},
+		{"parentheses", "(Live)", `"(Live)"`},
+		{"unicode", "Björk — Homogenic", `"Björk — Homogenic"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterQuote(tt.value); got != tt.want {
+				t.Errorf("FilterQuote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTag(t *testing.T) {
+	type args struct {
+		tag   string
+		op    FilterOp
+		value string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"equal", args{"Artist", FilterOpEqual, "Queen"}, `(Artist == "Queen")`},
+		{"not equal", args{"Genre", FilterOpNotEqual, "Pop"}, `(Genre != "Pop")`},
+		{"contains", args{"any", FilterOpContains, `say "hi"`}, `(any contains "say \"hi\"")`},
+		{"starts with", args{"Album", FilterOpStartsWith, "The"}, `(Album starts_with "The")`},
+		{"regex", args{"Title", FilterOpRegex, `^\d+$`}, `(Title =~ "^\\d+$")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterTag(tt.args.tag, tt.args.op, tt.args.value); got != tt.want {
+				t.Errorf("FilterTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		exprs []string
+		want  string
+	}{
+		{"no expressions", nil, ""},
+		{"single expression", []string{`(Artist == "A")`}, `(Artist == "A")`},
+		{"two expressions", []string{`(Artist == "A")`, `(Album == "B")`}, `((Artist == "A") AND (Album == "B"))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterAnd(tt.exprs...); got != tt.want {
+				t.Errorf("FilterAnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterClause_Expression(t *testing.T) {
+	tests := []struct {
+		name   string
+		clause FilterClause
+		want   string
+	}{
+		{"tag", FilterClause{Attr: "Artist", Op: FilterOpContains, Value: "Beatles"}, `(Artist contains "Beatles")`},
+		{"negated tag", FilterClause{Attr: "Genre", Op: FilterOpEqual, Value: "Jazz", Negate: true}, `(!(Genre == "Jazz"))`},
+		{"base", FilterClause{Attr: FilterAttrBase, Op: FilterOpEqual, Value: `Rock/"Quoted"`}, `(base "Rock/\"Quoted\"")`},
+		{"modified since", FilterClause{Attr: FilterAttrModifiedSince, Value: "2024-01-31T00:00:00Z"}, `(modified-since "2024-01-31T00:00:00Z")`},
+		{"added since", FilterClause{Attr: FilterAttrAddedSince, Value: "1700000000"}, `(added-since "1700000000")`},
+		{"negated base", FilterClause{Attr: FilterAttrBase, Value: "Podcasts", Negate: true}, `(!(base "Podcasts"))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.clause.Expression(); got != tt.want {
+				t.Errorf("Expression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterQuery_Expressions(t *testing.T) {
+	a := FilterClause{Attr: "Artist", Op: FilterOpEqual, Value: "A"}
+	b := FilterClause{Attr: "Album", Op: FilterOpEqual, Value: "B"}
+	c := FilterClause{Attr: "Genre", Op: FilterOpEqual, Value: "C", Or: true}
+	d := FilterClause{Attr: "Date", Op: FilterOpStartsWith, Value: "19", Negate: true}
+	tests := []struct {
+		name  string
+		query FilterQuery
+		want  []string
+	}{
+		{"empty query", nil, nil},
+		{"single clause", FilterQuery{a}, []string{`(Artist == "A")`}},
+		{"leading OR is ignored", FilterQuery{c}, []string{`(Genre == "C")`}},
+		{"AND", FilterQuery{a, b}, []string{`((Artist == "A") AND (Album == "B"))`}},
+		{"OR", FilterQuery{a, c}, []string{`(Artist == "A")`, `(Genre == "C")`}},
+		{
+			"AND takes precedence over OR",
+			FilterQuery{a, b, c, d},
+			[]string{`((Artist == "A") AND (Album == "B"))`, `((Genre == "C") AND (!(Date starts_with "19")))`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Expressions(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expressions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
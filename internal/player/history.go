@@ -0,0 +1,288 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/playhistory"
+	"github.com/yktoo/ymuse/internal/util"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// History list store columns
+	historyColTime     = 0
+	historyColArtist   = 1
+	historyColTitle    = 2
+	historyColAlbum    = 3
+	historyColListened = 4
+	historyColStatus   = 5
+
+	// History statistics list store columns
+	historyStatsColName      = 0
+	historyStatsColDetail    = 1
+	historyStatsColPlays     = 2
+	historyStatsColCompleted = 3
+	historyStatsColListened  = 4
+
+	// Maximum number of plays displayed on the History page
+	historyMaxPlays = 1000
+)
+
+func (w *MainWindow) onHistoryClear() {
+	if util.ConfirmDialog(w.AppWindow, glib.Local("Clear history"), glib.Local("Are you sure you want to clear the whole listening history?")) {
+		w.errCheckDialog(w.playHistory.Clear(), glib.Local("Failed to clear listening history"))
+		w.updateHistory()
+	}
+}
+
+func (w *MainWindow) onHistoryExport() {
+	// Ask for the file name. The format is chosen by the extension
+	filters := []util.FileFilterSpec{
+		{Name: glib.Local("CSV files"), Patterns: []string{"*.csv", "*.CSV"}},
+		{Name: glib.Local("JSON files"), Patterns: []string{"*.json", "*.JSON"}},
+	}
+	fileName, ok := util.SaveFileDialog(w.AppWindow, glib.Local("Export listening history"), "listening-history.csv", glib.Local("Export"), filters)
+	if !ok {
+		return
+	}
+
+	// Write out the plays of the selected period, oldest first
+	var buf bytes.Buffer
+	var err error
+	plays := w.getHistoryPeriodPlays()
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		err = playhistory.WriteJSON(&buf, plays)
+	} else {
+		err = playhistory.WriteCSV(&buf, plays)
+	}
+	if err == nil {
+		err = os.WriteFile(fileName, buf.Bytes(), 0644)
+	}
+	w.errCheckDialog(err, glib.Local("Failed to export listening history"))
+}
+
+func (w *MainWindow) onHistoryPlayRecorded(playhistory.Play) {
+	glib.IdleAdd(func() {
+		// Ignore when not mapped
+		if w.mapped {
+			w.updateHistory()
+		}
+	})
+}
+
+func (w *MainWindow) onHistoryRowActivated() {
+	w.applyHistorySelection(tbNone)
+}
+
+func (w *MainWindow) onHistoryStackSwitched() {
+	w.updateHistoryActions()
+	w.focusMainList()
+}
+
+func (w *MainWindow) onHistoryStatsChanged() {
+	if w.historyUpdating {
+		return
+	}
+	cfg := config.GetConfig()
+	cfg.HistoryStatsPeriod = w.HistoryPeriodComboBox.GetActiveID()
+	cfg.HistoryStatsCategory = w.HistoryStatsCategoryComboBox.GetActiveID()
+	w.updateHistory()
+}
+
+func (w *MainWindow) onHistoryStatsRowActivated(_ *gtk.TreeView, path *gtk.TreePath) {
+	if ix := path.GetIndices(); len(ix) > 0 && ix[0] < len(w.historyStats) {
+		w.librarySearchFor(w.historyStats[ix[0]].Name)
+	}
+}
+
+// applyHistorySelection adds or replaces the content of the queue with the tracks selected on the History page
+func (w *MainWindow) applyHistorySelection(replace triBool) {
+	uris := w.getHistorySelectedURIs()
+	if len(uris) == 0 {
+		return
+	}
+
+	var err error
+	replaced := replace == tbTrue || replace == tbNone && config.GetConfig().TrackDefaultReplace
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+
+		// Clear the queue, if needed
+		if replaced {
+			commands.Clear()
+		}
+
+		// Add the URIs of the tracks
+		for _, uri := range uris {
+			commands.Add(uri)
+		}
+
+		// Run the commands
+		err = commands.End()
+	})
+
+	// Check for error
+	if w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue")) {
+		return
+	}
+
+	// Initiate post-replace actions, if necessary
+	if replaced {
+		w.queueReplaced()
+	}
+}
+
+// getHistoryPeriodPlays returns the recorded plays within the period selected on the History page, oldest first
+func (w *MainWindow) getHistoryPeriodPlays() []playhistory.Play {
+	period := playhistory.Period(w.HistoryPeriodComboBox.GetActiveID())
+	return playhistory.Since(w.playHistory.Plays(), playhistory.PeriodStart(period, time.Now()))
+}
+
+// getHistorySelectedURIs returns URIs of the tracks selected in the recent plays list, without duplicates
+func (w *MainWindow) getHistorySelectedURIs() []string {
+	var uris []string
+	w.HistoryTreeSelection.SelectedForEach(func(_ *gtk.TreeModel, path *gtk.TreePath, _ *gtk.TreeIter) {
+		if ix := path.GetIndices(); len(ix) > 0 && ix[0] < len(w.historyPlays) {
+			if uri := w.historyPlays[ix[0]].URI; uri != "" && !slices.Contains(uris, uri) {
+				uris = append(uris, uri)
+			}
+		}
+	})
+	return uris
+}
+
+// initHistoryWidgets initialises History page widgets and actions
+func (w *MainWindow) initHistoryWidgets() {
+	// Create actions
+	w.aHistoryAppend = w.addAction("history.append", "", func() { w.applyHistorySelection(tbFalse) })
+	w.aHistoryReplace = w.addAction("history.replace", "", func() { w.applyHistorySelection(tbTrue) })
+	w.aHistoryExport = w.addAction("history.export", "", w.onHistoryExport)
+	w.aHistoryClear = w.addAction("history.clear", "", w.onHistoryClear)
+
+	// Restore the statistics settings
+	cfg := config.GetConfig()
+	w.historyUpdating = true
+	if !w.HistoryPeriodComboBox.SetActiveID(cfg.HistoryStatsPeriod) {
+		w.HistoryPeriodComboBox.SetActiveID(config.HistoryStatsPeriodMonth)
+	}
+	if !w.HistoryStatsCategoryComboBox.SetActiveID(cfg.HistoryStatsCategory) {
+		w.HistoryStatsCategoryComboBox.SetActiveID(config.HistoryStatsCategoryArtist)
+	}
+	w.historyUpdating = false
+
+	// Load the listening history. On failure, continue with whatever could be loaded
+	var err error
+	w.playHistory, err = playhistory.Open(path.Join(glib.GetUserDataDir(), "ymuse", "play-history.jsonl"))
+	errCheck(err, "Failed to load listening history")
+}
+
+// updateHistory fills the History page with the plays and statistics of the selected period
+func (w *MainWindow) updateHistory() {
+	plays := w.getHistoryPeriodPlays()
+
+	// Fill in the recent plays list, newest first
+	w.HistoryListStore.Clear()
+	w.historyPlays = nil
+	now := time.Now()
+	var total int
+	for i := len(plays) - 1; i >= 0; i-- {
+		p := plays[i]
+		total += p.Listened
+		if len(w.historyPlays) >= historyMaxPlays {
+			continue
+		}
+		w.historyPlays = append(w.historyPlays, p)
+
+		// Omit the date for today's plays
+		t := p.Start.Local()
+		layout := "2006-01-02 15:04"
+		if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+			layout = "15:04"
+		}
+		title := p.Title
+		if title == "" {
+			title = path.Base(p.URI)
+		}
+		status := glib.Local("Skipped")
+		if p.Completed {
+			status = glib.Local("Completed")
+		}
+		errCheck(
+			w.HistoryListStore.InsertWithValues(
+				nil,
+				-1,
+				[]int{historyColTime, historyColArtist, historyColTitle, historyColAlbum, historyColListened, historyColStatus},
+				[]interface{}{t.Format(layout), p.Artist, title, p.Album, util.FormatSeconds(float64(p.Listened)), status}),
+			"HistoryListStore.InsertWithValues() failed")
+	}
+
+	// Fill in the statistics
+	category := playhistory.Category(w.HistoryStatsCategoryComboBox.GetActiveID())
+	w.HistoryStatsListStore.Clear()
+	w.historyStats = playhistory.Stats(plays, category)
+	w.HistoryStatsDetailColumn.SetVisible(category == playhistory.CategoryAlbum)
+	for _, item := range w.historyStats {
+		errCheck(
+			w.HistoryStatsListStore.InsertWithValues(
+				nil,
+				-1,
+				[]int{historyStatsColName, historyStatsColDetail, historyStatsColPlays, historyStatsColCompleted, historyStatsColListened},
+				[]interface{}{
+					item.Name,
+					item.Detail,
+					strconv.Itoa(item.Plays),
+					strconv.Itoa(item.Completed),
+					util.FormatHoursMinutes(item.Listened.Seconds()),
+				}),
+			"HistoryStatsListStore.InsertWithValues() failed")
+	}
+
+	// Update info
+	var info string
+	switch n := len(plays); {
+	case n == 0:
+		info = glib.Local("No plays recorded in this period")
+	case n > len(w.historyPlays):
+		info = fmt.Sprintf(glib.Local("%d plays, %s listened, showing the latest %d"), n, util.FormatSeconds(float64(total)), len(w.historyPlays))
+	default:
+		info = fmt.Sprintf(glib.Local("%d plays, %s listened"), n, util.FormatSeconds(float64(total)))
+	}
+	w.HistoryInfoLabel.SetText(info)
+	w.updateHistoryActions()
+}
+
+// updateHistoryActions updates the actions and widgets of the History page
+func (w *MainWindow) updateHistoryActions() {
+	stats := w.HistoryStack.GetVisibleChildName() == "stats"
+	selected := !stats && w.HistoryTreeSelection.CountSelectedRows() > 0
+	w.aHistoryAppend.SetEnabled(selected)
+	w.aHistoryReplace.SetEnabled(selected)
+	w.aHistoryExport.SetEnabled(len(w.historyPlays) > 0)
+	w.aHistoryClear.SetEnabled(w.playHistory.Len() > 0)
+	w.HistoryStatsCategoryComboBox.SetVisible(stats)
+}
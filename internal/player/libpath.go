@@ -22,7 +22,9 @@ import (
 	"github.com/yktoo/ymuse/internal/config"
 	"github.com/yktoo/ymuse/internal/util"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // LibraryPathElement represents one element in the library path
@@ -58,25 +60,50 @@ type DetailsHolder interface {
 	Details() string
 }
 
+// FilterArgsHolder represents an object identified by several MPD attributes rather than a single one
+type FilterArgsHolder interface {
+	FilterArgs() []string // Attribute names and values, in pairs
+}
+
+// FilterExpressionHolder represents an object restricting the elements below it with MPD filter expressions
+type FilterExpressionHolder interface {
+	FilterExpressions() []string // MPD filter expressions, all of which must match
+}
+
 // PlaylistHolder represents an object that references a playlist
 type PlaylistHolder interface {
 	PlaylistName() string
 }
 
+// PlaylistEntryHolder represents an object that references an entry of a stored playlist
+type PlaylistEntryHolder interface {
+	EntryPlaylist() string // Name of the playlist containing the entry
+	EntryPos() int         // Entry's position in the playlist
+}
+
+// FilterHolder represents an object whose contents is defined by MPD filter expressions
+type FilterHolder interface {
+	Filters() []string // MPD filter expressions whose results are combined
+}
+
 var elementConstructors = map[string]func() LibraryPathElement{
-	"lvlup":      NewLevelUpLibElement,
-	"filesystem": NewFilesystemLibElement,
-	"dir":        NewDirLibElement,
-	"file":       NewFileLibElement,
-	"playlists":  NewPlaylistsLibElement,
-	"playlist":   NewPlaylistLibElement,
-	"genres":     NewGenresLibElement,
-	"genre":      NewGenreLibElement,
-	"artists":    NewArtistsLibElement,
-	"artist":     NewArtistLibElement,
-	"albums":     NewAlbumsLibElement,
-	"album":      NewAlbumLibElement,
-	"track":      NewTrackLibElement,
+	"lvlup":        NewLevelUpLibElement,
+	"filesystem":   NewFilesystemLibElement,
+	"dir":          NewDirLibElement,
+	"file":         NewFileLibElement,
+	"playlists":    NewPlaylistsLibElement,
+	"playlist":     NewPlaylistLibElement,
+	"plentry":      NewPlaylistEntryLibElement,
+	"genres":       NewGenresLibElement,
+	"genre":        NewGenreLibElement,
+	"artists":      NewArtistsLibElement,
+	"artist":       NewArtistLibElement,
+	"albums":       NewAlbumsLibElement,
+	"album":        NewAlbumLibElement,
+	"track":        NewTrackLibElement,
+	"smartfolder":  NewSmartFolderLibElement,
+	"recent":       NewRecentLibElement,
+	"recentwindow": NewRecentWindowLibElement,
 }
 
 const (
@@ -145,10 +172,15 @@ func AttrsToElements(attrs []mpd.Attrs, uriPrefix string) []LibraryPathElement {
 // LibraryPath
 //----------------------------------------------------------------------------------------------------------------------
 
-// LibraryPath holds a series of LibraryPathElement's
+// libraryPathMaxHistory is the maximum number of entries kept in the back/forward history of a LibraryPath
+const libraryPathMaxHistory = 100
+
+// LibraryPath holds a series of LibraryPathElement's, along with the back/forward navigation history
 type LibraryPath struct {
-	elements  []LibraryPathElement // Internal list of elements
-	onChanged func()               // On path change callback
+	elements  []LibraryPathElement   // Internal list of elements
+	back      [][]LibraryPathElement // Previously visited paths, the most recent last
+	forward   [][]LibraryPathElement // Paths navigated back from, the most recent last
+	onChanged func()                 // On path change callback
 }
 
 func NewLibraryPath(onChanged func()) *LibraryPath {
@@ -157,16 +189,21 @@ func NewLibraryPath(onChanged func()) *LibraryPath {
 
 // Append extends the current path with the given element
 func (p *LibraryPath) Append(e LibraryPathElement) {
-	p.elements = append(p.elements, e)
-	p.onChanged()
+	p.navigate(append(p.Elements(), e))
 }
 
-// AsFilter converts the path (with optional additions) into a slice of arguments for MPD's filter function
+// AsFilter converts the path (with optional additions) into a slice of arguments for MPD's filter function: attribute
+// name and value pairs, followed by filter expressions, if any
 func (p *LibraryPath) AsFilter(extraElements ...LibraryPathElement) (result []string) {
 	// Iterate all elements, including extras
+	var exprs []string
 	for _, e := range append(p.elements, extraElements...) {
-		// Select only those associated with attributes
-		if ah, oka := e.(AttributeHolder); oka {
+		// Select only those associated with attributes or expressions
+		if eh, ok := e.(FilterExpressionHolder); ok {
+			exprs = append(exprs, eh.FilterExpressions()...)
+		} else if fh, ok := e.(FilterArgsHolder); ok {
+			result = append(result, fh.FilterArgs()...)
+		} else if ah, oka := e.(AttributeHolder); oka {
 			// For each element, add two elements to the slice: the name and the value
 			result = append(
 				result,
@@ -174,7 +211,30 @@ func (p *LibraryPath) AsFilter(extraElements ...LibraryPathElement) (result []st
 				ah.AttributeValue())
 		}
 	}
-	return
+	return append(result, exprs...)
+}
+
+// Back navigates to the previously visited path, if any. Returns whether there was one
+func (p *LibraryPath) Back() bool {
+	i := len(p.back) - 1
+	if i < 0 {
+		return false
+	}
+	p.forward = append(p.forward, p.elements)
+	p.elements = p.back[i]
+	p.back = p.back[:i]
+	p.onChanged()
+	return true
+}
+
+// CanGoBack returns whether there's a previously visited path to navigate back to
+func (p *LibraryPath) CanGoBack() bool {
+	return len(p.back) > 0
+}
+
+// CanGoForward returns whether there's a path to navigate forward to
+func (p *LibraryPath) CanGoForward() bool {
+	return len(p.forward) > 0
 }
 
 // ElementAt returns the element at the given index, or nil if no such element exists
@@ -185,9 +245,22 @@ func (p *LibraryPath) ElementAt(index int) LibraryPathElement {
 	return nil
 }
 
-// Elements returns the elements slice
+// Elements returns a copy of the elements slice
 func (p *LibraryPath) Elements() []LibraryPathElement {
-	return p.elements
+	return append([]LibraryPathElement(nil), p.elements...)
+}
+
+// Forward navigates to the path previously navigated back from, if any. Returns whether there was one
+func (p *LibraryPath) Forward() bool {
+	i := len(p.forward) - 1
+	if i < 0 {
+		return false
+	}
+	p.back = append(p.back, p.elements)
+	p.elements = p.forward[i]
+	p.forward = p.forward[:i]
+	p.onChanged()
+	return true
 }
 
 // IsRoot returns whether the current path represents root
@@ -206,27 +279,28 @@ func (p *LibraryPath) Last() LibraryPathElement {
 // LevelUp shifts the current path one level up (by dropping the last element)
 func (p *LibraryPath) LevelUp() {
 	if i := len(p.elements); i > 0 {
-		p.elements = p.elements[:i-1]
-		p.onChanged()
+		p.navigate(p.Elements()[:i-1])
 	}
 }
 
 // Marshal serialises the current path as a string
 func (p *LibraryPath) Marshal() string {
-	s := ""
-	for _, e := range p.elements {
-		if s != "" {
-			s += pathElementSeparator
-		}
-		s += MarshalLibPathElement(e)
+	return marshalLibPath(p.elements)
+}
+
+// Navigate deserialises a path from a string and navigates to it, recording the current path in the history
+func (p *LibraryPath) Navigate(s string) error {
+	elements, err := unmarshalLibPath(s)
+	if err != nil {
+		return err
 	}
-	return s
+	p.navigate(elements)
+	return nil
 }
 
 // SetElements updates the elements to the given slice
 func (p *LibraryPath) SetElements(elements []LibraryPathElement) {
-	p.elements = elements
-	p.onChanged()
+	p.navigate(elements)
 }
 
 // SetLength limits the length of the path at the given figure
@@ -234,12 +308,51 @@ func (p *LibraryPath) SetLength(length int) {
 	if length > len(p.elements) {
 		return
 	}
-	p.elements = p.elements[:length]
-	p.onChanged()
+	p.navigate(p.Elements()[:length])
 }
 
-// Unmarshal deserialises the path from a string
+// Unmarshal deserialises the path from a string, discarding the history
 func (p *LibraryPath) Unmarshal(s string) error {
+	elements, err := unmarshalLibPath(s)
+	if err != nil {
+		return err
+	}
+
+	// Succeeded
+	p.elements = elements
+	p.back, p.forward = nil, nil
+	p.onChanged()
+	return nil
+}
+
+// navigate replaces the path with the given elements, recording the current path in the back history (unless they're
+// the same) and clearing the forward history
+func (p *LibraryPath) navigate(elements []LibraryPathElement) {
+	if marshalLibPath(elements) != p.Marshal() {
+		p.back = append(p.back, p.elements)
+		if n := len(p.back) - libraryPathMaxHistory; n > 0 {
+			p.back = p.back[n:]
+		}
+		p.forward = nil
+	}
+	p.elements = elements
+	p.onChanged()
+}
+
+// marshalLibPath serialises the given path elements as a string
+func marshalLibPath(elements []LibraryPathElement) string {
+	s := ""
+	for _, e := range elements {
+		if s != "" {
+			s += pathElementSeparator
+		}
+		s += MarshalLibPathElement(e)
+	}
+	return s
+}
+
+// unmarshalLibPath deserialises path elements from a string
+func unmarshalLibPath(s string) ([]LibraryPathElement, error) {
 	// Iterate serialised elements
 	var elements []LibraryPathElement
 	for _, s := range strings.Split(s, pathElementSeparator) {
@@ -247,16 +360,12 @@ func (p *LibraryPath) Unmarshal(s string) error {
 		if s != "" {
 			element, err := UnmarshalLibPathElement(s)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			elements = append(elements, element)
 		}
 	}
-
-	// Succeeded
-	p.elements = elements
-	p.onChanged()
-	return nil
+	return elements, nil
 }
 
 //----------------------------------------------------------------------------------------------------------------------
@@ -532,7 +641,7 @@ func (e *PlaylistLibElement) Label() string {
 }
 
 func (e *PlaylistLibElement) IsFolder() bool {
-	return false
+	return true
 }
 
 func (e *PlaylistLibElement) IsPlayable() bool {
@@ -560,6 +669,101 @@ func (e *PlaylistLibElement) PlaylistName() string {
 	return e.name
 }
 
+//----------------------------------------------------------------------------------------------------------------------
+// PlaylistEntryLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type PlaylistEntryLibElement struct {
+	playlist string  // Name of the playlist
+	pos      int     // Position in the playlist
+	uri      string  // URI of the track
+	title    string  // Title of the track
+	length   float64 // Length of the track in seconds
+}
+
+func NewPlaylistEntryLibElement() LibraryPathElement {
+	return &PlaylistEntryLibElement{}
+}
+
+// NewPlaylistEntryLibElementAttrs creates a new PlaylistEntryLibElement from the attributes of the track at the given
+// position in the playlist
+func NewPlaylistEntryLibElementAttrs(playlist string, pos int, a mpd.Attrs) LibraryPathElement {
+	e := &PlaylistEntryLibElement{
+		playlist: playlist,
+		pos:      pos,
+		uri:      a["file"],
+		title:    a["Title"],
+		length:   util.ParseFloatDef(a["duration"], 0.0),
+	}
+
+	// Fall back to the file name if there's no title
+	if e.title == "" {
+		e.title = path.Base(e.uri)
+	} else if artist := a["Artist"]; artist != "" {
+		e.title = artist + " — " + e.title
+	}
+	return e
+}
+
+func (e *PlaylistEntryLibElement) Icon() string {
+	return "ymuse-audio-file"
+}
+
+func (e *PlaylistEntryLibElement) Label() string {
+	return e.title
+}
+
+func (e *PlaylistEntryLibElement) IsFolder() bool {
+	return false
+}
+
+func (e *PlaylistEntryLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *PlaylistEntryLibElement) Prefix() string {
+	return "plentry"
+}
+
+func (e *PlaylistEntryLibElement) Marshal() string {
+	return strings.Join([]string{e.playlist, strconv.Itoa(e.pos), e.uri, e.title}, pathFieldSeparator)
+}
+
+func (e *PlaylistEntryLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 4 {
+		return fmt.Errorf("failed to unmarshal PlaylistEntryLibElement: want 4 fields, got %d", len(fields))
+	}
+	pos, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal PlaylistEntryLibElement: invalid position '%v'", fields[1])
+	}
+	e.playlist = fields[0]
+	e.pos = pos
+	e.uri = fields[2]
+	e.title = fields[3]
+	return nil
+}
+
+func (e *PlaylistEntryLibElement) URI() string {
+	return e.uri
+}
+
+func (e *PlaylistEntryLibElement) Details() string {
+	if e.length > 0 {
+		return util.FormatSeconds(e.length)
+	}
+	return ""
+}
+
+func (e *PlaylistEntryLibElement) EntryPlaylist() string {
+	return e.playlist
+}
+
+func (e *PlaylistEntryLibElement) EntryPos() int {
+	return e.pos
+}
+
 //----------------------------------------------------------------------------------------------------------------------
 // GenresLibElement
 //----------------------------------------------------------------------------------------------------------------------
@@ -824,6 +1028,7 @@ func (e *AlbumsLibElement) NewChild(value string) LibraryPathElement {
 
 type AlbumLibElement struct {
 	BaseAttrHolder
+	id AlbumIdentity // Album identity; only the title is known for elements created from a single attribute value
 }
 
 func NewAlbumLibElement() LibraryPathElement {
@@ -831,7 +1036,11 @@ func NewAlbumLibElement() LibraryPathElement {
 }
 
 func NewAlbumLibElementVal(value string) LibraryPathElement {
-	return &AlbumLibElement{BaseAttrHolder{attrID: config.MTAttrAlbum, attrValue: value}}
+	return NewAlbumLibElementID(AlbumIdentity{Album: value})
+}
+
+func NewAlbumLibElementID(id AlbumIdentity) LibraryPathElement {
+	return &AlbumLibElement{BaseAttrHolder{attrID: config.MTAttrAlbum, attrValue: id.Album}, id}
 }
 
 func (e *AlbumLibElement) Icon() string {
@@ -858,18 +1067,39 @@ func (e *AlbumLibElement) Prefix() string {
 }
 
 func (e *AlbumLibElement) Marshal() string {
-	return e.attrValue
+	return strings.Join([]string{e.id.Album, e.id.AlbumArtist, e.id.Artist, e.id.Date, e.id.MBID}, pathFieldSeparator)
 }
 
 func (e *AlbumLibElement) Unmarshal(data string) error {
 	fields := strings.Split(data, pathFieldSeparator)
-	if len(fields) != 1 {
-		return fmt.Errorf("failed to unmarshal AlbumLibElement: want 1 field, got %d", len(fields))
+	switch len(fields) {
+	// Album title only
+	case 1:
+		e.id = AlbumIdentity{Album: fields[0]}
+	case 5:
+		e.id = AlbumIdentity{Album: fields[0], AlbumArtist: fields[1], Artist: fields[2], Date: fields[3], MBID: fields[4]}
+	default:
+		return fmt.Errorf("failed to unmarshal AlbumLibElement: want 1 or 5 fields, got %d", len(fields))
 	}
-	e.attrValue = fields[0]
+	e.attrValue = e.id.Album
 	return nil
 }
 
+func (e *AlbumLibElement) Details() string {
+	if e.id.Date != "" {
+		return e.id.ArtistName() + " · " + e.id.Date
+	}
+	return e.id.ArtistName()
+}
+
+func (e *AlbumLibElement) FilterArgs() []string {
+	return e.id.FilterArgs()
+}
+
+func (e *AlbumLibElement) Identity() AlbumIdentity {
+	return e.id
+}
+
 func (e *AlbumLibElement) ChildAttributeID() int {
 	return config.MTAttrTrack
 }
@@ -929,3 +1159,242 @@ func (e *TrackLibElement) Unmarshal(data string) error {
 	e.attrValue = fields[0]
 	return nil
 }
+
+//----------------------------------------------------------------------------------------------------------------------
+// SmartFolderLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type SmartFolderLibElement struct {
+	name    string   // Smart folder name
+	filters []string // Filter expressions
+}
+
+func NewSmartFolderLibElement() LibraryPathElement {
+	return &SmartFolderLibElement{}
+}
+
+func NewSmartFolderLibElementSpec(spec *config.SmartFolderSpec) LibraryPathElement {
+	return &SmartFolderLibElement{name: spec.Name, filters: spec.Filters}
+}
+
+func (e *SmartFolderLibElement) Icon() string {
+	return "folder-saved-search"
+}
+
+func (e *SmartFolderLibElement) Label() string {
+	return e.name
+}
+
+func (e *SmartFolderLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *SmartFolderLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *SmartFolderLibElement) Prefix() string {
+	return "smartfolder"
+}
+
+func (e *SmartFolderLibElement) Marshal() string {
+	return e.name
+}
+
+func (e *SmartFolderLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 1 {
+		return fmt.Errorf("failed to unmarshal SmartFolderLibElement: want 1 field, got %d", len(fields))
+	}
+	e.name = fields[0]
+
+	// Fetch the filters from the config since they aren't serialised
+	folders := config.GetConfig().SmartFolders
+	idx := findSmartFolder(folders, e.name)
+	if idx < 0 {
+		return fmt.Errorf("failed to unmarshal SmartFolderLibElement: no smart folder named '%s'", e.name)
+	}
+	e.filters = folders[idx].Filters
+	return nil
+}
+
+func (e *SmartFolderLibElement) Filters() []string {
+	return e.filters
+}
+
+func (e *SmartFolderLibElement) SmartFolderName() string {
+	return e.name
+}
+
+// findSmartFolder returns the index of the smart folder with the given name, or -1 if there's none
+func findSmartFolder(folders []config.SmartFolderSpec, name string) int {
+	for i := range folders {
+		if folders[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// RecentLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type RecentLibElement struct {
+	added bool // Whether the element lists tracks added to the database rather than modified files
+}
+
+func NewRecentLibElement() LibraryPathElement {
+	return &RecentLibElement{}
+}
+
+func NewRecentLibElementKind(added bool) LibraryPathElement {
+	return &RecentLibElement{added: added}
+}
+
+func (e *RecentLibElement) Icon() string {
+	return "document-open-recent"
+}
+
+func (e *RecentLibElement) Label() string {
+	if e.added {
+		return glib.Local("Recently added")
+	}
+	return glib.Local("Recently modified")
+}
+
+func (e *RecentLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *RecentLibElement) IsPlayable() bool {
+	return false
+}
+
+func (e *RecentLibElement) Prefix() string {
+	return "recent"
+}
+
+func (e *RecentLibElement) Marshal() string {
+	return recentKind(e.added)
+}
+
+func (e *RecentLibElement) Unmarshal(data string) error {
+	added, err := parseRecentKind(data)
+	e.added = added
+	return err
+}
+
+func (e *RecentLibElement) NewChild(window string) LibraryPathElement {
+	return newRecentWindowLibElement(e.added, window)
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+// RecentWindowLibElement
+//----------------------------------------------------------------------------------------------------------------------
+
+type RecentWindowLibElement struct {
+	added  bool   // Whether the element lists tracks added to the database rather than modified files
+	window string // Time window, one of the RecentWindow* constants
+	since  int64  // UNIX time the window starts at, fixed on creation so that the listing doesn't shift while browsing
+}
+
+func NewRecentWindowLibElement() LibraryPathElement {
+	return &RecentWindowLibElement{}
+}
+
+// newRecentWindowLibElement returns a new element for the given time window, starting now
+func newRecentWindowLibElement(added bool, window string) *RecentWindowLibElement {
+	return &RecentWindowLibElement{
+		added:  added,
+		window: window,
+		since:  RecentWindowSince(window, time.Now(), config.GetConfig().LibraryLastVisit),
+	}
+}
+
+func (e *RecentWindowLibElement) Icon() string {
+	return "document-open-recent"
+}
+
+func (e *RecentWindowLibElement) Label() string {
+	switch e.window {
+	case RecentWindowDay:
+		return glib.Local("Last 24 hours")
+	case RecentWindowWeek:
+		return glib.Local("Last 7 days")
+	case RecentWindowMonth:
+		return glib.Local("Last 30 days")
+	case RecentWindowLastVisit:
+		return glib.Local("Since the last visit")
+	}
+	return e.window
+}
+
+func (e *RecentWindowLibElement) IsFolder() bool {
+	return true
+}
+
+func (e *RecentWindowLibElement) IsPlayable() bool {
+	return true
+}
+
+func (e *RecentWindowLibElement) Prefix() string {
+	return "recentwindow"
+}
+
+func (e *RecentWindowLibElement) Marshal() string {
+	return recentKind(e.added) + pathFieldSeparator + e.window
+}
+
+func (e *RecentWindowLibElement) Unmarshal(data string) error {
+	fields := strings.Split(data, pathFieldSeparator)
+	if len(fields) != 2 {
+		return fmt.Errorf("failed to unmarshal RecentWindowLibElement: want 2 fields, got %d", len(fields))
+	}
+	added, err := parseRecentKind(fields[0])
+	if err != nil {
+		return err
+	}
+	*e = *newRecentWindowLibElement(added, fields[1])
+	return nil
+}
+
+func (e *RecentWindowLibElement) Filters() []string {
+	since := strconv.FormatInt(e.since, 10)
+	if e.added {
+		return []string{FilterAddedSince(since)}
+	}
+	return []string{FilterModifiedSince(since)}
+}
+
+// FilterExpressions restricts the elements listed in the window, such as album headers, to the tracks changed within it
+func (e *RecentWindowLibElement) FilterExpressions() []string {
+	return e.Filters()
+}
+
+// TimeAttr returns the name of the song attribute holding the time the window applies to
+func (e *RecentWindowLibElement) TimeAttr() string {
+	if e.added {
+		return recentAttrAdded
+	}
+	return recentAttrModified
+}
+
+// recentKind returns the serialised form of the recent element kind
+func recentKind(added bool) string {
+	if added {
+		return "added"
+	}
+	return "modified"
+}
+
+// parseRecentKind parses the serialised form of the recent element kind
+func parseRecentKind(s string) (bool, error) {
+	switch s {
+	case "added":
+		return true, nil
+	case "modified":
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to unmarshal recent element kind: '%s'", s)
+}
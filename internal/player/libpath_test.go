@@ -0,0 +1,130 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLibraryPath_History(t *testing.T) {
+	changes := 0
+	p := NewLibraryPath(func() { changes++ })
+	check := func(step, want string, wantBack, wantForward bool) {
+		t.Helper()
+		if got := p.Marshal(); got != want {
+			t.Errorf("%s: Marshal() = %q, want %q", step, got, want)
+		}
+		if got := p.CanGoBack(); got != wantBack {
+			t.Errorf("%s: CanGoBack() = %v, want %v", step, got, wantBack)
+		}
+		if got := p.CanGoForward(); got != wantForward {
+			t.Errorf("%s: CanGoForward() = %v, want %v", step, got, wantForward)
+		}
+	}
+	genres := MarshalLibPathElement(NewGenresLibElement())
+	rock := genres + pathElementSeparator + MarshalLibPathElement(NewGenreLibElementVal("Rock"))
+	playlists := MarshalLibPathElement(NewPlaylistsLibElement())
+
+	// Initially there's no history
+	check("initial", "", false, false)
+	if p.Back() || p.Forward() {
+		t.Errorf("Back()/Forward() = true for an empty history, want false")
+	}
+
+	// Navigate down and up
+	p.Append(NewGenresLibElement())
+	p.Append(NewGenreLibElementVal("Rock"))
+	check("append", rock, true, false)
+	p.LevelUp()
+	check("level up", genres, true, false)
+
+	// Go back twice, then forward once
+	p.Back()
+	check("back", rock, true, true)
+	p.Back()
+	check("back again", genres, true, true)
+	p.Forward()
+	check("forward", rock, true, true)
+
+	// Navigating elsewhere clears the forward history
+	p.SetElements([]LibraryPathElement{NewPlaylistsLibElement()})
+	check("set elements", playlists, true, false)
+
+	// Navigating to the same path isn't recorded
+	p.SetLength(1)
+	p.Back()
+	check("back after no-op", rock, true, true)
+
+	// Navigating to a marshalled path is recorded
+	if err := p.Navigate(genres); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	check("navigate", genres, true, false)
+	p.Back()
+	check("back after navigate", rock, true, true)
+
+	// Unmarshalling discards the history
+	if err := p.Unmarshal(playlists); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	check("unmarshal", playlists, false, false)
+
+	if changes != 12 {
+		t.Errorf("number of change notifications = %d, want 12", changes)
+	}
+}
+
+func TestLibraryPath_HistoryLimit(t *testing.T) {
+	p := NewLibraryPath(func() {})
+	for i := 0; i < libraryPathMaxHistory+10; i++ {
+		p.Append(NewDirLibElement())
+	}
+	n := 0
+	for p.Back() {
+		n++
+	}
+	if n != libraryPathMaxHistory {
+		t.Errorf("number of back steps = %d, want %d", n, libraryPathMaxHistory)
+	}
+}
+
+func TestLibraryPath_HistoryIntact(t *testing.T) {
+	p := NewLibraryPath(func() {})
+	p.Append(NewGenresLibElement())
+	p.Append(NewGenreLibElementVal("Rock"))
+	p.LevelUp()
+
+	// Extending the shortened path must not affect the recorded history
+	p.Append(NewGenreLibElementVal("Jazz"))
+	p.Back()
+	p.Back()
+	if got, want := p.Last().Marshal(), NewGenreLibElementVal("Rock").Marshal(); got != want {
+		t.Errorf("Last() = %v, want %v", got, want)
+	}
+}
+
+func TestLibraryPath_AsFilter(t *testing.T) {
+	// Filter expressions follow the attribute pairs, whatever the element order
+	p := NewLibraryPath(func() {})
+	p.Append(&RecentWindowLibElement{window: RecentWindowDay, since: 1000})
+	id := AlbumIdentity{Album: "Arrival", AlbumArtist: "ABBA"}
+	got := p.AsFilter(NewAlbumLibElementID(id))
+	want := append(id.FilterArgs(), FilterModifiedSince("1000"))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AsFilter() = %q, want %q", got, want)
+	}
+}
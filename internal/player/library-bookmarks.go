@@ -0,0 +1,115 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"slices"
+	"strings"
+)
+
+// libraryAddBookmark bookmarks the current library path under a user-provided name
+func (w *MainWindow) libraryAddBookmark() {
+	// Suggest the label of the current level as the name
+	name := glib.Local("Library")
+	if e := w.libPath.Last(); e != nil {
+		name = e.Label()
+	}
+	name, ok := util.EditDialog(w.AppWindow, glib.Local("Add bookmark"), name, glib.Local("Add"))
+	if !ok {
+		return
+	}
+
+	// Replace an existing bookmark with the same name, after a confirmation
+	bookmarks := &config.GetConfig().LibraryBookmarks
+	spec := config.BookmarkSpec{Name: name, Path: w.libPath.Marshal()}
+	if idx := slices.IndexFunc(*bookmarks, func(b config.BookmarkSpec) bool { return b.Name == name }); idx >= 0 {
+		if !util.ConfirmDialog(w.AppWindow, glib.Local("Add bookmark"), fmt.Sprintf(glib.Local("Bookmark \"%s\" already exists. Do you want to replace it?"), name)) {
+			return
+		}
+		(*bookmarks)[idx] = spec
+	} else {
+		*bookmarks = append(*bookmarks, spec)
+	}
+}
+
+// libraryBookmarks shows a popover menu listing the library bookmarks
+func (w *MainWindow) libraryBookmarks() {
+	// Clean up and repopulate the menu
+	util.ClearChildren(w.LibraryBookmarksBox.Container)
+	addBtn, err := gtk.ModelButtonNew()
+	if errCheck(err, "ModelButtonNew() failed") {
+		return
+	}
+	errCheck(addBtn.Set("text", glib.Local("Bookmark this location…")), "Set(text) failed")
+	addBtn.Connect("clicked", w.libraryAddBookmark)
+	w.LibraryBookmarksBox.PackStart(addBtn, false, true, 0)
+
+	// Add a row per bookmark
+	for _, bm := range config.GetConfig().LibraryBookmarks {
+		bm := bm // Make an in-loop copy
+
+		// Make a new button for opening the bookmark, with the path's labels in the tooltip
+		btn, err := gtk.ModelButtonNew()
+		if errCheck(err, "ModelButtonNew() failed") {
+			return
+		}
+		errCheck(btn.Set("text", bm.Name), "Set(text) failed")
+		var labels []string
+		if elements, err := unmarshalLibPath(bm.Path); err == nil {
+			for _, e := range elements {
+				labels = append(labels, e.Label())
+			}
+		}
+		btn.SetTooltipText(strings.Join(append([]string{glib.Local("Library")}, labels...), " › "))
+		btn.Connect("clicked", func() { w.libraryOpenBookmark(bm.Path) })
+
+		// Add a delete button next to it
+		hbx, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 6)
+		if errCheck(err, "BoxNew() failed") {
+			return
+		}
+		hbx.PackStart(btn, true, true, 0)
+		delBtn := util.NewButton("", glib.Local("Delete bookmark"), "", "ymuse-delete-symbolic", func() { w.libraryDeleteBookmark(bm.Name) })
+		delBtn.SetRelief(gtk.RELIEF_NONE)
+		hbx.PackEnd(delBtn, false, false, 0)
+		w.LibraryBookmarksBox.PackStart(hbx, false, true, 0)
+	}
+
+	// Show the popover
+	w.LibraryBookmarksBox.ShowAll()
+	w.LibraryBookmarksPopoverMenu.Popup()
+}
+
+// libraryDeleteBookmark deletes the library bookmark with the given name
+func (w *MainWindow) libraryDeleteBookmark(name string) {
+	w.LibraryBookmarksPopoverMenu.Popdown()
+	if util.ConfirmDialog(w.AppWindow, glib.Local("Delete bookmark"), fmt.Sprintf(glib.Local("Are you sure you want to delete bookmark \"%s\"?"), name)) {
+		bookmarks := &config.GetConfig().LibraryBookmarks
+		*bookmarks = slices.DeleteFunc(*bookmarks, func(b config.BookmarkSpec) bool { return b.Name == name })
+	}
+}
+
+// libraryOpenBookmark navigates to the given bookmarked (serialised) library path
+func (w *MainWindow) libraryOpenBookmark(path string) {
+	// Leave the search mode, if active
+	w.LibrarySearchToolButton.SetActive(false)
+	w.errCheckDialog(w.libPath.Navigate(path), glib.Local("Failed to open the bookmark"))
+}
@@ -0,0 +1,177 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/util"
+	"strings"
+)
+
+// libraryCountJob describes a track count to be fetched for a library list row or for the current library level
+type libraryCountJob struct {
+	row      int      // List row index, or -1 for the current level
+	filter   []string // Arguments for MPD's count command
+	playlist string   // Name of the playlist whose entries to count, instead of using the filter
+	prefix   string   // Text to prepend to the count
+}
+
+// libraryCountLoad fetches track counts for the given jobs in the background and displays them. The loading is
+// abandoned once the library content generation differs from gen
+func (w *MainWindow) libraryCountLoad(gen int64, jobs []*libraryCountJob) {
+	for _, job := range jobs {
+		// Stop if the library has been repopulated
+		if w.libGeneration.Load() != gen {
+			return
+		}
+
+		// Fetch the count
+		var songs int
+		var playtime float64
+		var err error
+		w.connector.IfConnected(func(client *mpd.Client) {
+			// MPD can't count playlist entries, so sum them up
+			if job.playlist != "" {
+				var attrs []mpd.Attrs
+				if attrs, err = client.PlaylistContents(job.playlist); err == nil {
+					songs = len(attrs)
+					for _, a := range attrs {
+						playtime += util.ParseFloatDef(a["duration"], 0)
+					}
+				}
+				return
+			}
+			var attrs mpd.Attrs
+			cmd := "count" + strings.Repeat(" %s", len(job.filter))
+			if attrs, err = client.Command(cmd, mpdCommandArgs(job.filter...)...).Attrs(); err == nil {
+				songs = util.AtoiDef(attrs["songs"], 0)
+				playtime = util.ParseFloatDef(attrs["playtime"], 0)
+			}
+		})
+		if errCheck(err, "libraryCountLoad(): count failed") {
+			continue
+		}
+
+		// Compose the text
+		text := fmt.Sprintf(util.LocalN("%d track · %s", "%d tracks · %s", songs), songs, util.FormatHoursMinutes(playtime))
+		if job.prefix != "" {
+			text = job.prefix + " · " + text
+		}
+
+		// Update the row or the info label in the GUI thread
+		row := job.row
+		glib.IdleAdd(func() {
+			if w.libGeneration.Load() != gen {
+				return
+			}
+			if row < 0 {
+				w.LibraryInfoLabel.SetText(text)
+				return
+			}
+			path, err := gtk.TreePathNewFromIndicesv([]int{row})
+			if errCheck(err, "TreePathNewFromIndicesv() failed") {
+				return
+			}
+			if iter, err := w.LibraryListStore.GetIter(path); !errCheck(err, "GetIter() failed") {
+				errCheck(w.LibraryListStore.SetValue(iter, libraryColDetails, text), "SetValue() failed")
+			}
+		})
+	}
+}
+
+// libraryCountLoadVisible initiates fetching track counts for library list rows scrolled into view
+func (w *MainWindow) libraryCountLoadVisible() {
+	// Root elements aren't counted
+	count := len(w.libListLabels)
+	if count == 0 || w.libPath.Last() == nil || !w.LibraryListHBox.GetVisible() {
+		return
+	}
+
+	// Determine the visible row range, skipping if the list isn't laid out yet
+	height := int(w.LibraryScrolledWindow.GetVAdjustment().GetPageSize())
+	path, _, _, _, ok := w.LibraryTreeView.GetPathAtPos(0, 0)
+	if height <= 0 || !ok {
+		return
+	}
+	first, last := path.GetIndices()[0], count-1
+	if path, _, _, _, ok := w.LibraryTreeView.GetPathAtPos(0, height-1); ok {
+		last = path.GetIndices()[0]
+	}
+
+	// Create jobs for the rows whose counts haven't been requested yet
+	var jobs []*libraryCountJob
+	for row := first; row <= last && row < count; row++ {
+		if w.libCountsRequested[row] {
+			continue
+		}
+		w.libCountsRequested[row] = true
+		path, err := gtk.TreePathNewFromIndicesv([]int{row})
+		if errCheck(err, "TreePathNewFromIndicesv() failed") {
+			continue
+		}
+		iter, err := w.LibraryListStore.GetIter(path)
+		if errCheck(err, "GetIter() failed") {
+			continue
+		}
+		if job := w.newLibraryCountJob(row, w.libraryListElement(iter)); job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+
+	// Fetch the counts in the background
+	if len(jobs) > 0 {
+		go w.libraryCountLoad(w.libGeneration.Load(), jobs)
+	}
+}
+
+// newLibraryCountJob creates and returns a track count job for the given library list row element, or for the current
+// library level if element is nil. Returns nil if the element can't be counted
+func (w *MainWindow) newLibraryCountJob(row int, element LibraryPathElement) *libraryCountJob {
+	job := &libraryCountJob{row: row}
+	target := element
+	if target == nil {
+		target = w.libPath.Last()
+	} else if !element.IsFolder() {
+		// Only count folders: directories, tags, albums and playlists
+		return nil
+	} else if dh, ok := element.(DetailsHolder); ok {
+		job.prefix = dh.Details()
+	}
+
+	switch e := target.(type) {
+	case *LevelUpLibElement:
+		return nil
+	case PlaylistHolder:
+		job.playlist = e.PlaylistName()
+	case URIHolder:
+		job.filter = []string{FilterBase(e.URI())}
+	case FilterArgsHolder, FilterExpressionHolder, AttributeHolder, AttributeHolderParent:
+		if element != nil {
+			if job.filter = w.libPath.AsFilter(element); len(job.filter) == 0 {
+				return nil
+			}
+		} else if job.filter = w.libPath.AsFilter(); len(job.filter) == 0 {
+			// Top tag level: count the whole library
+			job.filter = []string{FilterBase("")}
+		}
+	default:
+		return nil
+	}
+	return job
+}
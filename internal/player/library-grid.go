@@ -0,0 +1,238 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/gotk3/gotk3/pango"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+// Size of album thumbnails in the library grid view, in pixels
+const libraryGridThumbSize = 128
+
+// libraryGridItem represents an element displayed in the library grid view
+type libraryGridItem struct {
+	child     *gtk.FlowBoxChild // Flow box child holding the item's widgets
+	image     *gtk.Image        // Image displaying the album thumbnail
+	findArgs  []string          // Arguments for finding a track of the album, nil if the element has no thumbnail
+	requested bool              // Whether the thumbnail has been requested
+}
+
+// onLibraryGridToggle switches between the list and the grid view for the current library level
+func (w *MainWindow) onLibraryGridToggle() {
+	// Ignore if the button state is being updated programmatically
+	if w.libGridUpdating {
+		return
+	}
+
+	// Remember the view mode for the current level
+	if e := w.libPath.Last(); e != nil {
+		cfg := config.GetConfig()
+		if cfg.LibraryGridLevels == nil {
+			cfg.LibraryGridLevels = make(map[string]bool)
+		}
+		cfg.LibraryGridLevels[e.Prefix()] = w.LibraryGridToolButton.GetActive()
+	}
+
+	// Reload the library
+	w.updateLibrary()
+	w.focusMainList()
+}
+
+// libraryGridAvailable returns whether the given library level can be displayed as a grid, which is the case for levels
+// listing albums
+func (w *MainWindow) libraryGridAvailable(e LibraryPathElement) bool {
+	ahp, ok := e.(AttributeHolderParent)
+	return ok && ahp.ChildAttributeID() == config.MTAttrAlbum
+}
+
+// libraryGridItemAt returns the library grid item at the given flow box coordinates, or nil if there's none
+func (w *MainWindow) libraryGridItemAt(x, y int) *libraryGridItem {
+	for _, item := range w.libGridItems {
+		a := item.child.GetAllocation()
+		if x >= a.GetX() && x < a.GetX()+a.GetWidth() && y >= a.GetY() && y < a.GetY()+a.GetHeight() {
+			return item
+		}
+	}
+	return nil
+}
+
+// libraryGridLoadThumbnails fetches album art for the given library grid items in the background. The loading is
+// abandoned once the grid content generation differs from gen
+func (w *MainWindow) libraryGridLoadThumbnails(gen int64, items []*libraryGridItem) {
+	for _, item := range items {
+		// Stop if the grid has been repopulated
+		if w.libGeneration.Load() != gen {
+			return
+		}
+
+		// Find a track belonging to the album
+		var attrs []mpd.Attrs
+		var err error
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.Find(item.findArgs...)
+		})
+		if errCheck(err, "libraryGridLoadThumbnails(): Find() failed") || len(attrs) == 0 {
+			continue
+		}
+
+		// Fetch and decode its album art
+		albumArt := w.connector.GetAlbumArt(attrs[0]["file"], libraryGridThumbSize)
+		if len(albumArt) == 0 {
+			continue
+		}
+		px, err := util.NewPixbufScaled(albumArt, libraryGridThumbSize)
+		if errCheck(err, "NewPixbufScaled() failed") {
+			continue
+		}
+
+		// Update the image in the GUI thread
+		image := item.image
+		glib.IdleAdd(func() {
+			if w.libGeneration.Load() == gen {
+				image.SetFromPixbuf(px)
+			}
+		})
+	}
+}
+
+// libraryGridLoadVisible initiates loading thumbnails for library grid items scrolled into view
+func (w *MainWindow) libraryGridLoadVisible() {
+	// Determine the visible area
+	adj := w.LibraryGridScrolledWindow.GetVAdjustment()
+	top := int(adj.GetValue())
+	bottom := top + int(adj.GetPageSize())
+
+	// Collect visible items whose thumbnails haven't been requested yet
+	var items []*libraryGridItem
+	for _, item := range w.libGridItems {
+		if item.findArgs == nil || item.requested {
+			continue
+		}
+		// Skip items not allocated yet
+		a := item.child.GetAllocation()
+		if a.GetWidth() <= 1 {
+			continue
+		}
+		if a.GetY()+a.GetHeight() >= top && a.GetY() <= bottom {
+			item.requested = true
+			items = append(items, item)
+		}
+	}
+
+	// Load the thumbnails in the background
+	if len(items) > 0 {
+		go w.libraryGridLoadThumbnails(w.libGeneration.Load(), items)
+	}
+}
+
+// newLibraryGridItem creates and returns a new library grid item for the given element
+func (w *MainWindow) newLibraryGridItem(element LibraryPathElement) (*libraryGridItem, error) {
+	item := &libraryGridItem{}
+	var err error
+
+	// Create a flow box child, which stores the marshalled element in its name
+	if item.child, err = gtk.FlowBoxChildNew(); err != nil {
+		return nil, err
+	}
+	item.child.SetName(MarshalLibPathElement(element))
+	tooltip := element.Label()
+	if dh, ok := element.(DetailsHolder); ok && dh.Details() != "" {
+		tooltip += "\n" + dh.Details()
+	}
+	item.child.SetTooltipText(tooltip)
+	box, err := gtk.BoxNew(gtk.ORIENTATION_VERTICAL, 3)
+	if err != nil {
+		return nil, err
+	}
+	item.child.Add(box)
+
+	// Add an image, initially showing the element's icon
+	if item.image, err = gtk.ImageNewFromIconName(element.Icon(), gtk.ICON_SIZE_DIALOG); err != nil {
+		return nil, err
+	}
+	item.image.SetPixelSize(libraryGridThumbSize)
+	item.image.SetSizeRequest(libraryGridThumbSize, libraryGridThumbSize)
+	box.PackStart(item.image, false, false, 0)
+
+	// Add a label
+	lbl, err := gtk.LabelNew(element.Label())
+	if err != nil {
+		return nil, err
+	}
+	lbl.SetEllipsize(pango.ELLIPSIZE_END)
+	lbl.SetMaxWidthChars(16)
+	box.PackStart(lbl, false, false, 0)
+
+	// Add append/replace buttons for playable elements
+	if element.IsPlayable() {
+		hbx, err := gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 0)
+		if err != nil {
+			return nil, err
+		}
+		hbx.SetHAlign(gtk.ALIGN_CENTER)
+		for _, btn := range []*gtk.Button{
+			util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueLibraryElements(tbFalse, element) }),
+			util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueLibraryElements(tbTrue, element) }),
+		} {
+			btn.SetRelief(gtk.RELIEF_NONE)
+			hbx.PackStart(btn, false, false, 0)
+		}
+		box.PackStart(hbx, false, false, 0)
+	}
+
+	// Albums get their thumbnails loaded lazily
+	if _, ok := element.(*AlbumLibElement); ok {
+		item.findArgs = append(w.libPath.AsFilter(element), "window", "0:1")
+	}
+	return item, nil
+}
+
+// populateLibraryGrid fills the library grid view with the given elements, showing at most maxItems of them (unless
+// it's negative). Returns the number of added items, whether the list was limited, and whether the operation succeeded
+func (w *MainWindow) populateLibraryGrid(elements []LibraryPathElement, maxItems int) (countItems int, limited, ok bool) {
+	var childToSelect *gtk.FlowBoxChild
+	for _, element := range elements {
+		item, err := w.newLibraryGridItem(element)
+		if errCheck(err, "newLibraryGridItem() failed") {
+			return
+		}
+		w.LibraryFlowBox.Insert(item.child, -1)
+		w.libGridItems = append(w.libGridItems, item)
+
+		// If no specific item to select, pick the first one. Otherwise, check for a matching marshalled form
+		if childToSelect == nil && (w.libPathElementToSelect == "" || w.libPathElementToSelect == element.Marshal()) {
+			childToSelect = item.child
+		}
+		countItems++
+
+		if maxItems >= 0 && countItems >= maxItems {
+			limited = true
+			break
+		}
+	}
+
+	// Show all items and select the required one
+	w.LibraryFlowBox.ShowAll()
+	if childToSelect != nil {
+		w.LibraryFlowBox.SelectChild(childToSelect)
+	}
+	return countItems, limited, true
+}
@@ -0,0 +1,239 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/pkg/errors"
+	"github.com/yktoo/ymuse/internal/util"
+	"slices"
+	"strings"
+)
+
+// libraryPlaylistCompare shows the differences between the selected playlist and another one
+func (w *MainWindow) libraryPlaylistCompare() {
+	name := w.libraryPlaylistName()
+	other, ok := w.librarySelectOtherPlaylist(name, glib.Local("Compare playlists"), fmt.Sprintf(glib.Local("Compare playlist \"%s\" with:"), name), glib.Local("Compare"))
+	if !ok {
+		return
+	}
+
+	// Fetch both playlists
+	uris, err := w.libraryPlaylistURIs(name)
+	if w.errCheckDialog(err, glib.Local("Failed to compare the playlists")) {
+		return
+	}
+	otherURIs, err := w.libraryPlaylistURIs(other)
+	if w.errCheckDialog(err, glib.Local("Failed to compare the playlists")) {
+		return
+	}
+
+	// Find and show the differences
+	onlyThis, onlyOther := playlistDiff(uris, otherURIs)
+	if len(onlyThis) == 0 && len(onlyOther) == 0 {
+		util.InfoDialog(w.AppWindow, glib.Local("The playlists contain the same tracks"))
+		return
+	}
+	var sb strings.Builder
+	for _, d := range []struct {
+		name string
+		uris []string
+	}{{name, onlyThis}, {other, onlyOther}} {
+		sb.WriteString(fmt.Sprintf(glib.Local("Only in \"%s\" (%d):"), d.name, len(d.uris)) + "\n")
+		for _, uri := range d.uris {
+			sb.WriteString("    " + uri + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	util.TextDialog(w.AppWindow, glib.Local("Compare playlists"), sb.String())
+}
+
+// libraryPlaylistDedupe removes repeated tracks from the selected or open playlist
+func (w *MainWindow) libraryPlaylistDedupe() {
+	name := w.libraryPlaylistName()
+	if name == "" {
+		return
+	}
+	uris, err := w.libraryPlaylistURIs(name)
+	if w.errCheckDialog(err, glib.Local("Failed to remove duplicates from the playlist")) {
+		return
+	}
+
+	// Find the duplicates
+	positions := playlistDuplicates(uris)
+	if len(positions) == 0 {
+		util.InfoDialog(w.AppWindow, glib.Local("The playlist contains no duplicate tracks"))
+		return
+	}
+	if !util.ConfirmDialog(w.AppWindow, glib.Local("Remove duplicates"), fmt.Sprintf(glib.Local("Are you sure you want to remove %d duplicate track(s) from playlist \"%s\"?"), len(positions), name)) {
+		return
+	}
+
+	// Remove them, last first
+	err = errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		for _, pos := range positions {
+			commands.PlaylistDelete(name, pos)
+		}
+		err = commands.End()
+	})
+	w.errCheckDialog(err, glib.Local("Failed to remove duplicates from the playlist"))
+}
+
+// libraryPlaylistDuplicate saves a copy of the selected playlist under a new name
+func (w *MainWindow) libraryPlaylistDuplicate() {
+	name := w.libraryPlaylistName()
+	if name == "" {
+		return
+	}
+	newName, ok := util.EditDialog(w.AppWindow, glib.Local("Duplicate playlist"), fmt.Sprintf(glib.Local("%s (copy)"), name), glib.Local("Duplicate"))
+	if !ok {
+		return
+	}
+	if slices.Contains(w.connector.GetPlaylists(), newName) {
+		util.ErrorDialog(w.AppWindow, fmt.Sprintf(glib.Local("Playlist \"%s\" already exists"), newName))
+		return
+	}
+
+	// Copy the tracks over
+	uris, err := w.libraryPlaylistURIs(name)
+	if w.errCheckDialog(err, glib.Local("Failed to duplicate the playlist")) {
+		return
+	}
+	w.libPathElementToSelect = NewPlaylistLibElementName(newName).Marshal()
+	w.libraryAppendPlaylist(newName, uris...)
+}
+
+// libraryPlaylistInsert inserts the tracks selected in the queue into the open playlist, before the selected entry or,
+// if there's none, at the end
+func (w *MainWindow) libraryPlaylistInsert() {
+	ph, ok := w.libPath.Last().(PlaylistHolder)
+	if !ok {
+		return
+	}
+	uris := w.getQueueSelectedURIs()
+	if len(uris) == 0 {
+		return
+	}
+
+	// Determine the insertion position
+	count := w.libraryPlaylistLength()
+	pos := count
+	if eh, ok := w.getSelectedLibraryElement().(PlaylistEntryHolder); ok {
+		pos = eh.EntryPos()
+	}
+
+	// Append the tracks and move them into place
+	name := ph.PlaylistName()
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		for i, uri := range uris {
+			commands.PlaylistAdd(name, uri)
+			if pos < count {
+				commands.PlaylistMove(name, count+i, pos+i)
+			}
+		}
+		err = commands.End()
+	})
+	w.errCheckDialog(err, glib.Local("Failed to add item to the playlist"))
+}
+
+// libraryPlaylistLength returns the number of entries in the open playlist
+func (w *MainWindow) libraryPlaylistLength() int {
+	if _, ok := w.libPath.Last().(PlaylistHolder); !ok {
+		return 0
+	}
+	// Don't count the "level up" row
+	return max(w.LibraryListStore.IterNChildren(nil)-1, 0)
+}
+
+// libraryPlaylistMerge adds tracks of the selected playlist missing from another playlist to the latter
+func (w *MainWindow) libraryPlaylistMerge() {
+	name := w.libraryPlaylistName()
+	target, ok := w.librarySelectOtherPlaylist(name, glib.Local("Merge playlists"), fmt.Sprintf(glib.Local("Add tracks of playlist \"%s\" to:"), name), glib.Local("Merge"))
+	if !ok {
+		return
+	}
+
+	// Fetch both playlists
+	uris, err := w.libraryPlaylistURIs(name)
+	if w.errCheckDialog(err, glib.Local("Failed to merge the playlists")) {
+		return
+	}
+	targetURIs, err := w.libraryPlaylistURIs(target)
+	if w.errCheckDialog(err, glib.Local("Failed to merge the playlists")) {
+		return
+	}
+
+	// Append the missing tracks
+	if missing := playlistMissing(targetURIs, uris); len(missing) > 0 {
+		w.libraryAppendPlaylist(target, missing...)
+	} else {
+		util.InfoDialog(w.AppWindow, fmt.Sprintf(glib.Local("Playlist \"%s\" already contains all the tracks"), target))
+	}
+}
+
+// libraryPlaylistMove moves the selected playlist entry by the given number of positions
+func (w *MainWindow) libraryPlaylistMove(delta int) {
+	e, ok := w.getSelectedLibraryElement().(*PlaylistEntryLibElement)
+	if !ok {
+		return
+	}
+	to := e.pos + delta
+	if to < 0 || to >= w.libraryPlaylistLength() {
+		return
+	}
+
+	// Keep the moved entry selected
+	from := e.pos
+	e.pos = to
+	w.libPathElementToSelect = e.Marshal()
+
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.PlaylistMove(e.playlist, from, to)
+	})
+	w.errCheckDialog(err, glib.Local("Failed to move the track"))
+}
+
+// libraryPlaylistName returns the name of the playlist playlist actions apply to: the open one inside a playlist, or
+// the selected one otherwise
+func (w *MainWindow) libraryPlaylistName() string {
+	if ph, ok := w.libPath.Last().(PlaylistHolder); ok {
+		return ph.PlaylistName()
+	}
+	if ph, ok := w.getSelectedLibraryElement().(PlaylistHolder); ok {
+		return ph.PlaylistName()
+	}
+	return ""
+}
+
+// libraryPlaylistURIs returns URIs of the tracks in the playlist with the given name
+func (w *MainWindow) libraryPlaylistURIs(name string) ([]string, error) {
+	var attrs []mpd.Attrs
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		attrs, err = client.PlaylistContents(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return util.MapAttrsToSlice(attrs, "file"), nil
+}
@@ -0,0 +1,203 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/lyrics"
+	"github.com/yktoo/ymuse/internal/util"
+	"time"
+)
+
+func (w *MainWindow) onLyricsRowActivated(_ *gtk.ListBox, row *gtk.ListBoxRow) {
+	// Only synced lyrics can be used for seeking
+	if w.lyrics == nil || !w.lyrics.Synced {
+		return
+	}
+	i := row.GetIndex()
+	if i < 0 || i >= len(w.lyrics.Lines) {
+		return
+	}
+
+	// Jump to the line's position
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.SeekCur(w.lyrics.Lines[i].Time, false)
+	})
+	w.errCheckDialog(err, glib.Local("Failed to seek in the current track"))
+}
+
+// lyricsLoad fetches the lyrics of the song with the given URI and displays them in the lyrics panel. The lyrics come
+// from the song's tags or, if there are none and the music directory is locally reachable, from a sidecar .lrc file.
+// Supposed to be run in a background goroutine
+func (w *MainWindow) lyricsLoad(uri string) {
+	var text, musicDir string
+	w.connector.IfConnected(func(client *mpd.Client) {
+		// Look for lyrics in the song's tags
+		comments, err := client.Command("readcomments %s", mpdCommandArgs(uri)...).Attrs()
+		if !errCheck(err, "lyricsLoad(): readcomments failed") {
+			text = lyrics.FromComments(comments)
+		}
+
+		// MPD only reveals its music directory to clients connected via a local socket, so ignore any error
+		if text == "" {
+			if attrs, err := client.Command("config").Attrs(); err == nil {
+				musicDir = attrs["music_directory"]
+			}
+		}
+	})
+
+	// Fall back to the sidecar file
+	if text == "" && musicDir != "" {
+		var err error
+		text, err = lyrics.FromSidecar(musicDir, uri)
+		errCheck(err, "lyricsLoad(): FromSidecar() failed")
+	}
+
+	// Display the lyrics in the GUI thread
+	l := lyrics.Parse(text)
+	glib.IdleAdd(func() { w.lyricsShow(uri, l) })
+}
+
+// lyricsShow populates the lyrics panel with the given lyrics, unless the song has changed in the meantime
+func (w *MainWindow) lyricsShow(uri string, l *lyrics.Lyrics) {
+	if uri != w.lyricsURI {
+		return
+	}
+	w.lyrics = l
+	w.lyricsLine = -1
+
+	// Only synced lines can be selected
+	mode := gtk.SELECTION_NONE
+	if l.Synced {
+		mode = gtk.SELECTION_SINGLE
+	}
+	w.LyricsListBox.SetSelectionMode(mode)
+
+	// Add a row per line
+	for _, line := range l.Lines {
+		label, err := gtk.LabelNew(line.Text)
+		if errCheck(err, "LabelNew() failed") {
+			return
+		}
+		label.SetLineWrap(true)
+		label.SetJustify(gtk.JUSTIFY_CENTER)
+		label.SetMarginStart(6)
+		label.SetMarginEnd(6)
+		label.SetMarginTop(3)
+		label.SetMarginBottom(3)
+		w.LyricsListBox.Add(label)
+	}
+	w.LyricsListBox.ShowAll()
+
+	// Update info
+	var info string
+	switch {
+	case len(l.Lines) == 0:
+		info = glib.Local("No lyrics found")
+	case l.Synced:
+		info = glib.Local("Synchronized lyrics")
+	default:
+		info = glib.Local("Unsynchronized lyrics")
+	}
+	w.LyricsInfoLabel.SetText(info)
+
+	// Highlight the current line
+	w.updateLyricsLine(util.ParseFloatDef(w.connector.Status()["elapsed"], -1))
+}
+
+// lyricsToggle shows or hides the lyrics panel
+func (w *MainWindow) lyricsToggle() {
+	// Ignore if the state of the button is being updated programmatically
+	if w.lyricsUpdating {
+		return
+	}
+	cfg := config.GetConfig()
+	cfg.PlayerLyrics = !cfg.PlayerLyrics
+	w.updateLyricsPanel()
+}
+
+// updateLyrics starts loading the lyrics of the song with the given URI, unless the lyrics panel is hidden or the lyrics
+// are already displayed
+func (w *MainWindow) updateLyrics(uri string) {
+	w.lyricsSongURI = uri
+	if !config.GetConfig().PlayerLyrics || uri == w.lyricsURI {
+		return
+	}
+
+	// Clear the panel
+	w.lyricsURI = uri
+	w.lyrics = nil
+	w.lyricsLine = -1
+	util.ClearChildren(w.LyricsListBox.Container)
+
+	switch {
+	case uri == "":
+		w.LyricsInfoLabel.SetText(glib.Local("No track playing"))
+	case util.IsStreamURI(uri):
+		w.LyricsInfoLabel.SetText(glib.Local("No lyrics found"))
+	default:
+		w.LyricsInfoLabel.SetText(glib.Local("Loading lyrics…"))
+		go w.lyricsLoad(uri)
+	}
+}
+
+// updateLyricsLine highlights the lyrics line matching the given play position, in seconds
+func (w *MainWindow) updateLyricsLine(pos float64) {
+	if w.lyrics == nil || !w.lyrics.Synced {
+		return
+	}
+
+	// Find the line and check if it has changed
+	line := -1
+	if pos >= 0 {
+		line = w.lyrics.LineAt(time.Duration(pos * float64(time.Second)))
+	}
+	if line == w.lyricsLine {
+		return
+	}
+	w.lyricsLine = line
+
+	// Select the line's row
+	row := w.LyricsListBox.GetRowAtIndex(line)
+	if row == nil {
+		w.LyricsListBox.UnselectAll()
+		return
+	}
+	w.LyricsListBox.SelectRow(row)
+
+	// Scroll the row to the middle of the panel
+	adj := w.LyricsScrolledWindow.GetVAdjustment()
+	alloc := row.GetAllocation()
+	adj.SetValue(float64(alloc.GetY()) + float64(alloc.GetHeight())/2 - adj.GetPageSize()/2)
+}
+
+// updateLyricsPanel shows or hides the lyrics panel according to the configuration
+func (w *MainWindow) updateLyricsPanel() {
+	show := config.GetConfig().PlayerLyrics
+	w.lyricsUpdating = true
+	w.LyricsButton.SetActive(show)
+	w.lyricsUpdating = false
+	w.LyricsRevealer.SetRevealChild(show)
+
+	// The lyrics aren't loaded while the panel is hidden, so catch up
+	if show {
+		w.updateLyrics(w.lyricsSongURI)
+	}
+}
@@ -24,14 +24,30 @@ import (
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/pkg/errors"
+	"github.com/yktoo/ymuse/internal/cache"
 	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/fade"
+	"github.com/yktoo/ymuse/internal/lyrics"
+	"github.com/yktoo/ymuse/internal/notify"
+	"github.com/yktoo/ymuse/internal/playhistory"
+	"github.com/yktoo/ymuse/internal/playlistfile"
+	"github.com/yktoo/ymuse/internal/scrobbler"
+	"github.com/yktoo/ymuse/internal/sleep"
+	"github.com/yktoo/ymuse/internal/streamhistory"
+	"github.com/yktoo/ymuse/internal/tray"
 	"github.com/yktoo/ymuse/internal/util"
 	"html"
 	"html/template"
+	"net/url"
+	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -50,11 +66,17 @@ type MainWindow struct {
 	RandomButton           *gtk.ToggleToolButton
 	RepeatButton           *gtk.ToggleToolButton
 	ConsumeButton          *gtk.ToggleToolButton
+	LyricsButton           *gtk.ToggleToolButton
 	VolumeButton           *gtk.VolumeButton
 	VolumeAdjustment       *gtk.Adjustment
 	PlayPositionScale      *gtk.Scale
 	PlayPositionAdjustment *gtk.Adjustment
 	AlbumArtworkImage      *gtk.Image
+	// Lyrics widgets
+	LyricsRevealer       *gtk.Revealer
+	LyricsScrolledWindow *gtk.ScrolledWindow
+	LyricsListBox        *gtk.ListBox
+	LyricsInfoLabel      *gtk.Label
 	// Queue widgets
 	QueueBox                         *gtk.Box
 	QueueToolbar                     *gtk.Toolbar
@@ -85,6 +107,8 @@ type MainWindow struct {
 	// Library widgets
 	LibraryUpdatePopoverMenu        *gtk.PopoverMenu
 	LibraryAddToPlaylistPopoverMenu *gtk.PopoverMenu
+	LibraryBookmarksPopoverMenu     *gtk.PopoverMenu
+	LibraryBookmarksBox             *gtk.Box
 	LibraryAddToPlaylistBox         *gtk.Box
 	LibraryBox                      *gtk.Box
 	LibraryPathBox                  *gtk.Box
@@ -93,21 +117,45 @@ type MainWindow struct {
 	LibraryToolStack                *gtk.Stack
 	LibrarySearchEntry              *gtk.SearchEntry
 	LibrarySearchAttrComboBox       *gtk.ComboBoxText
-	LibraryListBox                  *gtk.ListBox
+	LibrarySearchAdvancedButton     *gtk.ToggleButton
+	LibraryAdvancedSearchRevealer   *gtk.Revealer
+	LibraryAdvancedSearchClauseBox  *gtk.Box
+	LibraryListHBox                 *gtk.Box
+	LibraryScrolledWindow           *gtk.ScrolledWindow
+	LibraryTreeView                 *gtk.TreeView
+	LibraryTreeSelection            *gtk.TreeSelection
+	LibraryListStore                *gtk.ListStore
+	LibraryAppendColumn             *gtk.TreeViewColumn
+	LibraryReplaceColumn            *gtk.TreeViewColumn
+	LibraryIndexBox                 *gtk.Box
+	LibraryGridToolButton           *gtk.ToggleToolButton
+	LibraryGridScrolledWindow       *gtk.ScrolledWindow
+	LibraryFlowBox                  *gtk.FlowBox
 	LibraryInfoLabel                *gtk.Label
 	LibraryMenu                     *gtk.Menu
 	LibraryAppendMenuItem           *gtk.MenuItem
 	LibraryReplaceMenuItem          *gtk.MenuItem
+	LibraryPlayNextMenuItem         *gtk.MenuItem
 	LibraryRenameMenuItem           *gtk.MenuItem
 	LibraryDeleteMenuItem           *gtk.MenuItem
 	LibraryUpdateSelMenuItem        *gtk.MenuItem
 	LibraryAddToPlaylistMenuItem    *gtk.MenuItem
+	// Library playlist editor menu items
+	LibraryPlaylistSeparatorMenuItem *gtk.SeparatorMenuItem
+	LibraryPlaylistMoveUpMenuItem    *gtk.MenuItem
+	LibraryPlaylistMoveDownMenuItem  *gtk.MenuItem
+	LibraryPlaylistInsertMenuItem    *gtk.MenuItem
+	LibraryPlaylistDuplicateMenuItem *gtk.MenuItem
+	LibraryPlaylistMergeMenuItem     *gtk.MenuItem
+	LibraryPlaylistCompareMenuItem   *gtk.MenuItem
+	LibraryPlaylistDedupeMenuItem    *gtk.MenuItem
 	// Streams widgets
 	StreamsBox             *gtk.Box
 	StreamsAddToolButton   *gtk.ToolButton
 	StreamsEditToolButton  *gtk.ToolButton
 	StreamsListBox         *gtk.ListBox
 	StreamsInfoLabel       *gtk.Label
+	StreamsSearchEntry     *gtk.SearchEntry
 	StreamsMenu            *gtk.Menu
 	StreamsAppendMenuItem  *gtk.MenuItem
 	StreamsReplaceMenuItem *gtk.MenuItem
@@ -117,6 +165,51 @@ type MainWindow struct {
 	StreamPropsPopoverMenu *gtk.PopoverMenu
 	StreamPropsNameEntry   *gtk.Entry
 	StreamPropsUriEntry    *gtk.Entry
+	// Streams props popup extras
+	StreamPropsGroupComboBox        *gtk.ComboBoxText
+	StreamPropsGroupEntry           *gtk.Entry
+	StreamPropsTagsEntry            *gtk.Entry
+	StreamPropsLogoEntry            *gtk.Entry
+	StreamPropsFavouriteCheckButton *gtk.CheckButton
+	// Stream history panel
+	StreamsHistoryToolButton   *gtk.ToggleToolButton
+	StreamHistoryRevealer      *gtk.Revealer
+	StreamHistoryTreeView      *gtk.TreeView
+	StreamHistoryTreeSelection *gtk.TreeSelection
+	StreamHistoryListStore     *gtk.ListStore
+	StreamHistoryInfoLabel     *gtk.Label
+	// History page
+	HistoryBox                   *gtk.Box
+	HistoryStack                 *gtk.Stack
+	HistoryTreeView              *gtk.TreeView
+	HistoryTreeSelection         *gtk.TreeSelection
+	HistoryListStore             *gtk.ListStore
+	HistoryStatsTreeView         *gtk.TreeView
+	HistoryStatsListStore        *gtk.ListStore
+	HistoryStatsDetailColumn     *gtk.TreeViewColumn
+	HistoryStatsCategoryComboBox *gtk.ComboBoxText
+	HistoryPeriodComboBox        *gtk.ComboBoxText
+	HistoryInfoLabel             *gtk.Label
+	// Sleep timer and alarm popover
+	SleepMenuButton       *gtk.MenuButton
+	SleepCountdownLabel   *gtk.Label
+	SleepPopoverMenu      *gtk.PopoverMenu
+	SleepModeComboBox     *gtk.ComboBoxText
+	SleepCountSpinButton  *gtk.SpinButton
+	SleepActionComboBox   *gtk.ComboBoxText
+	SleepFadeCheckButton  *gtk.CheckButton
+	AlarmHourSpinButton   *gtk.SpinButton
+	AlarmMinuteSpinButton *gtk.SpinButton
+	AlarmSourceComboBox   *gtk.ComboBoxText
+	AlarmFadeCheckButton  *gtk.CheckButton
+	// Mini player
+	MiniPlayerWindow             *gtk.Window
+	MiniPlayerAlbumArtImage      *gtk.Image
+	MiniPlayerTitleLabel         *gtk.Label
+	MiniPlayerPositionScale      *gtk.Scale
+	MiniPlayerPositionAdjustment *gtk.Adjustment
+	MiniPlayerPositionLabel      *gtk.Label
+	MiniPlayerPlayPauseImage     *gtk.Image
 
 	// Actions
 	aMPDDisconnect        *glib.SimpleAction
@@ -140,10 +233,23 @@ type MainWindow struct {
 	aLibraryRename        *glib.SimpleAction
 	aLibraryDelete        *glib.SimpleAction
 	aLibraryAddToPlaylist *glib.SimpleAction
+	aLibrarySearchSave    *glib.SimpleAction
+	aLibraryGrid          *glib.SimpleAction
+	aLibraryBack          *glib.SimpleAction
+	aLibraryForward       *glib.SimpleAction
 	aStreamAdd            *glib.SimpleAction
 	aStreamEdit           *glib.SimpleAction
 	aStreamDelete         *glib.SimpleAction
+	aStreamExport         *glib.SimpleAction
 	aStreamPropsApply     *glib.SimpleAction
+	aStreamHistoryCopy    *glib.SimpleAction
+	aStreamHistorySearch  *glib.SimpleAction
+	aStreamHistoryExport  *glib.SimpleAction
+	aStreamHistoryClear   *glib.SimpleAction
+	aHistoryAppend        *glib.SimpleAction
+	aHistoryReplace       *glib.SimpleAction
+	aHistoryExport        *glib.SimpleAction
+	aHistoryClear         *glib.SimpleAction
 	aPlayerPrevious       *glib.SimpleAction
 	aPlayerStop           *glib.SimpleAction
 	aPlayerPlayPause      *glib.SimpleAction
@@ -153,6 +259,9 @@ type MainWindow struct {
 	aPlayerRandom         *glib.SimpleAction
 	aPlayerRepeat         *glib.SimpleAction
 	aPlayerConsume        *glib.SimpleAction
+	aSleepCancel          *glib.SimpleAction
+	aAlarmSet             *glib.SimpleAction
+	aAlarmCancel          *glib.SimpleAction
 
 	// Colours
 	colourBgNormal string // Normal background colour
@@ -161,12 +270,69 @@ type MainWindow struct {
 	currentQueueSize  int // Number of items in the play queue
 	currentQueueIndex int // Queue's track index (last) marked as current
 
-	libPath                *LibraryPath // Current library path
-	libPathElementToSelect string       // Library path element to select after list load (serialised)
+	libPath                *LibraryPath       // Current library path
+	libPathElementToSelect string             // Library path element to select after list load (serialised)
+	libSelections          map[string]string  // Last selected element (serialised) per visited library path (serialised)
+	libSearchClauses       []*searchClauseRow // Condition rows of the advanced library search
+	libGridItems           []*libraryGridItem // Items of the library grid view
+	libGeneration          atomic.Int64       // Library content generation, used to discard outdated thumbnails and counts
+	libCountsRequested     map[int]bool       // Library list rows whose track counts have been requested
+	libGridUpdating        bool               // Library grid toggle button update flag
+	libListLabels          []string           // Labels of the library list rows, used for jumping to an index letter
+	libIndexButtons        []*gtk.Button      // Buttons of the library index bar
 
 	playerTitleTemplate      *template.Template // Compiled template for player's track title
 	playerCurrentAlbumArtUri string             // URI of the current player's album art
 
+	miniPlayerTemplate    *template.Template // Compiled template for the mini player's track title
+	miniPlayerAlbumArtURI string             // URI of the track whose album art the mini player shows
+	miniPlayerPosUpdating bool               // Mini player's play position manual update flag
+
+	notifier       *notify.Notifier   // Desktop notifier, nil if notifications are disabled
+	notifyTemplate *template.Template // Compiled template for the notification text, nil on error
+	notifySongID   string             // ID of the song the last notification was shown for
+
+	trayIcon *tray.Item // System tray icon, nil if disabled or there's no system tray
+	quitting bool       // Whether the application is quitting, as opposed to the window hiding in the tray
+
+	passwordPrompting bool // Whether the MPD password prompt is shown
+
+	configMonitor      *util.FileMonitor // Monitor of the config file, nil if unavailable
+	configReloadSource glib.SourceHandle // Timeout source of the pending config reload, 0 if there's none
+
+	fader           *fade.Fader                    // Volume fader
+	sleepTimer      *sleep.Timer                   // Running sleep timer, nil if none
+	sleepStatus     sleep.Status                   // Sleep timer status as of the last check
+	sleepVolume     int                            // Volume to restore after the sleep timer's fade-out, -1 if there's been no fade
+	sleepTickSource glib.SourceHandle              // Timeout source running the sleep timer and the alarm, 0 if there's none
+	alarmAt         time.Time                      // Time the alarm goes off, zero if it isn't set
+	fadeVolume      int                            // Volume the fade on pausing, stopping or resuming returns to, -1 if there's no fade
+	fadingOut       bool                           // Whether the fade under way precedes pausing or stopping
+	fadeSerial      int                            // Number of the last fade on pausing, stopping or resuming, to ignore outdated ones
+	fadeCommand     func(client *mpd.Client) error // MPD command to run once the fade-out under way completes, nil if none
+	fadeErrMessage  string                         // Error message to show if fadeCommand fails
+
+	streamsRows        []streamsListRow       // Rows of the Streams list
+	streamsURIToSelect string                 // URI of the stream to select after the Streams list update
+	streamLogos        map[string]*gdk.Pixbuf // Scaled stream logos by size and file path, nil values for unloadable files
+
+	streamHistory           *streamhistory.History // Titles played by streams
+	streamHistoryURI        string                 // URI of the stream displayed in the history panel
+	streamHistoryEntries    []streamhistory.Entry  // Entries displayed in the history panel, newest first
+	streamHistoryUpdating   bool                   // Stream history button update flag
+	streamHistorySaveSource glib.SourceHandle      // Timeout source of the pending stream history save, 0 if there's none
+
+	playHistory     *playhistory.Store     // Listening history
+	historyPlays    []playhistory.Play     // Plays displayed on the History page, newest first
+	historyStats    []playhistory.StatItem // Entries displayed in the statistics view
+	historyUpdating bool                   // History period and category update flag
+
+	lyrics         *lyrics.Lyrics // Lyrics displayed in the lyrics panel, nil if there are none
+	lyricsURI      string         // URI of the song the displayed lyrics belong to
+	lyricsSongURI  string         // URI of the current song
+	lyricsLine     int            // Index of the highlighted lyrics line, -1 if none
+	lyricsUpdating bool           // Lyrics button update flag
+
 	volumeUpdating  bool // Volume button update (initiated by an MPD event) flag
 	playPosUpdating bool // Play position manual update flag
 	optionsUpdating bool // Options update flag
@@ -180,8 +346,42 @@ const (
 
 	queueSaveNewPlaylistID = "\u0001new"
 	librarySearchAllAttrID = "\u0001any"
+
+	// Size of stream logos in the streams list, in pixels
+	streamsLogoSize = 24
+
+	// Library list store columns
+	libraryColIcon        = 0
+	libraryColLabel       = 1
+	libraryColDetails     = 2
+	libraryColFontWeight  = 3
+	libraryColElement     = 4
+	libraryColAppendIcon  = 5
+	libraryColReplaceIcon = 6
+
+	// Delay before reloading the config file after it's changed, in milliseconds, to let the change settle
+	configReloadDelay = 500
+	// Delay before writing out the stream history after a title change, in milliseconds, so that writes are batched
+	streamHistorySaveDelay = 30000
+
+	// Minimum number of items in the library list for the index bar to show up
+	libraryIndexMinItems = 50
+	// Label of the index bar button that jumps to items not starting with a letter
+	libraryIndexOther = "#"
 )
 
+// streamsListRow describes a row of the Streams list
+type streamsListRow struct {
+	index int    // Index of the stream in the stream list, -1 for a group header row
+	group string // ID of the group the row belongs to or is the header of
+}
+
+// libraryQueueItem is a library element resolved for queueing: either a URI or a stored playlist name
+type libraryQueueItem struct {
+	uri      string // Track or folder URI
+	playlist string // Stored playlist name
+}
+
 type triBool int
 
 const (
@@ -225,19 +425,45 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 		"on_QueueTreeSelection_changed":                w.updateQueueActions,
 		"on_QueueSearchBar_searchMode":                 w.onQueueSearchMode,
 		"on_QueueSearchEntry_searchChanged":            w.queueFilter,
-		"on_LibraryListBox_buttonPress":                w.onLibraryListBoxButtonPress,
-		"on_LibraryListBox_keyPress":                   w.onLibraryListBoxKeyPress,
-		"on_LibraryListBox_selectionChange":            w.updateLibraryActions,
+		"on_LibraryTreeView_buttonPress":               w.onLibraryTreeViewButtonPress,
+		"on_LibraryTreeView_keyPress":                  w.onLibraryKeyPress,
+		"on_LibraryTreeSelection_changed":              w.onLibrarySelectionChange,
+		"on_LibraryFlowBox_buttonPress":                w.onLibraryFlowBoxButtonPress,
+		"on_LibraryFlowBox_keyPress":                   w.onLibraryKeyPress,
+		"on_LibraryFlowBox_selectedChildrenChanged":    w.onLibrarySelectionChange,
+		"on_LibraryFlowBox_sizeAllocate":               w.libraryGridLoadVisible,
 		"on_LibrarySearchChanged":                      w.updateLibrary,
 		"on_LibrarySearchStop":                         w.onLibraryStopSearch,
+		"on_LibrarySearchAdvancedButton_toggled":       w.onLibrarySearchAdvancedToggle,
+		"on_LibraryAdvancedSearchAddButton_clicked":    w.libraryAddSearchClause,
 		"on_StreamsListBox_buttonPress":                w.onStreamListBoxButtonPress,
 		"on_StreamsListBox_keyPress":                   w.onStreamListBoxKeyPress,
-		"on_StreamsListBox_selectionChange":            w.updateStreamsActions,
+		"on_StreamsListBox_selectionChange":            w.onStreamsSelectionChange,
+		"on_StreamsBox_dragDataReceived":               w.onStreamsDragDataReceived,
+		"on_StreamsListBox_rowActivated":               w.onStreamsListBoxRowActivated,
+		"on_StreamsSearchEntry_searchChanged":          w.updateStreams,
+		"on_StreamsSearchEntry_stopSearch":             w.onStreamsStopSearch,
+		"on_StreamPropsLogoEntry_iconPress":            w.onStreamPropsLogoChoose,
 		"on_StreamPropsChanged":                        w.onStreamPropsChanged,
+		"on_StreamHistoryTreeView_rowActivated":        w.onStreamHistorySearch,
+		"on_StreamHistoryTreeSelection_changed":        w.updateStreamHistoryActions,
+		"on_HistoryStack_switched":                     w.onHistoryStackSwitched,
+		"on_HistoryStats_changed":                      w.onHistoryStatsChanged,
+		"on_HistoryTreeView_rowActivated":              w.onHistoryRowActivated,
+		"on_HistoryTreeSelection_changed":              w.updateHistoryActions,
+		"on_HistoryStatsTreeView_rowActivated":         w.onHistoryStatsRowActivated,
 		"on_QueueSavePopoverMenu_validate":             w.onQueueSavePopoverValidate,
+		"on_SleepPopoverMenu_show":                     w.onSleepPopoverShow,
+		"on_SleepModeComboBox_changed":                 w.onSleepModeChanged,
+		"on_AlarmMinuteSpinButton_output":              w.onAlarmMinuteOutput,
+		"on_AlarmSourceComboBox_changed":               w.updateSleepActions,
 		"on_VolumeButton_valueChanged":                 w.onVolumeValueChanged,
 		"on_PlayPositionScale_buttonEvent":             w.onPlayPositionButtonEvent,
 		"on_PlayPositionScale_valueChanged":            w.updatePlayerSeekBar,
+		"on_MiniPlayerWindow_delete":                   w.onMiniPlayerDelete,
+		"on_MiniPlayerPositionScale_buttonEvent":       w.onMiniPlayerPositionButtonEvent,
+		"on_MiniPlayerPositionScale_valueChanged":      w.updateMiniPlayerSeekBar,
+		"on_LyricsListBox_rowActivated":                w.onLyricsRowActivated,
 		"on_QueueNowPlayingMenuItem_activate":          w.updateQueueNowPlaying,
 		"on_QueueShowAlbumInLibraryMenuItem_activate":  w.libraryShowAlbumFromQueue,
 		"on_QueueShowArtistInLibraryMenuItem_activate": w.libraryShowArtistFromQueue,
@@ -247,17 +473,27 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 		"on_LibraryAddToPlaylistMenuItem_activate":     w.libraryAddToPlaylist,
 		"on_LibraryAppendMenuItem_activate":            func() { w.applyLibrarySelection(tbFalse) },
 		"on_LibraryReplaceMenuItem_activate":           func() { w.applyLibrarySelection(tbTrue) },
+		"on_LibraryPlayNextMenuItem_activate":          w.queuePlayNext,
+		"on_LibrarySelectAllMenuItem_activate":         w.librarySelectAll,
 		"on_LibraryRenameMenuItem_activate":            w.libraryRename,
 		"on_LibraryDeleteMenuItem_activate":            w.libraryDelete,
 		"on_LibraryUpdateSelMenuItem_activate":         func() { w.libraryUpdate(false, true) },
+		"on_LibraryPlaylistMoveUpMenuItem_activate":    func() { w.libraryPlaylistMove(-1) },
+		"on_LibraryPlaylistMoveDownMenuItem_activate":  func() { w.libraryPlaylistMove(1) },
+		"on_LibraryPlaylistInsertMenuItem_activate":    w.libraryPlaylistInsert,
+		"on_LibraryPlaylistDuplicateMenuItem_activate": w.libraryPlaylistDuplicate,
+		"on_LibraryPlaylistMergeMenuItem_activate":     w.libraryPlaylistMerge,
+		"on_LibraryPlaylistCompareMenuItem_activate":   w.libraryPlaylistCompare,
+		"on_LibraryPlaylistDedupeMenuItem_activate":    w.libraryPlaylistDedupe,
 		"on_StreamsAppendMenuItem_activate":            func() { w.applyStreamSelection(tbFalse) },
 		"on_StreamsReplaceMenuItem_activate":           func() { w.applyStreamSelection(tbTrue) },
 		"on_StreamsEditMenuItem_activate":              w.onStreamEdit,
 		"on_StreamsDeleteMenuItem_activate":            w.onStreamDelete,
 	})
 
-	// Register the main window with the app
+	// Register the main window and the mini player with the app
 	application.AddWindow(w.AppWindow)
+	application.AddWindow(w.MiniPlayerWindow)
 
 	// Restore library path
 	cfg := config.GetConfig()
@@ -273,10 +509,37 @@ func NewMainWindow(application *gtk.Application) (*MainWindow, error) {
 	}
 
 	// Instantiate a connector
-	w.connector = NewConnector(w.onConnectorStatusChange, w.onConnectorHeartbeat, w.onConnectorSubsystemChange)
+	w.connector = NewConnector(w.onConnectorStatusChange, w.onConnectorHeartbeat, w.onConnectorSubsystemChange, w.onConnectorAuthFailure)
+	w.applyScrobblerSettings()
+	w.connector.SetPlayTracker(playhistory.NewTracker(w.playHistory, w.onHistoryPlayRecorded))
+	w.fader = fade.New(w.setVolume)
+
+	// Arm the alarm, if it was set before
+	w.alarmSchedule()
+
+	// Pick up changes made to the config file by someone else
+	w.configMonitor, err = util.MonitorFile(cfg.File(), w.onConfigFileChanged)
+	errCheck(err, "Failed to monitor config file")
 	return w, nil
 }
 
+// onConfigFileChanged schedules a reload of the config file, postponing the pending one
+func (w *MainWindow) onConfigFileChanged() {
+	if w.configReloadSource != 0 {
+		glib.SourceRemove(w.configReloadSource)
+	}
+	w.configReloadSource = glib.TimeoutAdd(configReloadDelay, func() bool {
+		w.configReloadSource = 0
+		w.applyConfig()
+		return false
+	})
+}
+
+// onConnectorAuthFailure prompts for the MPD password after MPD has rejected the current one
+func (w *MainWindow) onConnectorAuthFailure() {
+	glib.IdleAdd(w.connectPrompt)
+}
+
 func (w *MainWindow) onConnectorStatusChange() {
 	// Ignore when not mapped
 	if w.mapped {
@@ -300,6 +563,10 @@ func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 
 	switch subsystem {
 	case "database", "update":
+		// Album art may have changed after a database update
+		if ac := cache.GetAlbumArtCache(); ac != nil && subsystem == "database" {
+			ac.Invalidate()
+		}
 		glib.IdleAdd(w.updateLibrary)
 	case "mixer":
 		glib.IdleAdd(w.updateVolume)
@@ -313,7 +580,8 @@ func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 			w.updatePlayer()
 		})
 	case "stored_playlist":
-		if _, ok := w.libPath.Last().(*PlaylistsLibElement); ok {
+		switch w.libPath.Last().(type) {
+		case *PlaylistsLibElement, *PlaylistLibElement:
 			glib.IdleAdd(w.updateLibrary)
 		}
 	}
@@ -322,9 +590,17 @@ func (w *MainWindow) onConnectorSubsystemChange(subsystem string) {
 func (w *MainWindow) onMap() {
 	log.Debug("MainWindow.onMap()")
 
+	// The window is shown again after being hidden in the tray: everything is up to date already
+	if w.mapped {
+		w.focusMainList()
+		w.updateTrayMenu()
+		return
+	}
+
 	// Update all lists
 	w.updateAll()
 	w.updateStreams()
+	w.updateHistory()
 
 	// Activate the Queue tree view
 	w.focusMainList()
@@ -334,95 +610,169 @@ func (w *MainWindow) onMap() {
 		w.connect()
 	}
 	w.mapped = true
+
+	// Let the user know if the settings couldn't be loaded
+	if msg := config.GetConfig().LoadWarning(); msg != "" {
+		glib.IdleAdd(func() { util.WarningDialog(w.AppWindow, msg) })
+	}
 }
 
-func (w *MainWindow) onDelete() {
-	log.Debug("MainWindow.onDelete()")
-	w.mapped = false
+// saveState stores the library path, the time of this visit and the window dimensions in the config, writes the config
+// out and writes out the album art cache index
+func (w *MainWindow) saveState() {
 	cfg := config.GetConfig()
 
-	// Save the current library path
+	// Save the current library path and the time of this visit
 	cfg.LibraryPath = w.libPath.Marshal()
+	cfg.LibraryLastVisit = time.Now().Unix()
 
 	// Save the current window dimensions in the config
 	x, y := w.AppWindow.GetPosition()
 	width, height := w.AppWindow.GetSize()
 	cfg.MainWindowDimensions = config.Dimensions{X: x, Y: y, Width: width, Height: height}
+	if w.MiniPlayerWindow.GetVisible() {
+		w.miniPlayerStoreDimensions()
+	}
 
-	// Write out the config
+	// Write out the config and the pending stream history changes
 	cfg.Save()
+	w.streamHistoryFlush()
 
-	// Disconnect from MPD
-	w.disconnect()
+	// Write out the album art cache index
+	if ac := cache.GetAlbumArtCache(); ac != nil {
+		ac.Flush()
+	}
 }
 
-func (w *MainWindow) onLibraryAddToPlaylist(playlist string) {
-	log.Debugf("MainWindow.onLibraryAddToPlaylist(%s)", playlist)
+func (w *MainWindow) onDelete() bool {
+	log.Debug("MainWindow.onDelete()")
 
-	// Fetch the selected element, which must be playable
-	element := w.getSelectedLibraryElement()
-	if element == nil || !element.IsPlayable() {
-		return
+	// Only hide the window if there's a tray icon to bring it back with, staying connected. The application may well
+	// be ended along with the session while hidden, so save the state all the same
+	if w.trayIcon != nil && !w.quitting {
+		w.saveState()
+		w.windowHide()
+		return true
 	}
+	w.mapped = false
 
-	// If it's a URI-enabled element
-	if uh, ok := element.(URIHolder); ok {
-		w.libraryAppendPlaylist(playlist, uh.URI())
-		return
+	// Stop watching the config file, which is about to be written
+	if w.configMonitor != nil {
+		w.configMonitor.Cancel()
+		w.configMonitor = nil
 	}
+	if w.configReloadSource != 0 {
+		glib.SourceRemove(w.configReloadSource)
+		w.configReloadSource = 0
+	}
+	w.saveState()
 
-	// Playlist-enabled element
-	if ph, ok := element.(PlaylistHolder); ok {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.PlaylistContents(ph.PlaylistName())
-		})
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) {
-			return
-		}
+	// Get rid of the mini player, which would otherwise keep the application running
+	w.MiniPlayerWindow.Destroy()
 
-		// Extract the URIs and append them to the playlist
-		w.libraryAppendPlaylist(playlist, util.MapAttrsToSlice(attrs, "file")...)
-		return
+	// Stop scrobbling, leaving unsubmitted listens for the next time, and record the current play
+	w.connector.SetScrobbler(nil)
+	w.connector.SetPlayTracker(nil)
+
+	// Cancel the sleep timer, restoring the volume
+	w.sleepCancel()
+
+	// Complete the fade on pausing, stopping or resuming right away, restoring the volume
+	w.playerFadeComplete()
+
+	// Withdraw the notification
+	if w.notifier != nil {
+		errCheck(w.notifier.Close(), "Failed to close notifier")
+		w.notifier = nil
 	}
 
-	// Attribute-enabled path: extend the current path filter with the element and query the tracks
-	if filter := w.libPath.AsFilter(element); len(filter) > 0 {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.Find(filter...)
-		})
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) {
-			return
-		}
+	// Remove the tray icon
+	if w.trayIcon != nil {
+		errCheck(w.trayIcon.Close(), "Failed to remove tray icon")
+		w.trayIcon = nil
+	}
 
-		// Extract the URIs and append them to the playlist
-		w.libraryAppendPlaylist(playlist, util.MapAttrsToSlice(attrs, "file")...)
+	// Disconnect from MPD
+	w.disconnect()
+	return false
+}
+
+func (w *MainWindow) onLibraryAddToPlaylist(playlist string) {
+	log.Debugf("MainWindow.onLibraryAddToPlaylist(%s)", playlist)
+
+	// Resolve the selected elements into track URIs, expanding playlists
+	items, err := w.libraryResolve(w.getSelectedLibraryElements(), true)
+	if w.errCheckDialog(err, glib.Local("Failed to add item to the playlist")) || len(items) == 0 {
 		return
 	}
 
-	// Oops
-	log.Errorf("element %T cannot be added to a playlist", element)
+	// Append the URIs to the playlist
+	uris := make([]string, len(items))
+	for i, item := range items {
+		uris[i] = item.uri
+	}
+	w.libraryAppendPlaylist(playlist, uris...)
 }
 
-func (w *MainWindow) onLibraryListBoxButtonPress(_ *gtk.ListBox, event *gdk.Event) {
-	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
+func (w *MainWindow) onLibraryTreeViewButtonPress(_ *gtk.TreeView, event *gdk.Event) bool {
+	btn := gdk.EventButtonNewFromEvent(event)
+	path, col, _, _, onRow := w.LibraryTreeView.GetPathAtPos(int(btn.X()), int(btn.Y()))
+	switch btn.Type() {
 	// Mouse click
 	case gdk.EVENT_BUTTON_PRESS:
-		// Right click
-		if btn.Button() == 3 {
-			w.LibraryListBox.SelectRow(w.LibraryListBox.GetRowAtY(int(btn.Y())))
+		switch btn.Button() {
+		// Left click: handle the append/replace "buttons"
+		case 1:
+			if !onRow || col == nil {
+				break
+			}
+			var replace triBool
+			switch col.Native() {
+			case w.LibraryAppendColumn.Native():
+				replace = tbFalse
+			case w.LibraryReplaceColumn.Native():
+				replace = tbTrue
+			default:
+				return false
+			}
+			// Only queue the clicked row, leaving the selection intact
+			iter, err := w.LibraryListStore.GetIter(path)
+			if errCheck(err, "GetIter() failed") {
+				break
+			}
+			if element := w.libraryListElement(iter); element != nil && element.IsPlayable() && w.libPath.Last() != nil {
+				w.queueLibraryElements(replace, element)
+				// Stop event propagation
+				return true
+			}
+
+		// Right click: keep the selection if the row clicked is part of it
+		case 3:
+			if onRow && !w.LibraryTreeSelection.PathIsSelected(path) {
+				w.LibraryTreeSelection.UnselectAll()
+				w.LibraryTreeSelection.SelectPath(path)
+			}
 			w.LibraryMenu.PopupAtPointer(event)
+			// Stop event propagation
+			return true
+
+		// "Back" and "Forward" mouse buttons
+		case 8:
+			w.libPath.Back()
+			return true
+		case 9:
+			w.libPath.Forward()
+			return true
 		}
 	// Double click
 	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
 		w.applyLibrarySelection(tbNone)
+		return true
 	}
+	return false
 }
 
-func (w *MainWindow) onLibraryListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) {
+func (w *MainWindow) onLibraryKeyPress(_ interface{}, event *gdk.Event) bool {
 	evt := gdk.EventKeyNewFromEvent(event)
 	state := gdk.ModifierType(evt.State()) & gtk.AcceleratorGetDefaultModMask()
 	switch evt.KeyVal() {
@@ -432,32 +782,68 @@ func (w *MainWindow) onLibraryListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event)
 		// Enter: use default mode
 		case 0:
 			w.applyLibrarySelection(tbNone)
+			return true
 		// Ctrl+Enter: replace
 		case gdk.CONTROL_MASK:
 			w.applyLibrarySelection(tbTrue)
+			return true
 		// Shift+Enter: append
 		case gdk.SHIFT_MASK:
 			w.applyLibrarySelection(tbFalse)
+			return true
+		// Alt+Enter: play next
+		case gdk.MOD1_MASK:
+			w.queuePlayNext()
+			return true
+		}
+
+	// Ctrl+A: select all
+	case gdk.KEY_a:
+		if state == gdk.CONTROL_MASK {
+			w.librarySelectAll()
+			return true
 		}
 
 	// Backspace: go level up (not in search mode)
 	case gdk.KEY_BackSpace:
 		if state == 0 && !w.LibrarySearchToolButton.GetActive() {
 			w.libraryLevelUp()
+			return true
 		}
 
 	// Escape: deactivate search mode
 	case gdk.KEY_Escape:
 		if state == 0 {
 			w.onLibraryStopSearch()
+			return true
+		}
+
+	// Delete: delete the selected element
+	case gdk.KEY_Delete:
+		if state == 0 {
+			w.libraryDelete()
+			return true
 		}
 
-	// Ctrl+F: activate search mode
+	// Alt+Up/Alt+Down: move the selected playlist entry
+	case gdk.KEY_Up, gdk.KEY_Down:
+		if state == gdk.MOD1_MASK {
+			if evt.KeyVal() == gdk.KEY_Up {
+				w.libraryPlaylistMove(-1)
+			} else {
+				w.libraryPlaylistMove(1)
+			}
+			return true
+		}
+
+	// Ctrl+F: activate search mode (instead of the tree view's own interactive search)
 	case gdk.KEY_f:
 		if state == gdk.CONTROL_MASK {
 			w.LibrarySearchToolButton.SetActive(true)
+			return true
 		}
 	}
+	return false
 }
 
 // onLibrarySearchToggle activates or deactivates library search mode
@@ -467,11 +853,17 @@ func (w *MainWindow) onLibrarySearchToggle() {
 	// Show the appropriate tool stack's page
 	if searchMode {
 		w.LibraryToolStack.SetVisibleChild(w.LibrarySearchBox)
-		// Clear and shift focus to the search entry
+		// Clear and shift focus to the search entry, or to the advanced search panel if it's active
 		w.LibrarySearchEntry.SetText("")
-		w.LibrarySearchEntry.GrabFocus()
+		if w.LibrarySearchAdvancedButton.GetActive() && len(w.libSearchClauses) > 0 {
+			w.LibraryAdvancedSearchRevealer.SetRevealChild(true)
+			w.libSearchClauses[0].grabFocus()
+		} else {
+			w.LibrarySearchEntry.GrabFocus()
+		}
 	} else {
 		w.LibraryToolStack.SetVisibleChild(w.LibraryPathBox)
+		w.LibraryAdvancedSearchRevealer.SetRevealChild(false)
 	}
 
 	// Run search or load library
@@ -483,6 +875,29 @@ func (w *MainWindow) onLibrarySearchToggle() {
 	}
 }
 
+// onLibrarySearchAdvancedToggle activates or deactivates the advanced library search panel
+func (w *MainWindow) onLibrarySearchAdvancedToggle() {
+	advanced := w.LibrarySearchAdvancedButton.GetActive()
+
+	// The simple search is replaced by the advanced panel
+	w.LibrarySearchEntry.SetSensitive(!advanced)
+	w.LibrarySearchAttrComboBox.SetSensitive(!advanced)
+	w.LibraryAdvancedSearchRevealer.SetRevealChild(advanced)
+
+	// Make sure there's at least one condition to start with
+	if advanced && len(w.libSearchClauses) == 0 {
+		w.libraryAddSearchClause()
+	}
+
+	// Rerun the search
+	w.updateLibrary()
+	if advanced && len(w.libSearchClauses) > 0 {
+		w.libSearchClauses[0].grabFocus()
+	} else {
+		w.LibrarySearchEntry.GrabFocus()
+	}
+}
+
 // onLibraryStopSearch deactivates library search mode
 func (w *MainWindow) onLibraryStopSearch() {
 	w.LibrarySearchToolButton.SetActive(false)
@@ -491,12 +906,48 @@ func (w *MainWindow) onLibraryStopSearch() {
 func (w *MainWindow) onLibraryPathChanged() {
 	// Ignore when not mapped
 	if w.mapped {
+		// Restore the selection last made on this path, unless a specific element is requested
+		if w.libPathElementToSelect == "" {
+			w.libPathElementToSelect = w.libSelections[w.libPath.Marshal()]
+		}
 		w.updateLibraryPath()
 		w.updateLibrary()
 		w.focusMainList()
 	}
 }
 
+func (w *MainWindow) onLibrarySelectionChange() {
+	// Remember the selection made on the current path, to restore it when the path is revisited
+	if e := w.getSelectedLibraryElement(); e != nil {
+		w.libSelections[w.libPath.Marshal()] = e.Marshal()
+	}
+	w.updateLibraryActions()
+}
+
+func (w *MainWindow) onLibraryFlowBoxButtonPress(_ *gtk.FlowBox, event *gdk.Event) {
+	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
+	// Mouse click
+	case gdk.EVENT_BUTTON_PRESS:
+		switch btn.Button() {
+		// Right click
+		case 3:
+			if item := w.libraryGridItemAt(int(btn.X()), int(btn.Y())); item != nil && !item.child.IsSelected() {
+				w.LibraryFlowBox.UnselectAll()
+				w.LibraryFlowBox.SelectChild(item.child)
+			}
+			w.LibraryMenu.PopupAtPointer(event)
+		// "Back" and "Forward" mouse buttons
+		case 8:
+			w.libPath.Back()
+		case 9:
+			w.libPath.Forward()
+		}
+	// Double click
+	case gdk.EVENT_DOUBLE_BUTTON_PRESS:
+		w.applyLibrarySelection(tbNone)
+	}
+}
+
 func (w *MainWindow) onPlayPositionButtonEvent(_ interface{}, event *gdk.Event) {
 	switch gdk.EventButtonNewFromEvent(event).Type() {
 	case gdk.EVENT_BUTTON_PRESS:
@@ -504,10 +955,7 @@ func (w *MainWindow) onPlayPositionButtonEvent(_ interface{}, event *gdk.Event)
 
 	case gdk.EVENT_BUTTON_RELEASE:
 		w.playPosUpdating = false
-		w.connector.IfConnected(func(client *mpd.Client) {
-			d := time.Duration(w.PlayPositionAdjustment.GetValue())
-			errCheck(client.SeekCur(d*time.Second, false), "SeekCur() failed")
-		})
+		w.playerSeekTo(w.PlayPositionAdjustment.GetValue())
 	}
 }
 
@@ -668,8 +1116,7 @@ func (w *MainWindow) onQueueTreeViewKeyPress(_ *gtk.TreeView, event *gdk.Event)
 
 func (w *MainWindow) onStreamAdd() {
 	// Reset property values
-	w.StreamPropsNameEntry.SetText("")
-	w.StreamPropsUriEntry.SetText("")
+	w.streamPropsFill(config.StreamSpec{})
 
 	// Disable the Apply action initially
 	w.aStreamPropsApply.SetEnabled(false)
@@ -708,8 +1155,7 @@ func (w *MainWindow) onStreamEdit() {
 	stream := config.GetConfig().Streams[idx]
 
 	// Reset property values
-	w.StreamPropsNameEntry.SetText(stream.Name)
-	w.StreamPropsUriEntry.SetText(stream.URI)
+	w.streamPropsFill(stream)
 
 	// Disable the Apply action initially
 	w.aStreamPropsApply.SetEnabled(false)
@@ -720,6 +1166,35 @@ func (w *MainWindow) onStreamEdit() {
 	w.StreamPropsPopoverMenu.Popup()
 }
 
+func (w *MainWindow) onStreamExport() {
+	// Ask for the file name
+	fileName, ok := util.SaveFileDialog(w.AppWindow, glib.Local("Export streams"), "streams.m3u", glib.Local("Export"), streamsFileFilters())
+	if !ok {
+		return
+	}
+
+	// Fall back to M3U if the format can't be derived from the file name
+	format := playlistfile.FormatOf(fileName)
+	if format == playlistfile.FormatUnknown {
+		format = playlistfile.FormatM3U
+		fileName += format.Extensions()[0]
+	}
+
+	// Write out the streams
+	streams := config.GetConfig().Streams
+	entries := make([]playlistfile.Entry, len(streams))
+	for i, stream := range streams {
+		entries[i] = playlistfile.Entry{Name: stream.Name, URI: stream.URI}
+	}
+	w.errCheckDialog(playlistfile.WriteFile(fileName, format, entries), glib.Local("Failed to export streams"))
+}
+
+func (w *MainWindow) onStreamImport() {
+	if fileNames, ok := util.OpenFilesDialog(w.AppWindow, glib.Local("Import streams"), glib.Local("Import"), streamsFileFilters()); ok {
+		w.streamsImport(fileNames)
+	}
+}
+
 func (w *MainWindow) onStreamListBoxButtonPress(_ *gtk.ListBox, event *gdk.Event) {
 	switch btn := gdk.EventButtonNewFromEvent(event); btn.Type() {
 	// Mouse click
@@ -752,6 +1227,57 @@ func (w *MainWindow) onStreamListBoxKeyPress(_ *gtk.ListBox, event *gdk.Event) {
 		case gdk.SHIFT_MASK:
 			w.applyStreamSelection(tbFalse)
 		}
+
+	// Ctrl+F: focus the search entry
+	case gdk.KEY_f:
+		if state == gdk.CONTROL_MASK {
+			w.StreamsSearchEntry.GrabFocus()
+		}
+	}
+}
+
+func (w *MainWindow) onStreamsListBoxRowActivated(_ *gtk.ListBox, row *gtk.ListBoxRow) {
+	// Only group headers react to activation, and only when not searching (all groups are expanded then)
+	i := row.GetIndex()
+	if i < 0 || i >= len(w.streamsRows) || w.streamsRows[i].index >= 0 || util.EntryText(&w.StreamsSearchEntry.Entry, "") != "" {
+		return
+	}
+
+	// Toggle the group's collapsed state
+	cfg := config.GetConfig()
+	if cfg.StreamsCollapsedGroups == nil {
+		cfg.StreamsCollapsedGroups = make(map[string]bool)
+	}
+	if id := w.streamsRows[i].group; cfg.StreamsCollapsedGroups[id] {
+		delete(cfg.StreamsCollapsedGroups, id)
+	} else {
+		cfg.StreamsCollapsedGroups[id] = true
+	}
+	w.updateStreams()
+}
+
+func (w *MainWindow) onStreamsStopSearch() {
+	w.StreamsSearchEntry.SetText("")
+	w.focusMainList()
+}
+
+func (w *MainWindow) onStreamsSelectionChange() {
+	w.updateStreamsActions()
+	w.updateStreamHistory()
+}
+
+func (w *MainWindow) onStreamsDragDataReceived(_, _ interface{}, _, _ int, data *gtk.SelectionData) {
+	// Pick local files from the dropped URIs
+	var fileNames []string
+	for _, uri := range data.GetURIs() {
+		if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+			fileNames = append(fileNames, u.Path)
+		}
+	}
+
+	// Import the files once the drop is over, since the import may show dialogs
+	if len(fileNames) > 0 {
+		glib.IdleAdd(func() { w.streamsImport(fileNames) })
 	}
 }
 
@@ -764,8 +1290,12 @@ func (w *MainWindow) onStreamPropsApply() {
 
 	// Make a stream spec instance
 	stream := config.StreamSpec{
-		Name: name,
-		URI:  uri,
+		Name:      name,
+		URI:       uri,
+		Group:     strings.TrimSpace(util.EntryText(w.StreamPropsGroupEntry, "")),
+		Tags:      util.SplitList(util.EntryText(w.StreamPropsTagsEntry, "")),
+		Logo:      strings.TrimSpace(util.EntryText(w.StreamPropsLogoEntry, "")),
+		Favourite: w.StreamPropsFavouriteCheckButton.GetActive(),
 	}
 
 	// Adding a stream
@@ -778,7 +1308,9 @@ func (w *MainWindow) onStreamPropsApply() {
 		cfg.Streams[idx] = stream
 	}
 
-	// Update stream list
+	// Reload the logos in case the image files have changed, and update stream list
+	w.streamLogos = nil
+	w.streamsURIToSelect = uri
 	w.updateStreams()
 	w.focusMainList()
 }
@@ -790,8 +1322,20 @@ func (w *MainWindow) onStreamPropsChanged() {
 			util.EntryText(w.StreamPropsUriEntry, "") != "")
 }
 
+func (w *MainWindow) onStreamPropsLogoChoose() {
+	filters := []util.FileFilterSpec{{Name: glib.Local("Images"), MimeTypes: []string{"image/*"}}}
+	if fileNames, ok := util.OpenFilesDialog(w.AppWindow, glib.Local("Choose logo image"), glib.Local("Choose"), filters); ok {
+		w.StreamPropsLogoEntry.SetText(fileNames[0])
+	}
+
+	// The popover may have closed while the dialog was open
+	w.StreamPropsPopoverMenu.Popup()
+}
+
 func (w *MainWindow) onVolumeValueChanged() {
 	if !w.volumeUpdating {
+		// The user takes over the volume
+		w.playerFadeCancel()
 		vol := int(w.VolumeAdjustment.GetValue())
 		log.Debugf("Adjusting volume to %d", vol)
 		w.connector.IfConnected(func(client *mpd.Client) {
@@ -811,43 +1355,83 @@ func (w *MainWindow) addAction(name, shortcut string, onActivate func()) *glib.S
 	return action
 }
 
+// applyConfig reloads the config file changed by someone else and applies the changes
+func (w *MainWindow) applyConfig() {
+	cfg := config.GetConfig()
+	network, addr := cfg.MpdNetworkAddress()
+	password, autoReconnect, grouping := cfg.MpdPassword, cfg.MpdAutoReconnect, cfg.AlbumGrouping
+	libPath, smartFolders, bookmarks := cfg.LibraryPath, cfg.SmartFolders, cfg.LibraryBookmarks
+	scrobblerSettings := func() []string {
+		return []string{cfg.ScrobblerService, cfg.ScrobblerURL, cfg.ScrobblerToken, cfg.ScrobblerAPIKey, cfg.ScrobblerAPISecret}
+	}
+	scrobbling := scrobblerSettings()
+	if changed, err := cfg.Reload(); errCheck(err, "Failed to reload config") || !changed {
+		return
+	}
+	log.Infof("Reloaded configuration from %s", cfg.File())
+
+	// Apply the new settings. Recreating the scrobbler would lose the current play, so only do it when needed
+	w.updateQueueColumns()
+	w.applyPlayerSettings()
+	if !slices.Equal(scrobblerSettings(), scrobbling) {
+		w.applyScrobblerSettings()
+	}
+	w.alarmSchedule()
+	w.updateStreams()
+
+	// Refresh the library if anything it shows has changed
+	switch {
+	case cfg.LibraryPath != libPath:
+		errCheck(w.libPath.Navigate(cfg.LibraryPath), "Failed to navigate to library path")
+	case cfg.AlbumGrouping != grouping ||
+		!reflect.DeepEqual(cfg.SmartFolders, smartFolders) ||
+		!reflect.DeepEqual(cfg.LibraryBookmarks, bookmarks):
+		w.updateLibrary()
+	}
+
+	// Reconnect if connection settings have changed
+	if n, a := cfg.MpdNetworkAddress(); n != network || a != addr || cfg.MpdPassword != password || cfg.MpdAutoReconnect != autoReconnect {
+		w.connect()
+	}
+}
+
 // applyLibrarySelection navigates into the folder or adds or replaces the content of the queue with the currently
 // selected items in the library
 func (w *MainWindow) applyLibrarySelection(replace triBool) {
-	// Get selected element
-	e := w.getSelectedLibraryElement()
-	if e == nil {
+	// Get selected elements
+	elements := w.getSelectedLibraryElements()
+	if len(elements) == 0 {
 		return
 	}
 
 	// Level-up element
-	if _, ok := e.(*LevelUpLibElement); ok {
+	if _, ok := elements[0].(*LevelUpLibElement); ok && len(elements) == 1 {
 		w.libraryLevelUp()
 
-	} else if replace == tbNone && e.IsFolder() {
-		// Default for folders is entering into
-		w.libPath.Append(e)
+	} else if replace == tbNone && len(elements) == 1 && elements[0].IsFolder() {
+		// Default for a single folder is entering into
+		w.libPath.Append(elements[0])
 
 	} else {
-		// Queue the element up otherwise
-		w.queueLibraryElement(replace, e)
+		// Queue the elements up otherwise
+		w.queueLibraryElements(replace, elements...)
 	}
 }
 
-// applyPlayerSettings compiles the player title template and updates the player
+// applyPlayerSettings compiles the player title and notification templates, sets up the notifier and the tray icon,
+// and updates the player
 func (w *MainWindow) applyPlayerSettings() {
 	// Apply toolbar setting
 	cfg := config.GetConfig()
 	w.QueueToolbar.SetVisible(cfg.QueueToolbar)
 
 	// Compile and apply the track title template
-	tmpl, err := template.New("playerTitle").
-		Funcs(template.FuncMap{
-			"default":  util.Default,
-			"dirname":  path.Dir,
-			"basename": path.Base,
-		}).
-		Parse(cfg.PlayerTitleTemplate)
+	funcs := template.FuncMap{
+		"default":  util.Default,
+		"dirname":  path.Dir,
+		"basename": path.Base,
+	}
+	tmpl, err := template.New("playerTitle").Funcs(funcs).Parse(cfg.PlayerTitleTemplate)
 	if errCheck(err, "Template parse error") {
 		w.playerTitleTemplate = template.Must(
 			template.New("error").Parse("<span foreground=\"red\">[" + glib.Local("Player title template error, check log") + "]</span>"))
@@ -855,6 +1439,47 @@ func (w *MainWindow) applyPlayerSettings() {
 		w.playerTitleTemplate = tmpl
 	}
 
+	// Compile the mini player title template
+	tmpl, err = template.New("miniPlayer").Funcs(funcs).Parse(cfg.MiniPlayerTemplate)
+	if errCheck(err, "Mini player template parse error") {
+		w.miniPlayerTemplate = template.Must(
+			template.New("error").Parse("<span foreground=\"red\">[" + glib.Local("Mini player template error, check log") + "]</span>"))
+	} else {
+		w.miniPlayerTemplate = tmpl
+	}
+
+	// Compile the notification template
+	w.notifyTemplate, err = template.New("notify").Funcs(funcs).Parse(cfg.PlayerNotifyTemplate)
+	if errCheck(err, "Notification template parse error") {
+		w.notifyTemplate = nil
+	}
+
+	// Connect to or disconnect from the notification service
+	switch {
+	case cfg.PlayerNotify && w.notifier == nil:
+		n, err := notify.New(config.AppMetadata.Name, w.onNotifyAction)
+		if !errCheck(err, "Failed to connect to notification service") {
+			w.notifier = n
+			// Notify about the song currently playing
+			w.notifySongID = ""
+		}
+	case !cfg.PlayerNotify && w.notifier != nil:
+		errCheck(w.notifier.Close(), "Failed to close notifier")
+		w.notifier = nil
+	}
+
+	// Show or remove the tray icon
+	switch {
+	case cfg.TrayIcon && w.trayIcon == nil:
+		t, err := tray.New(config.AppMetadata.ID, config.AppMetadata.Name, config.AppMetadata.Icon, w.onTrayActivate, w.onTrayScroll)
+		if !errCheck(err, "Failed to show tray icon") {
+			w.trayIcon = t
+		}
+	case !cfg.TrayIcon && w.trayIcon != nil:
+		errCheck(w.trayIcon.Close(), "Failed to remove tray icon")
+		w.trayIcon = nil
+	}
+
 	// Update the displayed title/artwork if the connector is initialised
 	if w.connector != nil {
 		w.updatePlayer()
@@ -876,6 +1501,35 @@ func (w *MainWindow) applyQueueSelection() {
 	w.errCheckDialog(err, glib.Local("Failed to play the selected track"))
 }
 
+// applyScrobblerSettings (re)creates the scrobbler according to the configuration
+func (w *MainWindow) applyScrobblerSettings() {
+	cfg := config.GetConfig()
+	var service scrobbler.Service
+	switch cfg.ScrobblerService {
+	case config.ScrobblerServiceListenBrainz:
+		service = &scrobbler.ListenBrainz{
+			URL:           cfg.ScrobblerURL,
+			Token:         cfg.ScrobblerToken,
+			ClientVersion: config.AppMetadata.Version,
+		}
+	case config.ScrobblerServiceAudioscrobbler:
+		service = &scrobbler.Audioscrobbler{
+			URL:        cfg.ScrobblerURL,
+			APIKey:     cfg.ScrobblerAPIKey,
+			APISecret:  cfg.ScrobblerAPISecret,
+			SessionKey: cfg.ScrobblerToken,
+		}
+	}
+
+	// Every service has its own queue of pending listens
+	var s *scrobbler.Scrobbler
+	if service != nil {
+		file := path.Join(glib.GetUserDataDir(), "ymuse", fmt.Sprintf("scrobbler-%s-queue.json", cfg.ScrobblerService))
+		s = scrobbler.New(service, file)
+	}
+	w.connector.SetScrobbler(s)
+}
+
 // applyStreamSelection adds or replaces the content of the queue with the currently selected stream
 func (w *MainWindow) applyStreamSelection(replace triBool) {
 	if idx := w.getSelectedStreamIndex(); idx >= 0 {
@@ -891,7 +1545,32 @@ func (w *MainWindow) connect() {
 	// Start connecting
 	cfg := config.GetConfig()
 	network, addr := cfg.MpdNetworkAddress()
-	w.connector.Start(network, addr, cfg.MpdPassword, cfg.MpdAutoReconnect)
+	w.connector.Start(network, addr, cfg.MpdPasswordLookup(), cfg.MpdAutoReconnect)
+}
+
+// connectPrompt asks the user for the MPD password, stores it and reconnects with it
+func (w *MainWindow) connectPrompt() {
+	// Don't stack up prompts
+	if w.passwordPrompting {
+		return
+	}
+	w.passwordPrompting = true
+	defer func() { w.passwordPrompting = false }()
+
+	cfg := config.GetConfig()
+	_, addr := cfg.MpdNetworkAddress()
+	password, ok := util.PasswordDialog(
+		w.AppWindow,
+		glib.Local("MPD password"),
+		fmt.Sprintf(glib.Local("MPD at %s has rejected the password or requires one. Please enter the password:"), addr),
+		glib.Local("Connect"))
+	if !ok {
+		return
+	}
+	cfg.StoreMpdPassword(password, func(err error) {
+		w.errCheckDialog(err, glib.Local("Failed to store the password in the keyring"))
+		w.connect()
+	})
 }
 
 // disconnect starts disconnecting from MPD
@@ -917,12 +1596,16 @@ func (w *MainWindow) focusMainList() {
 	case "queue":
 		widget = &w.QueueTreeView.Widget
 
-	// Library: move focus to the selected row, if any
+	// Library: move focus to the selected row or grid item, if any
 	case "library":
-		if row := w.LibraryListBox.GetSelectedRow(); row != nil {
-			widget = &row.Widget
+		if w.LibraryGridScrolledWindow.GetVisible() {
+			if children := w.LibraryFlowBox.GetSelectedChildren(); len(children) > 0 {
+				widget = &children[0].Widget
+			} else {
+				widget = &w.LibraryFlowBox.Widget
+			}
 		} else {
-			widget = &w.LibraryListBox.Widget
+			widget = &w.LibraryTreeView.Widget
 		}
 
 	// Streams: move focus to the selected row, if any
@@ -932,6 +1615,14 @@ func (w *MainWindow) focusMainList() {
 		} else {
 			widget = &w.StreamsListBox.Widget
 		}
+
+	// History: move focus to the list of the visible view
+	case "history":
+		if w.HistoryStack.GetVisibleChildName() == "stats" {
+			widget = &w.HistoryStatsTreeView.Widget
+		} else {
+			widget = &w.HistoryTreeView.Widget
+		}
 	}
 
 	// Move focus
@@ -996,25 +1687,61 @@ func (w *MainWindow) getQueueSelectedTrackAttrs() (mpd.Attrs, error) {
 	return nil, errors.New("No selection in the queue")
 }
 
-// getSelectedLibraryElement returns the path element of the currently selected library item or nil if there's an error
-func (w *MainWindow) getSelectedLibraryElement() LibraryPathElement {
-	// If there's selection
-	row := w.LibraryListBox.GetSelectedRow()
-	if row == nil {
-		return nil
+// getQueueSelectedURIs returns URIs of the currently selected tracks in the queue, in the queue order
+func (w *MainWindow) getQueueSelectedURIs() []string {
+	indices := w.getQueueSelectedIndices()
+	sort.Ints(indices)
+	uris := make([]string, 0, len(indices))
+	for _, idx := range indices {
+		iter, err := w.QueueListStore.GetIterFromString(strconv.Itoa(idx))
+		if errCheck(err, "getQueueSelectedURIs(): GetIterFromString() failed") {
+			continue
+		}
+		v, err := w.QueueListStore.GetValue(iter, config.MTAttrPath)
+		if errCheck(err, "getQueueSelectedURIs(): QueueListStore.GetValue() failed") {
+			continue
+		}
+		if uri, err := v.GetString(); err == nil && uri != "" {
+			uris = append(uris, uri)
+		}
 	}
+	return uris
+}
 
-	// Extract path, which is stored in the row's name
-	name, err := row.GetName()
-	if errCheck(err, "getSelectedLibraryPath(): row.GetName() failed") {
-		return nil
+// getSelectedLibraryElement returns the path element of the currently selected library item or nil if there's an error,
+// no selection, or more than one item is selected
+func (w *MainWindow) getSelectedLibraryElement() LibraryPathElement {
+	if elements := w.getSelectedLibraryElements(); len(elements) == 1 {
+		return elements[0]
 	}
+	return nil
+}
 
-	// Unmarshal the element from name
-	if element, err := UnmarshalLibPathElement(name); !errCheck(err, "Unmarshalling failed") {
-		return element
+// getSelectedLibraryElements returns the path elements of all currently selected library items
+func (w *MainWindow) getSelectedLibraryElements() []LibraryPathElement {
+	var elements []LibraryPathElement
+
+	// The marshalled element is stored in the grid item's name
+	if w.LibraryGridScrolledWindow.GetVisible() {
+		for _, child := range w.LibraryFlowBox.GetSelectedChildren() {
+			name, err := child.GetName()
+			if errCheck(err, "getSelectedLibraryElements(): GetName() failed") {
+				continue
+			}
+			if element, err := UnmarshalLibPathElement(name); !errCheck(err, "Unmarshalling failed") {
+				elements = append(elements, element)
+			}
+		}
+		return elements
 	}
-	return nil
+
+	// List: the element is stored in the list store
+	w.LibraryTreeSelection.SelectedForEach(func(_ *gtk.TreeModel, _ *gtk.TreePath, iter *gtk.TreeIter) {
+		if element := w.libraryListElement(iter); element != nil {
+			elements = append(elements, element)
+		}
+	})
+	return elements
 }
 
 // getSelectedStreamIndex returns the index of the currently selected stream, or -1 if there's an error
@@ -1024,7 +1751,10 @@ func (w *MainWindow) getSelectedStreamIndex() int {
 	if row == nil {
 		return -1
 	}
-	return row.GetIndex()
+	if i := row.GetIndex(); i >= 0 && i < len(w.streamsRows) {
+		return w.streamsRows[i].index
+	}
+	return -1
 }
 
 // initLibraryWidgets initialises library widgets and actions
@@ -1039,9 +1769,32 @@ func (w *MainWindow) initLibraryWidgets() {
 	w.aLibraryDelete = w.addAction("library.delete", "", w.libraryDelete)
 	w.aLibraryAddToPlaylist = w.addAction("library.add-to-playlist", "", w.libraryAddToPlaylist)
 	w.addAction("library.search.toggle", "", w.onLibrarySearchToggle)
+	w.aLibraryGrid = w.addAction("library.grid.toggle", "", w.onLibraryGridToggle)
+	w.aLibrarySearchSave = w.addAction("library.search.save", "", w.librarySaveSearch)
+	w.aLibraryBack = w.addAction("library.back", "<Alt>Left", func() { w.libPath.Back() })
+	w.aLibraryForward = w.addAction("library.forward", "<Alt>Right", func() { w.libPath.Forward() })
+	w.addAction("library.bookmarks", "", w.libraryBookmarks)
 
 	// Create a library path instance
 	w.libPath = NewLibraryPath(w.onLibraryPathChanged)
+	w.libSelections = make(map[string]string)
+
+	// Load grid thumbnails as they're scrolled into view
+	w.LibraryGridScrolledWindow.GetVAdjustment().Connect("value-changed", w.libraryGridLoadVisible)
+	w.LibraryScrolledWindow.GetVAdjustment().Connect("value-changed", w.libraryCountLoadVisible)
+	w.LibraryScrolledWindow.GetVAdjustment().Connect("changed", w.libraryCountLoadVisible)
+
+	// Populate the index bar
+	for _, letter := range append([]string{libraryIndexOther}, strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ", "")...) {
+		letter := letter // Make an in-loop copy for the closure
+		btn := util.NewButton(letter, "", "", "", func() { w.libraryJumpToIndex(letter) })
+		btn.SetRelief(gtk.RELIEF_NONE)
+		btn.SetCanFocus(false)
+		w.LibraryIndexBox.PackStart(btn, true, true, 0)
+		w.libIndexButtons = append(w.libIndexButtons, btn)
+	}
+	w.LibraryIndexBox.ShowAll()
+	w.LibraryIndexBox.SetNoShowAll(true)
 
 	// Populate search attribute combo box
 	w.LibrarySearchAttrComboBox.Append(librarySearchAllAttrID, glib.Local("Everywhere"))
@@ -1066,6 +1819,11 @@ func (w *MainWindow) initPlayerWidgets() {
 	w.aPlayerRandom = w.addAction("player.toggle.random", "<Ctrl>U", w.playerToggleRandom)
 	w.aPlayerRepeat = w.addAction("player.toggle.repeat", "<Ctrl>R", w.playerToggleRepeat)
 	w.aPlayerConsume = w.addAction("player.toggle.consume", "<Ctrl>N", w.playerToggleConsume)
+	w.addAction("lyrics.toggle", "<Ctrl>L", w.lyricsToggle)
+
+	// Show the lyrics panel if it was open before
+	w.lyricsLine = -1
+	w.updateLyricsPanel()
 }
 
 // initQueueWidgets initialises queue widgets and actions
@@ -1105,7 +1863,29 @@ func (w *MainWindow) initStreamsWidgets() {
 	w.aStreamAdd = w.addAction("stream.add", "", w.onStreamAdd)
 	w.aStreamEdit = w.addAction("stream.edit", "", w.onStreamEdit)
 	w.aStreamDelete = w.addAction("stream.delete", "", w.onStreamDelete)
+	w.addAction("stream.import", "", w.onStreamImport)
+	w.aStreamExport = w.addAction("stream.export", "", w.onStreamExport)
+
+	// Accept playlist files dropped onto the Streams page
+	if target, err := gtk.TargetEntryNew("text/uri-list", gtk.TARGET_OTHER_APP, 0); !errCheck(err, "TargetEntryNew() failed") {
+		w.StreamsBox.DragDestSet(gtk.DEST_DEFAULT_ALL, []gtk.TargetEntry{*target}, gdk.ACTION_COPY)
+	}
 	w.aStreamPropsApply = w.addAction("stream.props.apply", "", w.onStreamPropsApply)
+
+	// Stream history actions
+	w.addAction("stream.history.toggle", "<Ctrl>H", w.streamHistoryToggle)
+	w.aStreamHistoryCopy = w.addAction("stream.history.copy", "", w.onStreamHistoryCopy)
+	w.aStreamHistorySearch = w.addAction("stream.history.search", "", w.onStreamHistorySearch)
+	w.aStreamHistoryExport = w.addAction("stream.history.export", "", w.onStreamHistoryExport)
+	w.aStreamHistoryClear = w.addAction("stream.history.clear", "", w.onStreamHistoryClear)
+
+	// Load the stream history. On failure, continue with whatever could be loaded
+	var err error
+	w.streamHistory, err = streamhistory.Load(
+		path.Join(glib.GetUserDataDir(), "ymuse", "stream-history.json"),
+		streamhistory.DefaultLimit)
+	errCheck(err, "Failed to load stream history")
+	w.updateStreamHistoryPanel()
 }
 
 // initWidgets initialises all widgets and actions
@@ -1119,18 +1899,35 @@ func (w *MainWindow) initWidgets() {
 	w.aMPDInfo = w.addAction("mpd.info", "<Ctrl><Shift>I", w.showMPDInfo)
 	w.addAction("prefs", "<Ctrl>comma", w.showPreferences)
 	w.aMPDOutputs = w.addAction("outputs", "<Ctrl>O", w.showOutputs)
+	w.addAction("mini-player", "<Ctrl>M", w.miniPlayerToggle)
 	w.addAction("about", "F1", w.showAbout)
 	w.addAction("shortcuts", "<Ctrl><Shift>question", w.showShortcuts)
-	w.addAction("quit", "<Ctrl>Q", w.AppWindow.Close)
+	w.addAction("quit", "<Ctrl>Q", w.quit)
 	w.addAction("page.queue", "<Ctrl>1", func() { w.MainStack.SetVisibleChild(w.QueueBox) })
 	w.addAction("page.library", "<Ctrl>2", func() { w.MainStack.SetVisibleChild(w.LibraryBox) })
 	w.addAction("page.streams", "<Ctrl>3", func() { w.MainStack.SetVisibleChild(w.StreamsBox) })
+	w.addAction("page.history", "<Ctrl>4", func() { w.MainStack.SetVisibleChild(w.HistoryBox) })
 
 	// Init other widgets and actions
 	w.initQueueWidgets()
 	w.initLibraryWidgets()
 	w.initStreamsWidgets()
+	w.initHistoryWidgets()
 	w.initPlayerWidgets()
+	w.initSleepWidgets()
+}
+
+// libraryAddSearchClause adds a new condition row to the advanced library search panel
+func (w *MainWindow) libraryAddSearchClause() {
+	row, err := newSearchClauseRow(w.updateLibrary, w.libraryRemoveSearchClause)
+	if errCheck(err, "newSearchClauseRow() failed") {
+		return
+	}
+	row.setFirst(len(w.libSearchClauses) == 0)
+	w.libSearchClauses = append(w.libSearchClauses, row)
+	w.LibraryAdvancedSearchClauseBox.PackStart(row.box, false, false, 0)
+	row.box.ShowAll()
+	row.grabFocus()
 }
 
 // libraryAddToPlaylist shows a popover menu that allows to add the selected library element to a playlist
@@ -1161,6 +1958,31 @@ func (w *MainWindow) libraryAddToPlaylist() {
 	w.LibraryAddToPlaylistPopoverMenu.Popup()
 }
 
+// libraryAlbums returns elements for the albums of the tracks matching the given filter, telling the albums apart
+// according to the given grouping rule, one of the config.AlbumGrouping* constants. Safe to call off the GUI thread
+func (w *MainWindow) libraryAlbums(filter []string, grouping string) ([]LibraryPathElement, error) {
+	// Let MPD list the albums grouped by the tags telling them apart
+	args := append(slices.Clip(filter), "group", albumTagAlbumArtist, "group", albumTagArtist)
+	switch grouping {
+	case config.AlbumGroupingDate:
+		args = append(args, "group", albumTagDate)
+	case config.AlbumGroupingMBID:
+		args = append(args, "group", albumTagMBID)
+	}
+	attrs, err := w.connector.ListGrouped(albumTagAlbum, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the list into albums
+	ids := AlbumIdentities(attrs, grouping)
+	elements := make([]LibraryPathElement, len(ids))
+	for i, id := range ids {
+		elements[i] = NewAlbumLibElementID(id)
+	}
+	return elements, nil
+}
+
 // libraryAppendPlaylist appends the provided URIs to a playlist with the given name
 func (w *MainWindow) libraryAppendPlaylist(name string, uris ...string) {
 	err := errors.New(glib.Local("Not connected to MPD"))
@@ -1179,7 +2001,15 @@ func (w *MainWindow) libraryAppendPlaylist(name string, uris ...string) {
 // libraryDelete allows to delete the selected library element
 func (w *MainWindow) libraryDelete() {
 	element := w.getSelectedLibraryElement()
-	if ph, ok := element.(PlaylistHolder); ok {
+	if eh, ok := element.(PlaylistEntryHolder); ok {
+		// Keep the selection on the entry following the removed one
+		w.libPathElementToSelect = w.libraryNextEntryToSelect()
+		var err error
+		w.connector.IfConnected(func(client *mpd.Client) {
+			err = client.PlaylistDelete(eh.EntryPlaylist(), eh.EntryPos())
+		})
+		w.errCheckDialog(err, glib.Local("Failed to delete track from the playlist"))
+	} else if ph, ok := element.(PlaylistHolder); ok {
 		if util.ConfirmDialog(w.AppWindow, glib.Local("Delete playlist"), fmt.Sprintf(glib.Local("Are you sure you want to delete playlist \"%s\"?"), ph.PlaylistName())) {
 			var err error
 			w.connector.IfConnected(func(client *mpd.Client) {
@@ -1188,6 +2018,29 @@ func (w *MainWindow) libraryDelete() {
 			// Check for error (outside IfConnected() because it would keep the client locked)
 			w.errCheckDialog(err, glib.Local("Failed to delete the playlist"))
 		}
+	} else if sf, ok := element.(*SmartFolderLibElement); ok {
+		if util.ConfirmDialog(w.AppWindow, glib.Local("Delete smart folder"), fmt.Sprintf(glib.Local("Are you sure you want to delete smart folder \"%s\"?"), sf.SmartFolderName())) {
+			folders := &config.GetConfig().SmartFolders
+			if idx := findSmartFolder(*folders, sf.SmartFolderName()); idx >= 0 {
+				*folders = append((*folders)[:idx], (*folders)[idx+1:]...)
+			}
+			w.updateLibrary()
+		}
+	}
+}
+
+// libraryJumpToIndex selects the first library list row whose label starts with the given index letter, or with a
+// non-letter in case of libraryIndexOther
+func (w *MainWindow) libraryJumpToIndex(letter string) {
+	if letter == libraryIndexOther {
+		letter = ""
+	}
+	for i, label := range w.libListLabels {
+		if util.IndexLetter(label) == letter {
+			w.librarySelectRow(i)
+			w.LibraryTreeView.GrabFocus()
+			return
+		}
 	}
 }
 
@@ -1201,6 +2054,68 @@ func (w *MainWindow) libraryLevelUp() {
 	}
 }
 
+// libraryListElement returns the path element stored in the given library list row, or nil if there's an error
+func (w *MainWindow) libraryListElement(iter *gtk.TreeIter) LibraryPathElement {
+	v, err := w.LibraryListStore.GetValue(iter, libraryColElement)
+	if errCheck(err, "libraryListElement(): LibraryListStore.GetValue() failed") {
+		return nil
+	}
+	name, err := v.GetString()
+	if errCheck(err, "libraryListElement(): GetString() failed") {
+		return nil
+	}
+	if element, err := UnmarshalLibPathElement(name); !errCheck(err, "Unmarshalling failed") {
+		return element
+	}
+	return nil
+}
+
+// libraryNextEntryToSelect returns the marshalled form the entry following the selected playlist entry will have once
+// the latter is removed, or of the preceding element if there's no following entry
+func (w *MainWindow) libraryNextEntryToSelect() string {
+	var iter *gtk.TreeIter
+	w.LibraryTreeSelection.SelectedForEach(func(_ *gtk.TreeModel, _ *gtk.TreePath, it *gtk.TreeIter) {
+		if iter == nil {
+			iter, _ = it.Copy()
+		}
+	})
+	if iter == nil {
+		return ""
+	}
+	next, err := iter.Copy()
+	if errCheck(err, "libraryNextEntryToSelect(): Copy() failed") {
+		return ""
+	}
+	if w.LibraryListStore.IterNext(next) {
+		if e, ok := w.libraryListElement(next).(*PlaylistEntryLibElement); ok {
+			e.pos--
+			return e.Marshal()
+		}
+	} else if prev, err := iter.Copy(); err == nil && w.LibraryListStore.IterPrevious(prev) {
+		if e := w.libraryListElement(prev); e != nil {
+			return e.Marshal()
+		}
+	}
+	return ""
+}
+
+// libraryRemoveSearchClause removes the given condition row from the advanced library search panel
+func (w *MainWindow) libraryRemoveSearchClause(row *searchClauseRow) {
+	for i, r := range w.libSearchClauses {
+		if r == row {
+			w.libSearchClauses = append(w.libSearchClauses[:i], w.libSearchClauses[i+1:]...)
+			break
+		}
+	}
+	row.box.Destroy()
+
+	// The first remaining row doesn't need a join
+	if len(w.libSearchClauses) > 0 {
+		w.libSearchClauses[0].setFirst(true)
+	}
+	w.updateLibrary()
+}
+
 // libraryRename allows to rename the selected library element
 func (w *MainWindow) libraryRename() {
 	element := w.getSelectedLibraryElement()
@@ -1213,7 +2128,206 @@ func (w *MainWindow) libraryRename() {
 			// Check for error (outside IfConnected() because it would keep the client locked)
 			w.errCheckDialog(err, glib.Local("Failed to rename the playlist"))
 		}
+	} else if sf, ok := element.(*SmartFolderLibElement); ok {
+		newName, ok := util.EditDialog(w.AppWindow, glib.Local("Rename smart folder"), sf.SmartFolderName(), glib.Local("Rename"))
+		if newName = strings.TrimSpace(newName); ok && newName != "" && newName != sf.SmartFolderName() {
+			folders := config.GetConfig().SmartFolders
+			if findSmartFolder(folders, newName) >= 0 {
+				util.ErrorDialog(w.AppWindow, fmt.Sprintf(glib.Local("Smart folder \"%s\" already exists"), newName))
+				return
+			}
+			if idx := findSmartFolder(folders, sf.SmartFolderName()); idx >= 0 {
+				folders[idx].Name = newName
+				w.libPathElementToSelect = newName
+			}
+			w.updateLibrary()
+		}
+	}
+}
+
+// libraryResolve converts the specified library elements into a list of items to queue. Unless expand is true, folders
+// are referred to by their URI and playlists by their name, otherwise both are expanded into individual tracks
+func (w *MainWindow) libraryResolve(elements []LibraryPathElement, expand bool) ([]libraryQueueItem, error) {
+	var items []libraryQueueItem
+	addTracks := func(attrs []mpd.Attrs) {
+		for _, uri := range util.MapAttrsToSlice(attrs, "file") {
+			items = append(items, libraryQueueItem{uri: uri})
+		}
+	}
+	for _, element := range elements {
+		// Skip anything that can't be played
+		if !element.IsPlayable() {
+			continue
+		}
+
+		var attrs []mpd.Attrs
+		err := errors.New(glib.Local("Not connected to MPD"))
+		switch e := element.(type) {
+		// URI-enabled element: a folder or a track
+		case URIHolder:
+			if !expand || !element.IsFolder() {
+				items = append(items, libraryQueueItem{uri: e.URI()})
+				continue
+			}
+			w.connector.IfConnected(func(client *mpd.Client) {
+				attrs, err = client.ListAllInfo(e.URI())
+			})
+
+		// Playlist-enabled element
+		case PlaylistHolder:
+			if !expand {
+				items = append(items, libraryQueueItem{playlist: e.PlaylistName()})
+				continue
+			}
+			w.connector.IfConnected(func(client *mpd.Client) {
+				attrs, err = client.PlaylistContents(e.PlaylistName())
+			})
+
+		// Filter-enabled element: run the search
+		case FilterHolder:
+			attrs, err = w.librarySearch(e.Filters())
+
+		default:
+			// Attribute-enabled path: extend the current path filter with the element
+			filter := w.libPath.AsFilter(element)
+			if len(filter) == 0 {
+				log.Errorf("Element %T cannot be queued", element)
+				continue
+			}
+			w.connector.IfConnected(func(client *mpd.Client) {
+				// For the lack of FindAdd() command in gompd, we need to query tracks first
+				attrs, err = client.Find(filter...)
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		addTracks(attrs)
 	}
+	return items, nil
+}
+
+// librarySaveSearch saves the current library search as a smart folder
+func (w *MainWindow) librarySaveSearch() {
+	filters := w.librarySearchQuery().Expressions()
+	if len(filters) == 0 {
+		return
+	}
+
+	// Ask for a name
+	name, ok := util.EditDialog(w.AppWindow, glib.Local("Save search"), "", glib.Local("Save"))
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return
+	}
+
+	// Replace an existing folder with the same name, after a confirmation
+	folders := &config.GetConfig().SmartFolders
+	if idx := findSmartFolder(*folders, name); idx >= 0 {
+		if !util.ConfirmDialog(w.AppWindow, glib.Local("Save search"), fmt.Sprintf(glib.Local("Smart folder \"%s\" already exists. Do you want to replace it?"), name)) {
+			return
+		}
+		(*folders)[idx].Filters = filters
+	} else {
+		*folders = append(*folders, config.SmartFolderSpec{Name: name, Filters: filters})
+	}
+}
+
+// librarySearch runs a search for every given filter expression, since MPD doesn't support OR in filters, and returns
+// the merged results
+func (w *MainWindow) librarySearch(filters []string) (attrs []mpd.Attrs, err error) {
+	w.connector.IfConnected(func(client *mpd.Client) {
+		seen := make(map[string]bool)
+		for _, expr := range filters {
+			var found []mpd.Attrs
+			if found, err = client.Search(expr); err != nil {
+				return
+			}
+			for _, a := range found {
+				if uri := a["file"]; !seen[uri] {
+					seen[uri] = true
+					attrs = append(attrs, a)
+				}
+			}
+		}
+	})
+	return
+}
+
+// librarySearchFor switches to the library and runs a simple search for the given text in all track attributes
+func (w *MainWindow) librarySearchFor(text string) {
+	w.MainStack.SetVisibleChild(w.LibraryBox)
+	w.LibrarySearchAdvancedButton.SetActive(false)
+	w.LibrarySearchAttrComboBox.SetActiveID(librarySearchAllAttrID)
+
+	// Activating the search mode clears the search entry, so the text goes in afterwards
+	w.LibrarySearchToolButton.SetActive(true)
+	w.LibrarySearchEntry.SetText(text)
+}
+
+// librarySearchQuery returns the library search query composed of either the advanced search conditions or the simple
+// search pattern, depending on the mode. Returns nil if library search mode is inactive or there's nothing to search for
+func (w *MainWindow) librarySearchQuery() FilterQuery {
+	if !w.LibrarySearchToolButton.GetActive() {
+		return nil
+	}
+
+	// Advanced search: collect complete conditions
+	var query FilterQuery
+	if w.LibrarySearchAdvancedButton.GetActive() {
+		for _, row := range w.libSearchClauses {
+			if c, ok := row.clause(); ok {
+				query = append(query, c)
+			}
+		}
+		return query
+	}
+
+	// Simple search: fetch the pattern and the selected attribute
+	if pattern := util.EntryText(&w.LibrarySearchEntry.Entry, ""); pattern != "" {
+		attrName := FilterAttrAny
+		if attr, ok := config.MpdTrackAttributes[util.AtoiDef(w.LibrarySearchAttrComboBox.GetActiveID(), -1)]; ok {
+			attrName = attr.AttrName
+		}
+		query = FilterQuery{{Attr: attrName, Op: FilterOpContains, Value: pattern}}
+	}
+	return query
+}
+
+// librarySelectAll selects all items in the library list or grid
+func (w *MainWindow) librarySelectAll() {
+	if w.LibraryGridScrolledWindow.GetVisible() {
+		w.LibraryFlowBox.SelectAll()
+	} else {
+		w.LibraryTreeSelection.SelectAll()
+	}
+}
+
+// librarySelectOtherPlaylist asks the user to pick a playlist other than the given one
+func (w *MainWindow) librarySelectOtherPlaylist(name, title, text, okButton string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	var others []string
+	for _, s := range w.connector.GetPlaylists() {
+		if s != name {
+			others = append(others, s)
+		}
+	}
+	if len(others) == 0 {
+		util.InfoDialog(w.AppWindow, glib.Local("There are no other playlists"))
+		return "", false
+	}
+	return util.SelectDialog(w.AppWindow, title, text, others, okButton)
+}
+
+// librarySelectRow selects the library list row with the given index and scrolls it into view
+func (w *MainWindow) librarySelectRow(index int) {
+	path, err := gtk.TreePathNewFromIndicesv([]int{index})
+	if errCheck(err, "librarySelectRow(): TreePathNewFromIndicesv() failed") {
+		return
+	}
+	w.LibraryTreeView.SetCursor(path, nil, false)
+	w.LibraryTreeView.ScrollToCell(path, nil, true, 0.5, 0)
 }
 
 // libraryShowAlbumFromQueue opens the currently selected queue album in the library
@@ -1221,9 +2335,8 @@ func (w *MainWindow) libraryShowAlbumFromQueue() {
 	if attrs, err := w.getQueueSelectedTrackAttrs(); !w.errCheckDialog(err, glib.Local("Failed to get album information")) {
 		// Update the current library path
 		w.libPath.SetElements([]LibraryPathElement{
-			NewArtistsLibElement(),
-			NewArtistLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrArtist].AttrName]),
-			NewAlbumLibElementVal(attrs[config.MpdTrackAttributes[config.MTAttrAlbum].AttrName]),
+			NewAlbumsLibElement(),
+			NewAlbumLibElementID(NewAlbumIdentity(attrs, config.GetConfig().AlbumGrouping)),
 		})
 
 		// Switch to the library tab
@@ -1261,31 +2374,66 @@ func (w *MainWindow) libraryShowGenreFromQueue() {
 
 // libraryUpdate updates or rescans the library
 func (w *MainWindow) libraryUpdate(rescan, selectedOnly bool) {
-	// Determine the update path
-	libPath := ""
+	// Determine the update paths
+	libPaths := []string{""}
 	if selectedOnly {
 		// We only support updating file-based items
-		uh, ok := w.getSelectedLibraryElement().(URIHolder)
-		if !ok {
+		libPaths = nil
+		for _, e := range w.getSelectedLibraryElements() {
+			if uh, ok := e.(URIHolder); ok {
+				libPaths = append(libPaths, uh.URI())
+			}
+		}
+		if len(libPaths) == 0 {
 			return
 		}
-		libPath = uh.URI()
 	}
 
 	// Run the update
 	var err error
 	w.connector.IfConnected(func(client *mpd.Client) {
+		// gompd's command list lacks rescan, so issue those one by one
 		if rescan {
-			_, err = client.Rescan(libPath)
-		} else {
-			_, err = client.Update(libPath)
+			for _, p := range libPaths {
+				if _, err = client.Rescan(p); err != nil {
+					return
+				}
+			}
+			return
 		}
+		commands := client.BeginCommandList()
+		for _, p := range libPaths {
+			commands.Update(p)
+		}
+		err = commands.End()
 	})
 
 	// Check for error
 	w.errCheckDialog(err, glib.Local("Failed to update the library"))
 }
 
+// playerAlbumArt returns the album art of the track with the given URI, scaled to the given size and falling back to
+// the logo for streams, or nil if there's none
+func (w *MainWindow) playerAlbumArt(uri string, size int) *gdk.Pixbuf {
+	if uri == "" {
+		return nil
+	}
+	if albumArt := w.connector.GetAlbumArt(uri, size); len(albumArt) > 0 {
+		// Make a pixbuf from the data bytes and rescale it
+		if px, err := util.NewPixbufScaled(albumArt, size); !errCheck(err, "NewPixbufScaled() failed") {
+			return px
+		}
+	}
+	if util.IsStreamURI(uri) {
+		for _, stream := range config.GetConfig().Streams {
+			if stream.URI == uri {
+				return w.streamLogo(stream.Logo, size)
+			}
+		}
+	}
+	return nil
+}
+
 // playerPrevious rewinds the player to the previous track
 func (w *MainWindow) playerPrevious() {
 	var err error
@@ -1297,33 +2445,25 @@ func (w *MainWindow) playerPrevious() {
 	w.errCheckDialog(err, glib.Local("Failed to skip to previous track"))
 }
 
-// playerStop stops the playback
+// playerStop stops the playback, fading the volume out first if configured
 func (w *MainWindow) playerStop() {
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		err = client.Stop()
-	})
-
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to stop playback"))
+	w.playerFadeOut((*mpd.Client).Stop, glib.Local("Failed to stop playback"))
 }
 
-// playerPlayPause pauses or resumes the playback
+// playerPlayPause pauses or resumes the playback, fading the volume out or in if configured
 func (w *MainWindow) playerPlayPause() {
-	var err error
-	w.connector.IfConnected(func(client *mpd.Client) {
-		switch w.connector.Status()["state"] {
-		case "pause":
-			err = client.Pause(false)
-		case "play":
-			err = client.Pause(true)
-		default:
-			err = client.Play(-1)
-		}
-	})
-
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to toggle playback"))
+	errMessage := glib.Local("Failed to toggle playback")
+	switch {
+	// Pausing or stopping is under way: fade back in instead
+	case w.fadingOut:
+		w.playerFadeIn(nil, errMessage)
+	case w.connector.Status()["state"] == "play":
+		w.playerFadeOut(func(client *mpd.Client) error { return client.Pause(true) }, errMessage)
+	case w.connector.Status()["state"] == "pause":
+		w.playerFadeIn(func(client *mpd.Client) error { return client.Pause(false) }, errMessage)
+	default:
+		w.playerFadeIn(func(client *mpd.Client) error { return client.Play(-1) }, errMessage)
+	}
 }
 
 // playerNext advances the player to the next track
@@ -1349,6 +2489,22 @@ func (w *MainWindow) playerSeekCurrent(dir int) {
 	w.errCheckDialog(err, glib.Local("Failed to seek in the current track"))
 }
 
+// playerPosition returns the current track's length and playback position in seconds, -1 for either if it's unknown
+func (w *MainWindow) playerPosition() (trackLen, trackPos float64) {
+	if connected, _ := w.connector.ConnectStatus(); !connected {
+		return -1, -1
+	}
+	status := w.connector.Status()
+	return util.ParseFloatDef(status["duration"], -1), util.ParseFloatDef(status["elapsed"], -1)
+}
+
+// playerSeekTo seeks to the given position in the current track, in seconds
+func (w *MainWindow) playerSeekTo(pos float64) {
+	w.connector.IfConnected(func(client *mpd.Client) {
+		errCheck(client.SeekCur(time.Duration(pos)*time.Second, false), "SeekCur() failed")
+	})
+}
+
 // playerToggleConsume toggles player's consume mode
 func (w *MainWindow) playerToggleConsume() {
 	// Ignore if the state of the button is being updated programmatically
@@ -1399,8 +2555,78 @@ func (w *MainWindow) playerToggleRepeat() {
 		}
 	})
 
-	// Check for error
-	w.errCheckDialog(err, glib.Local("Failed to toggle repeat/single mode"))
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to toggle repeat/single mode"))
+}
+
+// populateLibraryList fills the library list with the given elements, showing at most maxItems of them (unless it's
+// negative). Returns the number of added rows, whether the list was limited, and whether the operation succeeded
+func (w *MainWindow) populateLibraryList(elements []LibraryPathElement, root bool, maxItems int) (countItems int, limited, ok bool) {
+	// Detach the model from the tree view to speed up the population
+	w.LibraryTreeView.SetModel(nil)
+	defer w.LibraryTreeView.SetModel(w.LibraryListStore)
+
+	// Root elements are bold
+	weight := fontWeightNormal
+	if root {
+		weight = fontWeightBold
+	}
+
+	rowToSelect := -1
+	rowIndices := []int{
+		libraryColIcon, libraryColLabel, libraryColDetails, libraryColFontWeight, libraryColElement,
+		libraryColAppendIcon, libraryColReplaceIcon,
+	}
+	for _, element := range elements {
+		label := element.Label()
+
+		// Add details [track length], if any
+		details := ""
+		if dh, ok := element.(DetailsHolder); ok {
+			details = dh.Details()
+		}
+
+		// For non-root elements, add replace/append "buttons" if needed
+		appendIcon, replaceIcon := "", ""
+		if !root && element.IsPlayable() {
+			appendIcon, replaceIcon = "ymuse-add-symbolic", "ymuse-replace-queue-symbolic"
+		}
+
+		// Add a new row
+		rowValues := []interface{}{
+			element.Icon(), label, details, weight, MarshalLibPathElement(element), appendIcon, replaceIcon,
+		}
+		if errCheck(w.LibraryListStore.InsertWithValues(nil, -1, rowIndices, rowValues), "LibraryListStore.InsertWithValues() failed") {
+			return
+		}
+		w.libListLabels = append(w.libListLabels, label)
+
+		// If no specific row to select, pick the first one. Otherwise, check for a matching marshalled form
+		if rowToSelect < 0 && (w.libPathElementToSelect == "" || w.libPathElementToSelect == element.Marshal()) {
+			rowToSelect = countItems
+		}
+		countItems++
+
+		if maxItems >= 0 && countItems >= maxItems {
+			limited = true
+			break
+		}
+	}
+
+	// Show the index bar for long lists only
+	w.updateLibraryIndex(!root && countItems >= libraryIndexMinItems)
+
+	// Select the required row and scroll to it (later)
+	if rowToSelect >= 0 {
+		glib.IdleAdd(func() { w.librarySelectRow(rowToSelect) })
+	}
+	return countItems, limited, true
+}
+
+// quit closes the window and quits the application, even if there's a tray icon
+func (w *MainWindow) quit() {
+	w.quitting = true
+	w.AppWindow.Close()
 }
 
 // queueClear empties MPD's play queue
@@ -1490,53 +2716,25 @@ func (w *MainWindow) queueFilter() {
 	w.QueueFilterLabel.SetText(fmt.Sprintf(glib.Local("%d track(s) displayed"), count))
 }
 
-// queueLibraryElement adds or replaces the content of the queue with the specified library path element
-func (w *MainWindow) queueLibraryElement(replace triBool, element LibraryPathElement) {
-	// Element must be playable
-	if !element.IsPlayable() {
+// queueLibraryElements adds or replaces the content of the queue with the specified library path elements, using a
+// single command list
+func (w *MainWindow) queueLibraryElements(replace triBool, elements ...LibraryPathElement) {
+	// Resolve the elements into URIs and playlists
+	items, err := w.libraryResolve(elements, false)
+	if w.errCheckDialog(err, glib.Local("Failed to add item to the queue")) || len(items) == 0 {
 		return
 	}
 
-	// If it's a URI-enabled element
-	if uh, ok := element.(URIHolder); ok {
-		w.queueURIs(replace, uh.URI())
-		return
-	}
-
-	// Playlist-enabled element
-	if ph, ok := element.(PlaylistHolder); ok {
-		w.queuePlaylist(replace, ph.PlaylistName())
-		return
-	}
-
-	// Attribute-enabled path: extend the current path filter with the element
-	if filter := w.libPath.AsFilter(element); len(filter) > 0 {
-		var attrs []mpd.Attrs
-		var err error
-		w.connector.IfConnected(func(client *mpd.Client) {
-			// For the lack of FindAdd() command in gompd, we need to query tracks first
-			attrs, err = client.Find(filter...)
-		})
-
-		// Check for error
-		if w.errCheckDialog(err, glib.Local("Failed to add item to the queue")) {
-			return
+	// The default mode for playlists only applies if there's nothing else to queue
+	replaced := replace == tbTrue
+	if replace == tbNone {
+		cfg := config.GetConfig()
+		replaced = cfg.TrackDefaultReplace
+		if !slices.ContainsFunc(items, func(item libraryQueueItem) bool { return item.playlist == "" }) {
+			replaced = cfg.PlaylistDefaultReplace
 		}
-
-		// Convert attrs to list of URIs and queue them
-		w.queueURIs(replace, util.MapAttrsToSlice(attrs, "file")...)
-		return
 	}
 
-	// Oops
-	log.Errorf("Element %T cannot be queued", element)
-}
-
-// queuePlaylist adds or replaces the content of the queue with the specified playlist
-func (w *MainWindow) queuePlaylist(replace triBool, uri string) {
-	log.Debugf("queuePlaylist(%v, %v)", replace, uri)
-	var err error
-	replaced := replace == tbTrue || replace == tbNone && config.GetConfig().PlaylistDefaultReplace
 	w.connector.IfConnected(func(client *mpd.Client) {
 		commands := client.BeginCommandList()
 
@@ -1545,16 +2743,21 @@ func (w *MainWindow) queuePlaylist(replace triBool, uri string) {
 			commands.Clear()
 		}
 
-		// Add the content of the playlist
-		// NB: extract only playlist name from the URI for now
-		commands.PlaylistLoad(strings.TrimSuffix(path.Base(uri), ".m3u"), -1, -1)
+		// Add the URIs and the content of the playlists
+		for _, item := range items {
+			if item.playlist != "" {
+				commands.PlaylistLoad(item.playlist, -1, -1)
+			} else {
+				commands.Add(item.uri)
+			}
+		}
 
 		// Run the commands
 		err = commands.End()
 	})
 
 	// Check for error
-	if w.errCheckDialog(err, glib.Local("Failed to add playlist to the queue")) {
+	if w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue")) {
 		return
 	}
 
@@ -1564,6 +2767,37 @@ func (w *MainWindow) queuePlaylist(replace triBool, uri string) {
 	}
 }
 
+// queuePlayNext inserts the tracks of the selected library elements right after the currently playing track, or
+// appends them to the queue if there's nothing playing
+func (w *MainWindow) queuePlayNext() {
+	// Resolve the elements into individual tracks
+	items, err := w.libraryResolve(w.getSelectedLibraryElements(), true)
+	if w.errCheckDialog(err, glib.Local("Failed to add item to the queue")) || len(items) == 0 {
+		return
+	}
+
+	// Position the tracks after the current one, if any
+	pos := -1
+	if song, ok := w.connector.Status()["song"]; ok {
+		pos = util.AtoiDef(song, -2) + 1
+	}
+
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		for i, item := range items {
+			if pos < 0 {
+				commands.Add(item.uri)
+			} else {
+				commands.AddID(item.uri, pos+i)
+			}
+		}
+		err = commands.End()
+	})
+
+	// Check for error
+	w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue"))
+}
+
 // queueReplaced runs necessary post-queue-replace actions
 func (w *MainWindow) queueReplaced() {
 	// Switch to the queue tab
@@ -1751,38 +2985,6 @@ func (w *MainWindow) queueStream(replace triBool, uri string) {
 	}
 }
 
-// queueURIs adds or replaces the content of the queue with the specified URIs
-func (w *MainWindow) queueURIs(replace triBool, uris ...string) {
-	var err error
-	replaced := replace == tbTrue || replace == tbNone && config.GetConfig().TrackDefaultReplace
-	w.connector.IfConnected(func(client *mpd.Client) {
-		commands := client.BeginCommandList()
-
-		// Clear the queue, if needed
-		if replaced {
-			commands.Clear()
-		}
-
-		// Add the URIs
-		for _, uri := range uris {
-			commands.Add(uri)
-		}
-
-		// Run the commands
-		err = commands.End()
-	})
-
-	// Check for error
-	if w.errCheckDialog(err, glib.Local("Failed to add track(s) to the queue")) {
-		return
-	}
-
-	// Initiate post-replace actions, if necessary
-	if replaced {
-		w.queueReplaced()
-	}
-}
-
 // Show displays the window and all its child widgets
 func (w *MainWindow) Show() {
 	w.AppWindow.Show()
@@ -1896,7 +3098,7 @@ func (w *MainWindow) showOutputs() {
 
 // showPreferences shows the Preferences dialog
 func (w *MainWindow) showPreferences() {
-	ShowPreferencesDialog(w.AppWindow, w.connect, w.updateQueueColumns, w.applyPlayerSettings)
+	ShowPreferencesDialog(w.AppWindow, w.connect, w.updateQueueColumns, w.updateLibrary, w.applyPlayerSettings, w.applyScrobblerSettings)
 }
 
 // showShortcuts displays a shortcut info window
@@ -1949,6 +3151,110 @@ func (w *MainWindow) setQueueHighlight(index int, selected bool) {
 	}
 }
 
+// setVolume sets MPD's volume to the given value, 0 to 100
+func (w *MainWindow) setVolume(vol int) error {
+	err := errors.New(glib.Local("Not connected to MPD"))
+	w.connector.IfConnected(func(client *mpd.Client) {
+		err = client.SetVolume(vol)
+	})
+	return err
+}
+
+// streamLogo returns the logo image in the given file scaled to the given size, or nil if there's no file or it can't
+// be loaded
+func (w *MainWindow) streamLogo(file string, size int) *gdk.Pixbuf {
+	if file == "" {
+		return nil
+	}
+
+	// Check the cache first, which also remembers failures so that they aren't retried on every list update
+	key := fmt.Sprintf("%d:%s", size, file)
+	if px, ok := w.streamLogos[key]; ok {
+		return px
+	}
+	if w.streamLogos == nil {
+		w.streamLogos = make(map[string]*gdk.Pixbuf)
+	}
+	var px *gdk.Pixbuf
+	if data, err := os.ReadFile(file); !errCheck(err, "streamLogo(): ReadFile() failed") {
+		if p, err := util.NewPixbufScaled(data, size); !errCheck(err, "streamLogo(): NewPixbufScaled() failed") {
+			px = p
+		}
+	}
+	w.streamLogos[key] = px
+	return px
+}
+
+// streamPropsFill populates the stream properties popover with the properties of the given stream
+func (w *MainWindow) streamPropsFill(stream config.StreamSpec) {
+	w.StreamPropsNameEntry.SetText(stream.Name)
+	w.StreamPropsUriEntry.SetText(stream.URI)
+
+	// Offer the existing groups for selection
+	groups := make(map[string]bool)
+	for _, s := range config.GetConfig().Streams {
+		if s.Group != "" {
+			groups[s.Group] = true
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	w.StreamPropsGroupComboBox.RemoveAll()
+	for _, g := range names {
+		w.StreamPropsGroupComboBox.AppendText(g)
+	}
+	w.StreamPropsGroupEntry.SetText(stream.Group)
+
+	w.StreamPropsTagsEntry.SetText(strings.Join(stream.Tags, ", "))
+	w.StreamPropsLogoEntry.SetText(stream.Logo)
+	w.StreamPropsFavouriteCheckButton.SetActive(stream.Favourite)
+}
+
+// streamsImport adds the streams listed in the given playlist files, skipping local files and URIs that are already
+// registered
+func (w *MainWindow) streamsImport(fileNames []string) {
+	cfg := config.GetConfig()
+	known := make(map[string]bool, len(cfg.Streams))
+	for _, stream := range cfg.Streams {
+		known[stream.URI] = true
+	}
+
+	// Read the files one by one
+	imported, skipped, read := 0, 0, 0
+	for _, fileName := range fileNames {
+		entries, err := playlistfile.ReadFile(fileName)
+		if w.errCheckDialog(err, fmt.Sprintf(glib.Local("Failed to import %s"), filepath.Base(fileName))) {
+			continue
+		}
+		read++
+		for _, e := range entries {
+			if known[e.URI] || !strings.Contains(e.URI, "://") {
+				skipped++
+				continue
+			}
+			known[e.URI] = true
+			name := e.Name
+			if name == "" {
+				name = e.URI
+			}
+			cfg.Streams = append(cfg.Streams, config.StreamSpec{Name: name, URI: e.URI})
+			imported++
+		}
+	}
+	if read == 0 {
+		return
+	}
+
+	// Update stream list and report the outcome
+	w.updateStreams()
+	util.InfoDialog(
+		w.AppWindow,
+		fmt.Sprintf(glib.Local("Streams imported: %d. Skipped as duplicates or local files: %d."), imported, skipped))
+}
+
 // updateAll updates all window's widgets and lists
 func (w *MainWindow) updateAll() {
 	// Update global actions
@@ -1972,35 +3278,28 @@ func (w *MainWindow) updateAll() {
 
 // updateLibrary updates the current library list contents
 func (w *MainWindow) updateLibrary() {
-	// Clear the library list
-	util.ClearChildren(w.LibraryListBox.Container)
+	// Clear the library list and grid
+	w.LibraryListStore.Clear()
+	w.libListLabels = nil
+	util.ClearChildren(w.LibraryFlowBox.Container)
+	w.libGridItems = nil
+	w.libCountsRequested = make(map[int]bool)
+	w.libGeneration.Add(1)
 
 	var (
 		elements []LibraryPathElement
 		err      error
-		pattern  string
 	)
 	maxResultRows := -1
 	lastElement := w.libPath.Last()
+	query := w.librarySearchQuery()
+	searching := len(query) > 0
 
-	// If search mode activated
-	if w.LibrarySearchToolButton.GetActive() {
-		pattern = util.EntryText(&w.LibrarySearchEntry.Entry, "")
-	}
-
-	// Search mode: fetch selected attribute
-	if pattern != "" {
-		attrName := "any"
-		if attr, ok := config.MpdTrackAttributes[util.AtoiDef(w.LibrarySearchAttrComboBox.GetActiveID(), -1)]; ok {
-			attrName = attr.AttrName
-		}
-
+	// Search mode
+	if searching {
 		// Run search
-		var attrs []mpd.Attrs
-		w.connector.IfConnected(func(client *mpd.Client) {
-			attrs, err = client.Search(fmt.Sprintf("(%s contains \"%s\")", attrName, pattern))
-		})
-		if errCheck(err, "updateLibrary(): Search() failed") {
+		attrs, err := w.librarySearch(query.Expressions())
+		if errCheck(err, "updateLibrary(): librarySearch() failed") {
 			return
 		}
 		maxResultRows = config.GetConfig().MaxSearchResults
@@ -2017,6 +3316,49 @@ func (w *MainWindow) updateLibrary() {
 			NewAlbumsLibElement(),
 			NewPlaylistsLibElement(),
 		}
+		// Add recent views; the database addition time is only available since MPD 0.24
+		elements = append(elements, NewRecentLibElementKind(false))
+		w.connector.IfConnected(func(client *mpd.Client) {
+			if util.VersionAtLeast(client.Version(), 0, 24) {
+				elements = append(elements, NewRecentLibElementKind(true))
+			}
+		})
+		// Add smart folders
+		for i := range config.GetConfig().SmartFolders {
+			elements = append(elements, NewSmartFolderLibElementSpec(&config.GetConfig().SmartFolders[i]))
+		}
+
+	} else if re, ok := lastElement.(*RecentLibElement); ok {
+		// Recent view: list the available time windows
+		for _, window := range RecentWindows {
+			elements = append(elements, re.NewChild(window))
+		}
+
+	} else if rw, ok := lastElement.(*RecentWindowLibElement); ok {
+		// Recent view time window: run the search and list the changed tracks grouped by album, newest first
+		attrs, err := w.librarySearch(rw.Filters())
+		if errCheck(err, "updateLibrary(): librarySearch() failed") {
+			return
+		}
+		maxResultRows = config.GetConfig().MaxSearchResults
+
+		// Convert the list into elements, each album followed by its tracks. Albums only cover the tracks within the
+		// window as the window element's filter applies to them, too
+		for _, album := range GroupRecentTracks(attrs, config.GetConfig().AlbumGrouping, rw.TimeAttr()) {
+			elements = append(elements, NewAlbumLibElementID(album.ID))
+			elements = append(elements, AttrsToElements(album.Tracks, "")...)
+		}
+
+	} else if fh, ok := lastElement.(FilterHolder); ok {
+		// Filter-enabled element: run the search
+		attrs, err := w.librarySearch(fh.Filters())
+		if errCheck(err, "updateLibrary(): librarySearch() failed") {
+			return
+		}
+		maxResultRows = config.GetConfig().MaxSearchResults
+
+		// Convert the list into elements
+		elements = AttrsToElements(attrs, "")
 
 	} else if uh, ok := lastElement.(URIHolder); ok {
 		// URI-enabled element: load list of directories/files at the current path
@@ -2031,6 +3373,22 @@ func (w *MainWindow) updateLibrary() {
 		// Convert the list into elements
 		elements = AttrsToElements(attrs, uh.URI()+"/")
 
+	} else if browseBy, ok := lastElement.(AttributeHolderParent); ok && browseBy.ChildAttributeID() == config.MTAttrAlbum {
+		// Album-listing path: albums are told apart by more than just the title, so list them along with the other tags.
+		// This may take a while on a large library, so list them in the background
+		gen, filter, grouping := w.libGeneration.Load(), w.libPath.AsFilter(), config.GetConfig().AlbumGrouping
+		go func() {
+			elements, err := w.libraryAlbums(filter, grouping)
+			glib.IdleAdd(func() {
+				// Skip if the library has been repopulated meanwhile
+				if w.libGeneration.Load() != gen || errCheck(err, "updateLibrary(): libraryAlbums() failed") {
+					return
+				}
+				w.showLibraryElements(elements, lastElement, false, maxResultRows)
+			})
+		}()
+		return
+
 	} else if browseBy, ok := lastElement.(AttributeHolderParent); ok {
 		// Attribute-enabled path: determine the attribute we're browsing by
 		args := append(
@@ -2056,6 +3414,22 @@ func (w *MainWindow) updateLibrary() {
 			}
 		}
 
+	} else if ph, ok := lastElement.(PlaylistHolder); ok {
+		// Playlist element: load the playlist's tracks
+		var attrs []mpd.Attrs
+		w.connector.IfConnected(func(client *mpd.Client) {
+			attrs, err = client.PlaylistContents(ph.PlaylistName())
+		})
+		if errCheck(err, "updateLibrary(): PlaylistContents() failed") {
+			return
+		}
+
+		// Convert the list into elements
+		elements = make([]LibraryPathElement, 0, len(attrs))
+		for i, a := range attrs {
+			elements = append(elements, NewPlaylistEntryLibElementAttrs(ph.PlaylistName(), i, a))
+		}
+
 	} else if pl, ok := lastElement.(*PlaylistsLibElement); ok {
 		// Playlists list element: load list of playlists
 		for _, name := range w.connector.GetPlaylists() {
@@ -2067,69 +3441,38 @@ func (w *MainWindow) updateLibrary() {
 		return
 	}
 
+	w.showLibraryElements(elements, lastElement, searching, maxResultRows)
+}
+
+// showLibraryElements displays the given elements listed for the library path ending with lastElement
+func (w *MainWindow) showLibraryElements(elements []LibraryPathElement, lastElement LibraryPathElement, searching bool, maxResultRows int) {
 	// If no search mode and not root, insert a "level up" element
-	if pattern == "" && lastElement != nil {
+	if !searching && lastElement != nil {
 		elements = append([]LibraryPathElement{NewLevelUpLibElement()}, elements...)
 	}
 
-	// Repopulate the library list
-	var rowToSelect *gtk.ListBoxRow
-	countItems, limited := 0, false
-	for _, element := range elements {
-		element := element // Make an in-loop copy for closures
-		label := element.Label()
-		markup := false
-		var buttons []gtk.IWidget
-
-		// Make root elements bold
-		if lastElement == nil {
-			label = "<b>" + label + "</b>"
-			markup = true
-
-			// For non-root elements, add replace/append buttons if needed
-		} else if element.IsPlayable() {
-			buttons = []gtk.IWidget{
-				util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueLibraryElement(tbFalse, element) }),
-				util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueLibraryElement(tbTrue, element) }),
-			}
-		}
-
-		// Add a new list box row
-		row, hbx, err := util.NewListBoxRow(w.LibraryListBox, markup, label, MarshalLibPathElement(element), element.Icon(), buttons...)
-		if errCheck(err, "NewListBoxRow() failed") {
-			return
-		}
-
-		// If no specific row to select, pick the first one. Otherwise, check for a matching marshalled form
-		if rowToSelect == nil && (w.libPathElementToSelect == "" || w.libPathElementToSelect == element.Marshal()) {
-			rowToSelect = row
-		}
-
-		// Add a label with details [track length], if any
-		if dh, ok := element.(DetailsHolder); ok {
-			if details := dh.Details(); details != "" {
-				lbl, err := gtk.LabelNew(details)
-				// Just ignore the error and proceed
-				if !errCheck(err, "LabelNew() failed") {
-					hbx.PackEnd(lbl, false, false, 0)
-				}
-			}
-		}
-		countItems++
-
-		if maxResultRows >= 0 && countItems >= maxResultRows {
-			limited = true
-			break
-		}
+	// Switch between the list and the grid view
+	gridAvailable := !searching && w.libraryGridAvailable(lastElement)
+	grid := gridAvailable && config.GetConfig().LibraryGridLevels[lastElement.Prefix()]
+	w.aLibraryGrid.SetEnabled(gridAvailable)
+	w.libGridUpdating = true
+	w.LibraryGridToolButton.SetActive(grid)
+	w.libGridUpdating = false
+	w.LibraryListHBox.SetVisible(!grid)
+	w.LibraryGridScrolledWindow.SetVisible(grid)
+
+	// Repopulate the library list or grid
+	var countItems int
+	var limited, ok bool
+	if grid {
+		countItems, limited, ok = w.populateLibraryGrid(elements, maxResultRows)
+	} else {
+		countItems, limited, ok = w.populateLibraryList(elements, lastElement == nil, maxResultRows)
 	}
-
-	// Show all rows
-	w.LibraryListBox.ShowAll()
-
-	// Select the required row and scroll to it (later)
-	w.LibraryListBox.SelectRow(rowToSelect)
-	glib.IdleAdd(func() { util.ListBoxScrollToSelected(w.LibraryListBox) })
 	w.libPathElementToSelect = ""
+	if !ok {
+		return
+	}
 
 	// Compose info
 	info := ""
@@ -2151,38 +3494,103 @@ func (w *MainWindow) updateLibrary() {
 
 	// Update info
 	w.LibraryInfoLabel.SetText(info)
+
+	// Add the track count of the current level to the info, except for the root and search results
+	if lastElement != nil && !searching {
+		if job := w.newLibraryCountJob(-1, nil); job != nil {
+			job.prefix = info
+			go w.libraryCountLoad(w.libGeneration.Load(), []*libraryCountJob{job})
+		}
+	}
 }
 
 // updateLibraryActions updates the widgets for library list
 func (w *MainWindow) updateLibraryActions() {
+	// Bulk actions apply to any of the selected elements, the others only to a single one
+	elements := w.getSelectedLibraryElements()
 	element := w.getSelectedLibraryElement()
 	connected, _ := w.connector.ConnectStatus()
 	selected := element != nil
 	_, playlist := element.(PlaylistHolder)
-	_, filesystem := element.(URIHolder)
-	editable := playlist && connected && selected
-	updatable := connected && selected && filesystem
-	playable := connected && selected && element.IsPlayable()
+	_, smartFolder := element.(*SmartFolderLibElement)
+	entry, isEntry := element.(PlaylistEntryHolder)
+	_, playlistsLevel := w.libPath.Last().(*PlaylistsLibElement)
+	_, playlistOpen := w.libPath.Last().(PlaylistHolder)
+	// Playlist entries can be deleted, but not renamed
+	renamable := (playlist && connected || smartFolder) && selected
+	deletable := ((playlist || isEntry) && connected || smartFolder) && selected
+	updatable := connected && slices.ContainsFunc(elements, func(e LibraryPathElement) bool {
+		_, ok := e.(URIHolder)
+		return ok
+	})
+	playable := connected && slices.ContainsFunc(elements, LibraryPathElement.IsPlayable)
 	// Actions
 	w.aLibraryUpdate.SetEnabled(connected)
 	w.aLibraryUpdateAll.SetEnabled(connected)
 	w.aLibraryUpdateSel.SetEnabled(updatable)
 	w.aLibraryRescanAll.SetEnabled(connected)
 	w.aLibraryRescanSel.SetEnabled(updatable)
-	w.aLibraryRename.SetEnabled(editable)
-	w.aLibraryDelete.SetEnabled(editable)
+	w.aLibraryRename.SetEnabled(renamable)
+	w.aLibraryDelete.SetEnabled(deletable)
 	w.aLibraryAddToPlaylist.SetEnabled(playable)
+	w.aLibrarySearchSave.SetEnabled(len(w.librarySearchQuery()) > 0)
 	// Menu items
 	w.LibraryAppendMenuItem.SetSensitive(playable)
 	w.LibraryReplaceMenuItem.SetSensitive(playable)
-	w.LibraryRenameMenuItem.SetSensitive(editable)
-	w.LibraryDeleteMenuItem.SetSensitive(editable)
+	w.LibraryPlayNextMenuItem.SetSensitive(playable)
+	w.LibraryRenameMenuItem.SetSensitive(renamable)
+	w.LibraryDeleteMenuItem.SetSensitive(deletable)
 	w.LibraryUpdateSelMenuItem.SetSensitive(updatable)
 	w.LibraryAddToPlaylistMenuItem.SetSensitive(playable)
+	// Playlist editor menu items
+	w.LibraryPlaylistSeparatorMenuItem.SetVisible(playlistsLevel || playlistOpen)
+	w.LibraryPlaylistMoveUpMenuItem.SetVisible(playlistOpen)
+	w.LibraryPlaylistMoveUpMenuItem.SetSensitive(connected && isEntry && entry.EntryPos() > 0)
+	w.LibraryPlaylistMoveDownMenuItem.SetVisible(playlistOpen)
+	w.LibraryPlaylistMoveDownMenuItem.SetSensitive(connected && isEntry && entry.EntryPos() < w.libraryPlaylistLength()-1)
+	w.LibraryPlaylistInsertMenuItem.SetVisible(playlistOpen)
+	w.LibraryPlaylistInsertMenuItem.SetSensitive(connected && w.getQueueSelectedCount() > 0)
+	w.LibraryPlaylistDuplicateMenuItem.SetVisible(playlistsLevel)
+	w.LibraryPlaylistDuplicateMenuItem.SetSensitive(connected && playlist)
+	w.LibraryPlaylistMergeMenuItem.SetVisible(playlistsLevel)
+	w.LibraryPlaylistMergeMenuItem.SetSensitive(connected && playlist)
+	w.LibraryPlaylistCompareMenuItem.SetVisible(playlistsLevel)
+	w.LibraryPlaylistCompareMenuItem.SetSensitive(connected && playlist)
+	w.LibraryPlaylistDedupeMenuItem.SetVisible(playlistsLevel || playlistOpen)
+	w.LibraryPlaylistDedupeMenuItem.SetSensitive(connected && (playlist || playlistOpen))
+}
+
+// updateLibraryIndex shows or hides the library index bar, enabling only the letters present in the list
+func (w *MainWindow) updateLibraryIndex(show bool) {
+	w.LibraryIndexBox.SetVisible(show)
+	if !show {
+		return
+	}
+
+	// Collect the letters in use
+	letters := make(map[string]bool)
+	for _, label := range w.libListLabels {
+		if l := util.IndexLetter(label); l != "" {
+			letters[l] = true
+		} else {
+			letters[libraryIndexOther] = true
+		}
+	}
+
+	// Update the buttons
+	for _, btn := range w.libIndexButtons {
+		if l, err := btn.GetLabel(); !errCheck(err, "GetLabel() failed") {
+			btn.SetSensitive(letters[l])
+		}
+	}
 }
 
 // updateLibraryPath updates the current library path selector
 func (w *MainWindow) updateLibraryPath() {
+	// Update the history actions
+	w.aLibraryBack.SetEnabled(w.libPath.CanGoBack())
+	w.aLibraryForward.SetEnabled(w.libPath.CanGoForward())
+
 	// Remove all buttons from the box
 	util.ClearChildren(w.LibraryPathBox.Container)
 
@@ -2199,7 +3607,7 @@ func (w *MainWindow) updateLibraryPath() {
 	for i, element := range w.libPath.Elements() {
 		// Create a button. The last button must be depressed
 		i := i // Make an in-loop copy of i
-		util.NewBoxToggleButton(
+		btn := util.NewBoxToggleButton(
 			w.LibraryPathBox,
 			element.Label(),
 			"",
@@ -2214,6 +3622,7 @@ func (w *MainWindow) updateLibraryPath() {
 				// Move to the selected level
 				w.libPath.SetLength(i + 1)
 			})
+		btn.SetTooltipText(element.Label())
 	}
 
 	// Show all buttons
@@ -2244,6 +3653,7 @@ func (w *MainWindow) updatePlayer() {
 	stopped := false
 	var statusHTML string
 	var err error
+	var curSong mpd.Attrs
 	curURI := ""
 
 	switch {
@@ -2254,7 +3664,6 @@ func (w *MainWindow) updatePlayer() {
 	// Already connected
 	case connected:
 		// Fetch the current track
-		var curSong mpd.Attrs
 		w.connector.IfConnected(func(client *mpd.Client) {
 			curSong, err = client.CurrentSong()
 			errCheck(err, "CurrentSong() failed")
@@ -2278,6 +3687,14 @@ func (w *MainWindow) updatePlayer() {
 
 			// Get the current URI
 			curURI = curSong["file"]
+
+			// Announce the song if it's changed
+			w.updateNotification(status, curSong)
+
+			// Record the title of the stream being played
+			if curURI != "" && util.IsStreamURI(curURI) {
+				w.streamHistoryRecord(curURI, curSong["Title"])
+			}
 		}
 
 		// Update play/pause button's appearance
@@ -2302,8 +3719,9 @@ func (w *MainWindow) updatePlayer() {
 		statusHTML += fmt.Sprintf(" — <span foreground=\"red\">%s</span>", html.EscapeString(errMsg))
 	}
 
-	// Update the album art
+	// Update the album art and lyrics
 	w.updatePlayerAlbumArt(curURI)
+	w.updateLyrics(curURI)
 
 	// Update status text
 	w.StatusLabel.SetMarkup(statusHTML)
@@ -2322,6 +3740,10 @@ func (w *MainWindow) updatePlayer() {
 	w.aPlayerRepeat.SetEnabled(connected)
 	w.aPlayerConsume.SetEnabled(connected)
 
+	// Reflect the state in the mini player and the tray
+	w.updateMiniPlayer(connected, status, curSong)
+	w.updateTray(connected, status, curSong)
+
 	// Update the seek bar
 	w.updatePlayerSeekBar()
 }
@@ -2340,50 +3762,11 @@ func (w *MainWindow) updatePlayerAlbumArt(uri string) {
 				util.MaxInt(curPx.GetWidth(), curPx.GetHeight()) == size &&
 				w.playerCurrentAlbumArtUri == uri {
 				show = true
-			} else {
-				// Try to fetch the album art
-				var albumArt []byte
-				log.Debugf("Fetching album art for %s", uri)
-				w.connector.IfConnected(func(client *mpd.Client) {
-					var err error
-
-					// Try the embedded image first
-					if albumArt, err = client.ReadPicture(uri); err == nil && len(albumArt) > 0 {
-						log.Debugf("Fetched embedded album art: %d bytes", len(albumArt))
-						return
-					}
-					log.Debugf("Failed to obtain embedded album art: %v", err)
-
-					// Then image from a cover file
-					if albumArt, err = client.AlbumArt(uri); err == nil && len(albumArt) > 0 {
-						log.Debugf("Fetched album art from cover file: %d bytes", len(albumArt))
-						return
-					}
-					log.Debugf("Failed to obtain album art from cover.* file: %v", err)
-					albumArt = nil
-				})
-
-				// If succeeded
-				if len(albumArt) > 0 {
-					// Make a pixbuf from the data bytes
-					if px, err := gdk.PixbufNewFromBytesOnly(albumArt); !errCheck(err, "PixbufNewFromBytesOnly() failed") {
-						// Determine the required dimensions, keeping the aspect ratio
-						aspect, iw, ih := float64(px.GetWidth())/float64(px.GetHeight()), float64(size), float64(size)
-						if aspect > 1 {
-							ih /= aspect
-						} else {
-							iw *= aspect
-						}
-
-						// Rescale the image
-						if px, err = px.ScaleSimple(int(iw), int(ih), gdk.INTERP_BILINEAR); !errCheck(err, "ScaleSimple() failed") {
-							w.AlbumArtworkImage.SetFromPixbuf(px)
-							show = true
-							// Save the last used URI
-							w.playerCurrentAlbumArtUri = uri
-						}
-					}
-				}
+			} else if px := w.playerAlbumArt(uri, size); px != nil {
+				w.AlbumArtworkImage.SetFromPixbuf(px)
+				show = true
+				// Save the last used URI
+				w.playerCurrentAlbumArtUri = uri
 			}
 		}
 	}
@@ -2415,13 +3798,7 @@ func (w *MainWindow) updatePlayerSeekBar() {
 	} else {
 		// The update comes from MPD: adjust the seek bar position if there's a connection
 		trackStart := -1.0
-		trackLen, trackPos = -1.0, -1.0
-		if connected, _ := w.connector.ConnectStatus(); connected {
-			// Fetch current player position and track length
-			status := w.connector.Status()
-			trackLen = util.ParseFloatDef(status["duration"], -1)
-			trackPos = util.ParseFloatDef(status["elapsed"], -1)
-		}
+		trackLen, trackPos = w.playerPosition()
 
 		// If not seekable, remove the slider
 		if trackPos >= 0 && trackLen >= trackPos {
@@ -2433,6 +3810,9 @@ func (w *MainWindow) updatePlayerSeekBar() {
 		w.PlayPositionAdjustment.SetLower(trackStart)
 		w.PlayPositionAdjustment.SetUpper(trackLen)
 		w.PlayPositionAdjustment.SetValue(trackPos)
+
+		// Follow the position in the lyrics
+		w.updateLyricsLine(trackPos)
 	}
 
 	// Update position text
@@ -2443,6 +3823,9 @@ func (w *MainWindow) updatePlayerSeekBar() {
 		}
 	}
 	w.PositionLabel.SetMarkup(seekPos)
+
+	// Keep the mini player in sync
+	w.updateMiniPlayerSeekBar()
 }
 
 // updateQueue updates the current play queue contents
@@ -2698,35 +4081,101 @@ func (w *MainWindow) updateQueueTreeViewModel() {
 
 // updateStreams updates the current streams list contents
 func (w *MainWindow) updateStreams() {
+	// Remember the stream to select
+	cfg := config.GetConfig()
+	uriToSelect := w.streamsURIToSelect
+	w.streamsURIToSelect = ""
+	if idx := w.getSelectedStreamIndex(); uriToSelect == "" && idx >= 0 && idx < len(cfg.Streams) {
+		uriToSelect = cfg.Streams[idx].URI
+	}
+
 	// Clear the streams list
 	util.ClearChildren(w.StreamsListBox.Container)
+	w.streamsRows = nil
 
 	// Make sure the streams are sorted by name
-	cfg := config.GetConfig()
 	sort.Slice(cfg.Streams, func(i, j int) bool {
 		return strings.ToUpper(cfg.Streams[i].Name) < strings.ToUpper(cfg.Streams[j].Name)
 	})
 
+	// Group the streams matching the search query. Headers are only needed if there's more than just ungrouped streams
+	query := util.EntryText(&w.StreamsSearchEntry.Entry, "")
+	groups := GroupStreams(cfg.Streams, query)
+	headers := len(groups) > 1 || len(groups) == 1 && groups[0].ID != ""
+
 	// Repopulate the streams list
 	var rowToSelect *gtk.ListBoxRow
-	for _, stream := range config.GetConfig().Streams {
-		stream := stream // Make an in-loop copy of the var
-		row, _, err := util.NewListBoxRow(
-			w.StreamsListBox,
-			false,
-			stream.Name,
-			"",
-			"ymuse-stream",
-			// Add replace/append buttons
-			util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueStream(tbFalse, stream.URI) }),
-			util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueStream(tbTrue, stream.URI) }))
-		if errCheck(err, "NewListBoxRow() failed") {
-			return
+	count := 0
+	for _, group := range groups {
+		// All groups are expanded while searching
+		collapsed := query == "" && cfg.StreamsCollapsedGroups[group.ID]
+		if headers {
+			title := group.ID
+			switch group.ID {
+			case streamsFavouritesGroupID:
+				title = glib.Local("Favourites")
+			case "":
+				title = glib.Local("Other streams")
+			}
+			icon := "pan-down-symbolic"
+			if collapsed {
+				icon = "pan-end-symbolic"
+			}
+			row, _, err := util.NewListBoxRow(
+				w.StreamsListBox,
+				true,
+				fmt.Sprintf("<b>%s</b> <small>(%d)</small>", html.EscapeString(title), len(group.Indices)),
+				"",
+				icon)
+			if errCheck(err, "NewListBoxRow() failed") {
+				return
+			}
+			row.SetSelectable(false)
+			w.streamsRows = append(w.streamsRows, streamsListRow{index: -1, group: group.ID})
+		}
+		count += len(group.Indices)
+		if collapsed {
+			continue
 		}
 
-		// Select the first row in the list
-		if rowToSelect == nil {
-			rowToSelect = row
+		for _, idx := range group.Indices {
+			stream := cfg.Streams[idx]
+			label := html.EscapeString(stream.Name)
+			if len(stream.Tags) > 0 {
+				label += fmt.Sprintf("  <small>%s</small>", html.EscapeString(strings.Join(stream.Tags, ", ")))
+			}
+
+			// Add replace/append buttons, followed by the logo, if any
+			widgets := []gtk.IWidget{
+				util.NewButton("", glib.Local("Append to the queue"), "", "ymuse-add-symbolic", func() { w.queueStream(tbFalse, stream.URI) }),
+				util.NewButton("", glib.Local("Replace the queue"), "", "ymuse-replace-queue-symbolic", func() { w.queueStream(tbTrue, stream.URI) }),
+			}
+			icon := "ymuse-stream"
+			if px := w.streamLogo(stream.Logo, streamsLogoSize); px != nil {
+				if img, err := gtk.ImageNewFromPixbuf(px); !errCheck(err, "ImageNewFromPixbuf() failed") {
+					widgets = append(widgets, img)
+					icon = ""
+				}
+			}
+			row, hbx, err := util.NewListBoxRow(w.StreamsListBox, true, label, "", icon, widgets...)
+			if errCheck(err, "NewListBoxRow() failed") {
+				return
+			}
+			row.SetTooltipText(stream.URI)
+			w.streamsRows = append(w.streamsRows, streamsListRow{index: idx, group: group.ID})
+
+			// Indent grouped streams
+			if headers {
+				hbx.SetMarginStart(24)
+			}
+
+			// Select the remembered stream, or the first one in the list
+			if rowToSelect == nil || stream.URI == uriToSelect && uriToSelect != "" {
+				rowToSelect = row
+				if stream.URI == uriToSelect {
+					uriToSelect = ""
+				}
+			}
 		}
 	}
 
@@ -2738,10 +4187,13 @@ func (w *MainWindow) updateStreams() {
 
 	// Compose info
 	var info string
-	if cnt := len(config.GetConfig().Streams); cnt > 0 {
-		info = fmt.Sprintf(glib.Local("%d streams"), cnt)
-	} else {
+	switch total := len(cfg.Streams); {
+	case total == 0:
 		info = glib.Local("No streams")
+	case query != "":
+		info = fmt.Sprintf(glib.Local("%d of %d streams"), count, total)
+	default:
+		info = fmt.Sprintf(glib.Local("%d streams"), total)
 	}
 
 	// Update info
@@ -2756,6 +4208,7 @@ func (w *MainWindow) updateStreamsActions() {
 	w.aStreamAdd.SetEnabled(true) // Adding a stream is always possible
 	w.aStreamEdit.SetEnabled(selected)
 	w.aStreamDelete.SetEnabled(selected)
+	w.aStreamExport.SetEnabled(len(config.GetConfig().Streams) > 0)
 	// Menu items
 	w.StreamsAppendMenuItem.SetSensitive(connected && selected)
 	w.StreamsReplaceMenuItem.SetSensitive(connected && selected)
@@ -2826,3 +4279,34 @@ func (w *MainWindow) updateVolume() {
 		w.volumeUpdating = false
 	}
 }
+
+// windowHide hides the window in the tray
+func (w *MainWindow) windowHide() {
+	w.AppWindow.Hide()
+	w.updateTrayMenu()
+}
+
+// windowToggle hides the window in the tray if it's visible, and shows it otherwise
+func (w *MainWindow) windowToggle() {
+	if w.AppWindow.GetVisible() {
+		w.windowHide()
+	} else {
+		w.AppWindow.Present()
+	}
+}
+
+// streamsFileFilters returns file chooser filters for the playlist formats streams can be imported from or exported to
+func streamsFileFilters() []util.FileFilterSpec {
+	all := util.FileFilterSpec{Name: glib.Local("All playlists")}
+	var filters []util.FileFilterSpec
+	for _, format := range playlistfile.Formats {
+		// File chooser patterns are case-sensitive
+		var patterns []string
+		for _, ext := range format.Extensions() {
+			patterns = append(patterns, "*"+ext, "*"+strings.ToUpper(ext))
+		}
+		all.Patterns = append(all.Patterns, patterns...)
+		filters = append(filters, util.FileFilterSpec{Name: fmt.Sprintf(glib.Local("%s playlists"), format), Patterns: patterns})
+	}
+	return append([]util.FileFilterSpec{all}, filters...)
+}
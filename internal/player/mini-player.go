@@ -0,0 +1,164 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+)
+
+// Size of the album art image in the mini player, in pixels
+const miniPlayerAlbumArtSize = 64
+
+// onMiniPlayerDelete switches back to the main window instead of closing the mini player
+func (w *MainWindow) onMiniPlayerDelete() bool {
+	w.miniPlayerHide()
+	return true
+}
+
+func (w *MainWindow) onMiniPlayerPositionButtonEvent(_ interface{}, event *gdk.Event) {
+	switch gdk.EventButtonNewFromEvent(event).Type() {
+	case gdk.EVENT_BUTTON_PRESS:
+		w.miniPlayerPosUpdating = true
+
+	case gdk.EVENT_BUTTON_RELEASE:
+		w.miniPlayerPosUpdating = false
+		w.playerSeekTo(w.MiniPlayerPositionAdjustment.GetValue())
+	}
+}
+
+// miniPlayerHide hides the mini player, remembering its dimensions, and shows the main window again
+func (w *MainWindow) miniPlayerHide() {
+	w.miniPlayerStoreDimensions()
+	w.MiniPlayerWindow.Hide()
+	w.AppWindow.Present()
+}
+
+// miniPlayerShow replaces the main window with the mini player
+func (w *MainWindow) miniPlayerShow() {
+	// Restore the mini player's dimensions
+	dim := config.GetConfig().MiniPlayerDimensions
+	if dim.Width > 0 && dim.Height > 0 {
+		w.MiniPlayerWindow.Resize(dim.Width, dim.Height)
+	}
+	if dim.X >= 0 && dim.Y >= 0 {
+		w.MiniPlayerWindow.Move(dim.X, dim.Y)
+	}
+	w.MiniPlayerWindow.Present()
+	w.AppWindow.Hide()
+
+	// The mini player isn't updated while hidden
+	w.updatePlayer()
+}
+
+// miniPlayerStoreDimensions saves the mini player's current dimensions in the config
+func (w *MainWindow) miniPlayerStoreDimensions() {
+	x, y := w.MiniPlayerWindow.GetPosition()
+	width, height := w.MiniPlayerWindow.GetSize()
+	config.GetConfig().MiniPlayerDimensions = config.Dimensions{X: x, Y: y, Width: width, Height: height}
+}
+
+// miniPlayerToggle switches between the main window and the mini player
+func (w *MainWindow) miniPlayerToggle() {
+	if w.MiniPlayerWindow.GetVisible() {
+		w.miniPlayerHide()
+	} else {
+		w.miniPlayerShow()
+	}
+}
+
+// updateMiniPlayer updates the mini player's title, album art and play/pause button to reflect the given current song,
+// provided the mini player is shown
+func (w *MainWindow) updateMiniPlayer(connected bool, status, song mpd.Attrs) {
+	if !w.MiniPlayerWindow.GetVisible() {
+		return
+	}
+
+	// Apply the title template
+	var title, uri string
+	switch {
+	case !connected:
+		title = fmt.Sprintf("<i>%s</i>", html.EscapeString(glib.Local("Not connected to MPD")))
+	case song != nil:
+		var buffer bytes.Buffer
+		if err := w.miniPlayerTemplate.Execute(&buffer, song); err != nil {
+			title = html.EscapeString(fmt.Sprintf("%s: %v", glib.Local("Template error"), err))
+		} else {
+			title = buffer.String()
+		}
+		uri = song["file"]
+	}
+	w.MiniPlayerTitleLabel.SetMarkup(title)
+
+	// Update the play/pause button's appearance
+	icon := "ymuse-play-symbolic"
+	if status["state"] == "play" {
+		icon = "ymuse-pause-symbolic"
+	}
+	w.MiniPlayerPlayPauseImage.SetFromIconName(icon, gtk.ICON_SIZE_BUTTON)
+
+	// Update the album art once the track changes, falling back to the application icon
+	if uri != w.miniPlayerAlbumArtURI {
+		w.miniPlayerAlbumArtURI = uri
+		if px := w.playerAlbumArt(uri, miniPlayerAlbumArtSize); px != nil {
+			w.MiniPlayerAlbumArtImage.SetFromPixbuf(px)
+		} else {
+			w.MiniPlayerAlbumArtImage.SetFromIconName(config.AppMetadata.Icon, gtk.ICON_SIZE_DIALOG)
+		}
+	}
+}
+
+// updateMiniPlayerSeekBar updates the mini player's seek bar position and status
+func (w *MainWindow) updateMiniPlayerSeekBar() {
+	if !w.MiniPlayerWindow.GetVisible() {
+		return
+	}
+	var trackLen, trackPos float64
+
+	// If the user is dragging the slider manually
+	if w.miniPlayerPosUpdating {
+		trackLen, trackPos = w.MiniPlayerPositionAdjustment.GetUpper(), w.MiniPlayerPositionAdjustment.GetValue()
+
+	} else {
+		// If not seekable, remove the slider
+		trackStart := -1.0
+		trackLen, trackPos = w.playerPosition()
+		if trackPos >= 0 && trackLen >= trackPos {
+			trackStart = 0
+		}
+		w.MiniPlayerPositionScale.SetSensitive(trackStart == 0)
+		w.MiniPlayerPositionAdjustment.SetLower(trackStart)
+		w.MiniPlayerPositionAdjustment.SetUpper(trackLen)
+		w.MiniPlayerPositionAdjustment.SetValue(trackPos)
+	}
+
+	// Update position text
+	seekPos := ""
+	if trackPos >= 0 {
+		seekPos = util.FormatSeconds(trackPos)
+		if trackLen >= trackPos {
+			seekPos += " / " + util.FormatSeconds(trackLen)
+		}
+	}
+	w.MiniPlayerPositionLabel.SetText(seekPos)
+}
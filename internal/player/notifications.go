@@ -0,0 +1,98 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/notify"
+	"github.com/yktoo/ymuse/internal/util"
+	"slices"
+)
+
+// Size of the album art image in desktop notifications, in pixels
+const notifyAlbumArtSize = 128
+
+// onNotifyAction handles an action invoked from a notification. Called from a separate goroutine
+func (w *MainWindow) onNotifyAction(key string) {
+	glib.IdleAdd(func() {
+		switch key {
+		case "previous":
+			w.aPlayerPrevious.Activate(nil)
+		case "next":
+			w.aPlayerNext.Activate(nil)
+		case notify.ActionDefault:
+			w.AppWindow.Present()
+		}
+	})
+}
+
+// updateNotification shows a desktop notification for the given current song, provided notifications are enabled and
+// a different song has started playing since the last notification
+func (w *MainWindow) updateNotification(status, song mpd.Attrs) {
+	songID := status["songid"]
+	if w.notifier == nil || status["state"] != "play" || songID == "" || songID == w.notifySongID {
+		return
+	}
+	w.notifySongID = songID
+	if w.notifyTemplate == nil {
+		return
+	}
+
+	// Render the text: the first line is the summary, the rest is the body
+	var buffer bytes.Buffer
+	if errCheck(w.notifyTemplate.Execute(&buffer, song), "Notification template error") {
+		return
+	}
+	n := notify.Notification{
+		Icon: config.AppMetadata.Icon,
+		Actions: []notify.Action{
+			{Key: "previous", Label: glib.Local("Previous")},
+			{Key: "next", Label: glib.Local("Next")},
+			{Key: notify.ActionDefault, Label: config.AppMetadata.Name},
+		},
+		Transient: true,
+	}
+	n.Summary, n.Body = notify.SplitText(buffer.String())
+	if n.Summary == "" {
+		return
+	}
+
+	// Add the album art, if it's cached or can be fetched
+	if uri := song["file"]; uri != "" {
+		if albumArt := w.connector.GetAlbumArt(uri, notifyAlbumArtSize); len(albumArt) > 0 {
+			if px, err := util.NewPixbufScaled(albumArt, notifyAlbumArtSize); !errCheck(err, "NewPixbufScaled() failed") {
+				n.Image = &notify.Image{
+					Width:         px.GetWidth(),
+					Height:        px.GetHeight(),
+					RowStride:     px.GetRowstride(),
+					HasAlpha:      px.GetHasAlpha(),
+					BitsPerSample: px.GetBitsPerSample(),
+					Channels:      px.GetNChannels(),
+					Data:          slices.Clone(px.GetPixels()),
+				}
+			}
+		}
+	}
+
+	// Don't block the UI while talking to the notification service
+	notifier := w.notifier
+	go func() {
+		errCheck(notifier.Show(n), "Failed to show notification")
+	}()
+}
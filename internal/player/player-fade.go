@@ -0,0 +1,149 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+	"time"
+)
+
+// playerFadeCancel stops the fade under way, if any, leaving the volume where it is
+func (w *MainWindow) playerFadeCancel() {
+	w.fader.Cancel()
+	w.fadeSerial++
+	w.fadeVolume, w.fadingOut = -1, false
+	w.fadeCommand, w.fadeErrMessage = nil, ""
+}
+
+// playerFadeComplete ends the fade under way, if any, without waiting for it: the command a fade-out precedes is run
+// right away, and the volume the fade started from is restored
+func (w *MainWindow) playerFadeComplete() {
+	if w.fadeVolume >= 0 {
+		w.playerFadeFinish(w.fadeCommand, w.fadeErrMessage)
+	}
+}
+
+// playerFadeIn runs the given MPD command, if any, and fades the volume in. Unless a fade is already under way, the
+// volume is muted before running the command. Without a fade-in configured, the command is simply run, and an
+// interrupted fade-out is undone
+func (w *MainWindow) playerFadeIn(command func(client *mpd.Client) error, errMessage string) {
+	status := w.connector.Status()
+	cur := util.AtoiDef(status["volume"], -1)
+	vol, fresh := w.fadeVolume, w.fadeVolume < 0
+	if fresh {
+		vol = cur
+	}
+	duration := time.Duration(config.GetConfig().PlayerFadeIn) * time.Millisecond
+	w.fader.Cancel()
+
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		switch {
+		case duration <= 0 || vol <= 0:
+			// No fading: bring back the volume an interrupted fade started from
+			if !fresh {
+				errCheck(client.SetVolume(vol), "Failed to restore volume")
+			}
+		case fresh:
+			errCheck(client.SetVolume(0), "Failed to mute volume")
+			cur = 0
+		}
+		if command != nil {
+			err = command(client)
+		}
+	})
+	if err != nil && duration > 0 && vol > 0 {
+		errCheck(w.setVolume(vol), "Failed to restore volume")
+	}
+	if duration <= 0 || vol <= 0 || err != nil {
+		w.playerFadeCancel()
+		w.errCheckDialog(err, errMessage)
+		return
+	}
+
+	// Resuming an interrupted fade-out takes proportionally less time
+	if cur > 0 && cur < vol {
+		duration = duration * time.Duration(vol-cur) / time.Duration(vol)
+	}
+	w.fadeSerial++
+	serial := w.fadeSerial
+	w.fadeVolume, w.fadingOut = vol, false
+	w.fader.Fade(max(cur, 0), vol, duration, func(completed bool) {
+		if completed {
+			glib.IdleAdd(func() {
+				if serial == w.fadeSerial {
+					w.fadeVolume = -1
+				}
+			})
+		}
+	})
+}
+
+// playerFadeOut fades the volume out, then runs the given MPD command and restores the volume. Without a fade-out
+// configured, or if there's nothing playing, the command is run right away
+func (w *MainWindow) playerFadeOut(command func(client *mpd.Client) error, errMessage string) {
+	status := w.connector.Status()
+	cur := util.AtoiDef(status["volume"], -1)
+	vol := w.fadeVolume
+	if vol < 0 {
+		vol = cur
+	}
+	duration := time.Duration(config.GetConfig().PlayerFadeOut) * time.Millisecond
+	if duration <= 0 || cur <= 0 || status["state"] != "play" {
+		w.playerFadeFinish(command, errMessage)
+		return
+	}
+
+	// Continuing an interrupted fade-in takes proportionally less time
+	if cur < vol {
+		duration = duration * time.Duration(cur) / time.Duration(vol)
+	}
+	w.fadeSerial++
+	serial := w.fadeSerial
+	w.fadeVolume, w.fadingOut = vol, true
+	w.fadeCommand, w.fadeErrMessage = command, errMessage
+	w.fader.Fade(cur, 0, duration, func(completed bool) {
+		if completed {
+			glib.IdleAdd(func() {
+				if serial == w.fadeSerial {
+					w.playerFadeFinish(command, errMessage)
+				}
+			})
+		}
+	})
+}
+
+// playerFadeFinish stops the fade under way, if any, runs the given MPD command, if any, and restores the volume the
+// fade started from
+func (w *MainWindow) playerFadeFinish(command func(client *mpd.Client) error, errMessage string) {
+	vol := w.fadeVolume
+	w.playerFadeCancel()
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		if command != nil {
+			err = command(client)
+		}
+		if vol >= 0 {
+			errCheck(client.SetVolume(vol), "Failed to restore volume")
+		}
+	})
+
+	// Check for error
+	w.errCheckDialog(err, errMessage)
+}
@@ -0,0 +1,57 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+// playlistDuplicates returns positions of the entries repeating an earlier entry of the given list of URIs, in
+// descending order, so that they can be deleted one by one
+func playlistDuplicates(uris []string) []int {
+	var result []int
+	seen := make(map[string]bool, len(uris))
+	for i, uri := range uris {
+		if seen[uri] {
+			result = append(result, i)
+		}
+		seen[uri] = true
+	}
+
+	// Reverse the order
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// playlistMissing returns the unique URIs from src that are absent from dst, in the order of their first occurrence
+func playlistMissing(dst, src []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(dst)+len(src))
+	for _, uri := range dst {
+		seen[uri] = true
+	}
+	for _, uri := range src {
+		if !seen[uri] {
+			result = append(result, uri)
+			seen[uri] = true
+		}
+	}
+	return result
+}
+
+// playlistDiff compares two lists of URIs and returns the unique entries found only in the first one and only in the
+// second one
+func playlistDiff(a, b []string) (onlyA, onlyB []string) {
+	return playlistMissing(b, a), playlistMissing(a, b)
+}
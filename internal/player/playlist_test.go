@@ -0,0 +1,85 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_playlistDuplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		uris []string
+		want []int
+	}{
+		{"empty", nil, nil},
+		{"no duplicates", []string{"a", "b", "c"}, nil},
+		{"one duplicate", []string{"a", "b", "a"}, []int{2}},
+		{"several duplicates", []string{"a", "b", "a", "b", "c", "a"}, []int{5, 3, 2}},
+		{"adjacent duplicates", []string{"a", "a", "a"}, []int{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := playlistDuplicates(tt.uris); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("playlistDuplicates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_playlistMissing(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst, src []string
+		want     []string
+	}{
+		{"both empty", nil, nil, nil},
+		{"empty source", []string{"a"}, nil, nil},
+		{"empty destination", nil, []string{"a", "b"}, []string{"a", "b"}},
+		{"all present", []string{"a", "b"}, []string{"b", "a"}, nil},
+		{"some missing", []string{"a", "b"}, []string{"c", "a", "d"}, []string{"c", "d"}},
+		{"repeated in source", []string{"a"}, []string{"c", "c", "a", "c"}, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := playlistMissing(tt.dst, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("playlistMissing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_playlistDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         []string
+		wantA, wantB []string
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, nil, nil},
+		{"reordered", []string{"a", "b"}, []string{"b", "a"}, nil, nil},
+		{"disjoint", []string{"a"}, []string{"b"}, []string{"a"}, []string{"b"}},
+		{"overlapping", []string{"a", "b", "c"}, []string{"c", "d", "a"}, []string{"b"}, []string{"d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotA, gotB := playlistDiff(tt.a, tt.b)
+			if !reflect.DeepEqual(gotA, tt.wantA) || !reflect.DeepEqual(gotB, tt.wantB) {
+				t.Errorf("playlistDiff() = (%v, %v), want (%v, %v)", gotA, gotB, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/scrobbler"
 	"github.com/yktoo/ymuse/internal/util"
+	"math"
 	"sync"
 	"time"
 )
@@ -51,10 +53,12 @@ type PrefsDialog struct {
 	QueueToolbarCheckButton            *gtk.CheckButton
 	LibraryDefaultReplaceRadioButton   *gtk.RadioButton
 	LibraryDefaultAppendRadioButton    *gtk.RadioButton
+	LibraryAlbumGroupingComboBox       *gtk.ComboBoxText
 	PlaylistsDefaultReplaceRadioButton *gtk.RadioButton
 	PlaylistsDefaultAppendRadioButton  *gtk.RadioButton
 	StreamsDefaultReplaceRadioButton   *gtk.RadioButton
 	StreamsDefaultAppendRadioButton    *gtk.RadioButton
+	TrayIconCheckButton                *gtk.CheckButton
 	// Automation page widgets
 	AutomationQueueReplaceSwitchToCheckButton *gtk.CheckButton
 	AutomationQueueReplacePlayCheckButton     *gtk.CheckButton
@@ -63,27 +67,56 @@ type PrefsDialog struct {
 	PlayerShowAlbumArtStreamsCheckButton *gtk.CheckButton
 	PlayerAlbumArtSizeAdjustment         *gtk.Adjustment
 	PlayerTitleTemplateTextBuffer        *gtk.TextBuffer
+	PlayerNotifyCheckButton              *gtk.CheckButton
+	PlayerNotifyTemplateTextView         *gtk.TextView
+	PlayerNotifyTemplateTextBuffer       *gtk.TextBuffer
+	MiniPlayerTemplateTextBuffer         *gtk.TextBuffer
+	PlayerFadeOutAdjustment              *gtk.Adjustment
+	PlayerFadeInAdjustment               *gtk.Adjustment
+	SleepFadeDurationAdjustment          *gtk.Adjustment
+	AlarmFadeDurationAdjustment          *gtk.Adjustment
 	// Columns page widgets
 	ColumnsListBox *gtk.ListBox
+	// Scrobbling page widgets
+	ScrobblerServiceComboBox *gtk.ComboBoxText
+	ScrobblerURLEntry        *gtk.Entry
+	ScrobblerTokenEntry      *gtk.Entry
+	ScrobblerTokenLabel      *gtk.Label
+	ScrobblerAPIKeyEntry     *gtk.Entry
+	ScrobblerAPIKeyLabel     *gtk.Label
+	ScrobblerAPISecretEntry  *gtk.Entry
+	ScrobblerAPISecretLabel  *gtk.Label
 
 	// Whether the dialog is initialised
 	initialised bool
+	// Whether the dialog is closed
+	closed bool
 	// Columns, in the same order as in the ColumnsListBox
 	queueColumns []queueCol
-	// Timer for delayed player setting change callback invocation
-	playerSettingChangeTimer *time.Timer
-	playerSettingChangeMutex sync.Mutex
+	// MPD password and address it's for, as last looked up or stored. The password is only stored on reconnecting and
+	// on closing, to avoid hitting the keyring on every keystroke
+	mpdPassword     string
+	mpdPasswordAddr string
+	// Timers for delayed setting change callback invocation
+	librarySettingChangeTimer   *time.Timer
+	playerSettingChangeTimer    *time.Timer
+	scrobblerSettingChangeTimer *time.Timer
+	settingChangeMutex          sync.Mutex
 	// Callbacks
-	onQueueColumnsChanged  func()
-	onPlayerSettingChanged func()
+	onQueueColumnsChanged     func()
+	onLibrarySettingChanged   func()
+	onPlayerSettingChanged    func()
+	onScrobblerSettingChanged func()
 }
 
 // ShowPreferencesDialog creates, shows and disposes of a Preferences dialog instance
-func ShowPreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsChanged, onPlayerSettingChanged func()) {
+func ShowPreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsChanged, onLibrarySettingChanged, onPlayerSettingChanged, onScrobblerSettingChanged func()) {
 	// Create the dialog
 	d := &PrefsDialog{
-		onQueueColumnsChanged:  onQueueColumnsChanged,
-		onPlayerSettingChanged: onPlayerSettingChanged,
+		onQueueColumnsChanged:     onQueueColumnsChanged,
+		onLibrarySettingChanged:   onLibrarySettingChanged,
+		onPlayerSettingChanged:    onPlayerSettingChanged,
+		onScrobblerSettingChanged: onScrobblerSettingChanged,
 	}
 
 	// Load the dialog layout and map the widgets
@@ -111,13 +144,15 @@ func ShowPreferencesDialog(parent gtk.IWindow, onMpdReconnect, onQueueColumnsCha
 	builder.ConnectSignals(map[string]interface{}{
 		"on_PreferencesDialog_map":            d.onMap,
 		"on_Setting_change":                   d.onSettingChange,
-		"on_MpdReconnect":                     onMpdReconnect,
+		"on_MpdReconnect":                     func() { d.storeMpdPassword(onMpdReconnect) },
 		"on_ColumnMoveUpToolButton_clicked":   d.onColumnMoveUp,
 		"on_ColumnMoveDownToolButton_clicked": d.onColumnMoveDown,
 	})
 
 	// Run the dialog
 	d.PreferencesDialog.Run()
+	d.closed = true
+	d.storeMpdPassword(nil)
 }
 
 func (d *PrefsDialog) onMap() {
@@ -130,7 +165,8 @@ func (d *PrefsDialog) onMap() {
 	d.MpdPathEntry.SetText(cfg.MpdSocketPath)
 	d.MpdHostEntry.SetText(cfg.MpdHost)
 	d.MpdPortAdjustment.SetValue(float64(cfg.MpdPort))
-	d.MpdPasswordEntry.SetText(cfg.MpdPassword)
+	_, d.mpdPasswordAddr = cfg.MpdNetworkAddress()
+	d.lookupMpdPassword(cfg.MpdPasswordLookup())
 	d.MpdAutoConnectCheckButton.SetActive(cfg.MpdAutoConnect)
 	d.MpdAutoReconnectCheckButton.SetActive(cfg.MpdAutoReconnect)
 	d.updateGeneralWidgets()
@@ -138,19 +174,36 @@ func (d *PrefsDialog) onMap() {
 	d.QueueToolbarCheckButton.SetActive(cfg.QueueToolbar)
 	d.LibraryDefaultReplaceRadioButton.SetActive(cfg.TrackDefaultReplace)
 	d.LibraryDefaultAppendRadioButton.SetActive(!cfg.TrackDefaultReplace)
+	d.LibraryAlbumGroupingComboBox.SetActiveID(cfg.AlbumGrouping)
 	d.PlaylistsDefaultReplaceRadioButton.SetActive(cfg.PlaylistDefaultReplace)
 	d.PlaylistsDefaultAppendRadioButton.SetActive(!cfg.PlaylistDefaultReplace)
 	d.StreamsDefaultReplaceRadioButton.SetActive(cfg.StreamDefaultReplace)
 	d.StreamsDefaultAppendRadioButton.SetActive(!cfg.StreamDefaultReplace)
+	d.TrayIconCheckButton.SetActive(cfg.TrayIcon)
 	d.PlayerShowAlbumArtTracksCheckButton.SetActive(cfg.PlayerAlbumArtTracks)
 	d.PlayerShowAlbumArtStreamsCheckButton.SetActive(cfg.PlayerAlbumArtStreams)
 	d.PlayerAlbumArtSizeAdjustment.SetValue(float64(cfg.PlayerAlbumArtSize))
 	d.PlayerTitleTemplateTextBuffer.SetText(cfg.PlayerTitleTemplate)
+	d.PlayerNotifyCheckButton.SetActive(cfg.PlayerNotify)
+	d.PlayerNotifyTemplateTextBuffer.SetText(cfg.PlayerNotifyTemplate)
+	d.MiniPlayerTemplateTextBuffer.SetText(cfg.MiniPlayerTemplate)
+	d.PlayerFadeOutAdjustment.SetValue(float64(cfg.PlayerFadeOut) / 1000)
+	d.PlayerFadeInAdjustment.SetValue(float64(cfg.PlayerFadeIn) / 1000)
+	d.SleepFadeDurationAdjustment.SetValue(float64(cfg.SleepFadeDuration))
+	d.AlarmFadeDurationAdjustment.SetValue(float64(cfg.AlarmFadeDuration))
+	d.updatePlayerWidgets()
 	// Automation page
 	d.AutomationQueueReplaceSwitchToCheckButton.SetActive(cfg.SwitchToOnQueueReplace)
 	d.AutomationQueueReplacePlayCheckButton.SetActive(cfg.PlayOnQueueReplace)
 	// Columns page
 	d.populateColumns()
+	// Scrobbling page
+	d.ScrobblerServiceComboBox.SetActiveID(cfg.ScrobblerService)
+	d.ScrobblerURLEntry.SetText(cfg.ScrobblerURL)
+	d.ScrobblerTokenEntry.SetText(cfg.ScrobblerToken)
+	d.ScrobblerAPIKeyEntry.SetText(cfg.ScrobblerAPIKey)
+	d.ScrobblerAPISecretEntry.SetText(cfg.ScrobblerAPISecret)
+	d.updateScrobblingWidgets()
 	d.initialised = true
 }
 
@@ -297,9 +350,6 @@ func (d *PrefsDialog) onSettingChange() {
 	cfg.MpdSocketPath = util.EntryText(d.MpdPathEntry, "")
 	cfg.MpdHost = util.EntryText(d.MpdHostEntry, "")
 	cfg.MpdPort = int(d.MpdPortAdjustment.GetValue())
-	if s, err := d.MpdPasswordEntry.GetText(); !errCheck(err, "MpdPasswordEntry.GetText() failed") {
-		cfg.MpdPassword = s
-	}
 	cfg.MpdAutoConnect = d.MpdAutoConnectCheckButton.GetActive()
 	cfg.MpdAutoReconnect = d.MpdAutoReconnectCheckButton.GetActive()
 	d.updateGeneralWidgets()
@@ -310,8 +360,16 @@ func (d *PrefsDialog) onSettingChange() {
 		d.schedulePlayerSettingChange()
 	}
 	cfg.TrackDefaultReplace = d.LibraryDefaultReplaceRadioButton.GetActive()
+	if s := d.LibraryAlbumGroupingComboBox.GetActiveID(); s != cfg.AlbumGrouping {
+		cfg.AlbumGrouping = s
+		d.scheduleLibrarySettingChange()
+	}
 	cfg.PlaylistDefaultReplace = d.PlaylistsDefaultReplaceRadioButton.GetActive()
 	cfg.StreamDefaultReplace = d.StreamsDefaultReplaceRadioButton.GetActive()
+	if b := d.TrayIconCheckButton.GetActive(); b != cfg.TrayIcon {
+		cfg.TrayIcon = b
+		d.schedulePlayerSettingChange()
+	}
 
 	// Automation page
 	cfg.SwitchToOnQueueReplace = d.AutomationQueueReplaceSwitchToCheckButton.GetActive()
@@ -336,6 +394,45 @@ func (d *PrefsDialog) onSettingChange() {
 			d.schedulePlayerSettingChange()
 		}
 	}
+	if b := d.PlayerNotifyCheckButton.GetActive(); b != cfg.PlayerNotify {
+		cfg.PlayerNotify = b
+		d.updatePlayerWidgets()
+		d.schedulePlayerSettingChange()
+	}
+	if s, err := util.GetTextBufferText(d.PlayerNotifyTemplateTextBuffer); !errCheck(err, "util.GetTextBufferText() failed") {
+		if s != cfg.PlayerNotifyTemplate {
+			cfg.PlayerNotifyTemplate = s
+			d.schedulePlayerSettingChange()
+		}
+	}
+	if s, err := util.GetTextBufferText(d.MiniPlayerTemplateTextBuffer); !errCheck(err, "util.GetTextBufferText() failed") {
+		if s != cfg.MiniPlayerTemplate {
+			cfg.MiniPlayerTemplate = s
+			d.schedulePlayerSettingChange()
+		}
+	}
+	cfg.PlayerFadeOut = int(math.Round(d.PlayerFadeOutAdjustment.GetValue() * 1000))
+	cfg.PlayerFadeIn = int(math.Round(d.PlayerFadeInAdjustment.GetValue() * 1000))
+	cfg.SleepFadeDuration = int(d.SleepFadeDurationAdjustment.GetValue())
+	cfg.AlarmFadeDuration = int(d.AlarmFadeDurationAdjustment.GetValue())
+
+	// Scrobbling page
+	changed := false
+	setString := func(p *string, s string) {
+		if s != *p {
+			*p = s
+			changed = true
+		}
+	}
+	setString(&cfg.ScrobblerService, d.ScrobblerServiceComboBox.GetActiveID())
+	setString(&cfg.ScrobblerURL, util.EntryText(d.ScrobblerURLEntry, ""))
+	setString(&cfg.ScrobblerToken, util.EntryText(d.ScrobblerTokenEntry, ""))
+	setString(&cfg.ScrobblerAPIKey, util.EntryText(d.ScrobblerAPIKeyEntry, ""))
+	setString(&cfg.ScrobblerAPISecret, util.EntryText(d.ScrobblerAPISecretEntry, ""))
+	if changed {
+		d.updateScrobblingWidgets()
+		d.scheduleCallback(&d.scrobblerSettingChangeTimer, d.onScrobblerSettingChanged)
+	}
 }
 
 // populateColumns fills in the Columns list box
@@ -365,23 +462,72 @@ func (d *PrefsDialog) populateColumns() {
 	d.ColumnsListBox.ShowAll()
 }
 
-func (d *PrefsDialog) schedulePlayerSettingChange() {
+// scheduleCallback schedules a delayed invocation of the given callback on the GTK main loop, cancelling the one
+// previously scheduled with the same timer
+func (d *PrefsDialog) scheduleCallback(timer **time.Timer, callback func()) {
 	// Cancel the currently scheduled callback, if any
-	d.playerSettingChangeMutex.Lock()
-	defer d.playerSettingChangeMutex.Unlock()
-	if d.playerSettingChangeTimer != nil {
-		d.playerSettingChangeTimer.Stop()
+	d.settingChangeMutex.Lock()
+	defer d.settingChangeMutex.Unlock()
+	if *timer != nil {
+		(*timer).Stop()
 	}
 
 	// Schedule a new callback
-	d.playerSettingChangeTimer = time.AfterFunc(time.Second, func() {
-		d.playerSettingChangeMutex.Lock()
-		d.playerSettingChangeTimer = nil
-		d.playerSettingChangeMutex.Unlock()
-		glib.IdleAdd(d.onPlayerSettingChanged)
+	*timer = time.AfterFunc(time.Second, func() {
+		d.settingChangeMutex.Lock()
+		*timer = nil
+		d.settingChangeMutex.Unlock()
+		glib.IdleAdd(callback)
 	})
 }
 
+func (d *PrefsDialog) scheduleLibrarySettingChange() {
+	d.scheduleCallback(&d.librarySettingChangeTimer, d.onLibrarySettingChanged)
+}
+
+func (d *PrefsDialog) schedulePlayerSettingChange() {
+	d.scheduleCallback(&d.playerSettingChangeTimer, d.onPlayerSettingChanged)
+}
+
+// lookupMpdPassword looks up the MPD password in the background, since the keyring may prompt the user, and puts it
+// into the password entry unless the user has already typed something there
+func (d *PrefsDialog) lookupMpdPassword(lookup func() (string, error)) {
+	go func() {
+		password, err := lookup()
+		errCheck(err, "Failed to look up MPD password")
+		glib.IdleAdd(func() {
+			if d.closed || util.EntryText(d.MpdPasswordEntry, d.mpdPassword) != d.mpdPassword {
+				return
+			}
+			d.MpdPasswordEntry.SetText(password)
+			d.mpdPassword = password
+		})
+	}()
+}
+
+// storeMpdPassword stores the entered MPD password if either the password or the server has changed, and calls done,
+// if not nil, once it's stored
+func (d *PrefsDialog) storeMpdPassword(done func()) {
+	cfg := config.GetConfig()
+	password := util.EntryText(d.MpdPasswordEntry, d.mpdPassword)
+	_, addr := cfg.MpdNetworkAddress()
+
+	// An empty password for another server would delete that server's password
+	if !d.initialised || (password == d.mpdPassword && (addr == d.mpdPasswordAddr || password == "")) {
+		if done != nil {
+			done()
+		}
+		return
+	}
+	cfg.StoreMpdPassword(password, func(err error) {
+		errCheck(err, "Failed to store MPD password")
+		if done != nil {
+			done()
+		}
+	})
+	d.mpdPassword, d.mpdPasswordAddr = password, addr
+}
+
 // updateGeneralWidgets updates widget states on the General tab
 func (d *PrefsDialog) updateGeneralWidgets() {
 	network := d.MpdNetworkComboBox.GetActiveID()
@@ -394,3 +540,29 @@ func (d *PrefsDialog) updateGeneralWidgets() {
 	d.MpdPortSpinButton.SetVisible(tcp)
 	d.MpdPortLabel.SetVisible(tcp)
 }
+
+// updatePlayerWidgets updates widget states on the Player tab
+func (d *PrefsDialog) updatePlayerWidgets() {
+	d.PlayerNotifyTemplateTextView.SetSensitive(d.PlayerNotifyCheckButton.GetActive())
+}
+
+// updateScrobblingWidgets updates widget states on the Scrobbling tab
+func (d *PrefsDialog) updateScrobblingWidgets() {
+	service := d.ScrobblerServiceComboBox.GetActiveID()
+	as := service == config.ScrobblerServiceAudioscrobbler
+	d.ScrobblerURLEntry.SetSensitive(service != config.ScrobblerServiceNone)
+	d.ScrobblerTokenEntry.SetSensitive(service != config.ScrobblerServiceNone)
+	d.ScrobblerAPIKeyEntry.SetVisible(as)
+	d.ScrobblerAPIKeyLabel.SetVisible(as)
+	d.ScrobblerAPISecretEntry.SetVisible(as)
+	d.ScrobblerAPISecretLabel.SetVisible(as)
+
+	// Show the default URL as a hint
+	if as {
+		d.ScrobblerURLEntry.SetPlaceholderText(scrobbler.AudioscrobblerURL)
+		d.ScrobblerTokenLabel.SetText(glib.Local("Session key:"))
+	} else {
+		d.ScrobblerURLEntry.SetPlaceholderText(scrobbler.ListenBrainzURL)
+		d.ScrobblerTokenLabel.SetText(glib.Local("User token:"))
+	}
+}
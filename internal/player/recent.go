@@ -0,0 +1,95 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"sort"
+	"time"
+)
+
+// Time windows of the recent library views
+const (
+	RecentWindowDay       = "24h"   // Last 24 hours
+	RecentWindowWeek      = "7d"    // Last 7 days
+	RecentWindowMonth     = "30d"   // Last 30 days
+	RecentWindowLastVisit = "visit" // Since the application was last closed
+)
+
+// RecentWindows lists all recent view time windows, in the display order
+var RecentWindows = []string{RecentWindowDay, RecentWindowWeek, RecentWindowMonth, RecentWindowLastVisit}
+
+// MPD song attributes holding the file modification and database addition times
+const (
+	recentAttrModified = "Last-Modified"
+	recentAttrAdded    = "Added" // MPD 0.24+
+)
+
+// RecentAlbum is a group of recently changed tracks belonging to the same album
+type RecentAlbum struct {
+	ID     AlbumIdentity // Album identity
+	Tracks []mpd.Attrs   // Album's changed tracks, ordered by URI
+	Newest string        // Time of the most recent change to the album's tracks, in ISO 8601 format
+}
+
+// RecentWindowSince returns the UNIX time the given window starts at. lastVisit is the UNIX time of the last visit, with
+// 0 meaning there was none, in which case the window falls back to 24 hours
+func RecentWindowSince(window string, now time.Time, lastVisit int64) int64 {
+	switch window {
+	case RecentWindowWeek:
+		return now.AddDate(0, 0, -7).Unix()
+	case RecentWindowMonth:
+		return now.AddDate(0, 0, -30).Unix()
+	case RecentWindowLastVisit:
+		if lastVisit > 0 {
+			return lastVisit
+		}
+	}
+	return now.Add(-24 * time.Hour).Unix()
+}
+
+// GroupRecentTracks groups the given tracks by album, ordering the albums by their most recent change, newest first.
+// timeAttr is the name of the attribute holding the change time
+func GroupRecentTracks(attrs []mpd.Attrs, grouping, timeAttr string) []RecentAlbum {
+	var result []RecentAlbum
+	index := make(map[AlbumIdentity]int)
+	for _, a := range attrs {
+		id := NewAlbumIdentity(a, grouping)
+		i, ok := index[id]
+		if !ok {
+			i = len(result)
+			index[id] = i
+			result = append(result, RecentAlbum{ID: id})
+		}
+		album := &result[i]
+		album.Tracks = append(album.Tracks, a)
+		// ISO 8601 times in UTC compare correctly as strings
+		if t := a[timeAttr]; t > album.Newest {
+			album.Newest = t
+		}
+	}
+
+	// Sort the albums newest first, and the tracks by URI
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Newest > result[j].Newest
+	})
+	for _, album := range result {
+		sort.SliceStable(album.Tracks, func(i, j int) bool {
+			return album.Tracks[i]["file"] < album.Tracks[j]["file"]
+		})
+	}
+	return result
+}
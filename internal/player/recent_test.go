@@ -0,0 +1,79 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/yktoo/ymuse/internal/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecentWindowSince(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		window    string
+		lastVisit int64
+		want      time.Time
+	}{
+		{"day", RecentWindowDay, 0, time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{"week", RecentWindowWeek, 0, time.Date(2026, 3, 24, 12, 0, 0, 0, time.UTC)},
+		{"month", RecentWindowMonth, 0, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"last visit", RecentWindowLastVisit, 1000, time.Unix(1000, 0)},
+		{"no last visit", RecentWindowLastVisit, 0, time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{"unknown", "foo", 1000, time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecentWindowSince(tt.window, now, tt.lastVisit); got != tt.want.Unix() {
+				t.Errorf("RecentWindowSince() = %v, want %v", got, tt.want.Unix())
+			}
+		})
+	}
+}
+
+func TestGroupRecentTracks(t *testing.T) {
+	a1 := mpd.Attrs{"file": "a/2.flac", "Album": "A", "AlbumArtist": "X", "Last-Modified": "2026-03-01T10:00:00Z"}
+	a2 := mpd.Attrs{"file": "a/1.flac", "Album": "A", "AlbumArtist": "X", "Last-Modified": "2026-03-02T10:00:00Z"}
+	b1 := mpd.Attrs{"file": "b/1.flac", "Album": "B", "Artist": "Y", "Last-Modified": "2026-03-03T10:00:00Z"}
+	c1 := mpd.Attrs{"file": "c/1.flac", "Album": "A", "AlbumArtist": "Z", "Last-Modified": "2026-02-01T10:00:00Z"}
+	tests := []struct {
+		name     string
+		attrs    []mpd.Attrs
+		timeAttr string
+		want     []RecentAlbum
+	}{
+		{"empty", nil, recentAttrModified, nil},
+		{"modified", []mpd.Attrs{a1, c1, b1, a2}, recentAttrModified, []RecentAlbum{
+			{ID: AlbumIdentity{Album: "B", Artist: "Y"}, Tracks: []mpd.Attrs{b1}, Newest: "2026-03-03T10:00:00Z"},
+			{ID: AlbumIdentity{Album: "A", AlbumArtist: "X"}, Tracks: []mpd.Attrs{a2, a1}, Newest: "2026-03-02T10:00:00Z"},
+			{ID: AlbumIdentity{Album: "A", AlbumArtist: "Z"}, Tracks: []mpd.Attrs{c1}, Newest: "2026-02-01T10:00:00Z"},
+		}},
+		{"missing time attribute", []mpd.Attrs{b1, a1}, recentAttrAdded, []RecentAlbum{
+			{ID: AlbumIdentity{Album: "B", Artist: "Y"}, Tracks: []mpd.Attrs{b1}},
+			{ID: AlbumIdentity{Album: "A", AlbumArtist: "X"}, Tracks: []mpd.Attrs{a1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupRecentTracks(tt.attrs, config.AlbumGroupingArtist, tt.timeAttr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupRecentTracks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
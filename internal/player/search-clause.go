@@ -0,0 +1,161 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/util"
+)
+
+const (
+	searchClauseJoinAnd = "and"
+	searchClauseJoinOr  = "or"
+)
+
+// searchClauseRow is a row of widgets representing a single condition in the advanced library search panel
+type searchClauseRow struct {
+	box         *gtk.Box
+	joinCombo   *gtk.ComboBoxText
+	negateCheck *gtk.CheckButton
+	attrCombo   *gtk.ComboBoxText
+	opCombo     *gtk.ComboBoxText
+	valueEntry  *gtk.SearchEntry
+	onChange    func()
+}
+
+// newSearchClauseRow creates and returns a new searchClauseRow instance. onChange is called whenever the condition
+// changes, onRemove when the user requests to remove the row
+func newSearchClauseRow(onChange func(), onRemove func(row *searchClauseRow)) (*searchClauseRow, error) {
+	r := &searchClauseRow{onChange: onChange}
+	var err error
+
+	// Create a container box
+	if r.box, err = gtk.BoxNew(gtk.ORIENTATION_HORIZONTAL, 6); err != nil {
+		return nil, err
+	}
+
+	// Create a join combo box
+	if r.joinCombo, err = gtk.ComboBoxTextNew(); err != nil {
+		return nil, err
+	}
+	r.joinCombo.Append(searchClauseJoinAnd, glib.Local("and"))
+	r.joinCombo.Append(searchClauseJoinOr, glib.Local("or"))
+	r.joinCombo.SetActiveID(searchClauseJoinAnd)
+	r.joinCombo.SetTooltipText(glib.Local("How to combine the condition with the preceding one"))
+	r.joinCombo.Connect("changed", onChange)
+	r.joinCombo.SetNoShowAll(true) // Visibility is controlled by setFirst()
+	r.box.PackStart(r.joinCombo, false, false, 0)
+
+	// Create a negation check button
+	if r.negateCheck, err = gtk.CheckButtonNewWithLabel(glib.Local("not")); err != nil {
+		return nil, err
+	}
+	r.negateCheck.SetTooltipText(glib.Local("Negate the condition"))
+	r.negateCheck.Connect("toggled", onChange)
+	r.box.PackStart(r.negateCheck, false, false, 0)
+
+	// Create an attribute combo box
+	if r.attrCombo, err = gtk.ComboBoxTextNew(); err != nil {
+		return nil, err
+	}
+	r.attrCombo.Append(FilterAttrAny, glib.Local("Everywhere"))
+	for _, id := range config.MpdTrackAttributeIds {
+		if attr := config.MpdTrackAttributes[id]; attr.Searchable {
+			r.attrCombo.Append(attr.AttrName, glib.Local(attr.LongName))
+		}
+	}
+	r.attrCombo.Append(FilterAttrBase, glib.Local("Directory"))
+	r.attrCombo.Append(FilterAttrModifiedSince, glib.Local("Modified since"))
+	r.attrCombo.SetActiveID(FilterAttrAny)
+	r.attrCombo.Connect("changed", r.onAttrChanged)
+	r.box.PackStart(r.attrCombo, false, false, 0)
+
+	// Create an operator combo box
+	if r.opCombo, err = gtk.ComboBoxTextNew(); err != nil {
+		return nil, err
+	}
+	for _, op := range FilterOps {
+		r.opCombo.Append(string(op), string(op))
+	}
+	r.opCombo.SetActiveID(string(FilterOpContains))
+	r.opCombo.Connect("changed", onChange)
+	r.box.PackStart(r.opCombo, false, false, 0)
+
+	// Create a value entry
+	if r.valueEntry, err = gtk.SearchEntryNew(); err != nil {
+		return nil, err
+	}
+	r.valueEntry.Connect("search-changed", onChange)
+	r.box.PackStart(r.valueEntry, true, true, 0)
+
+	// Create a remove button
+	r.box.PackEnd(util.NewButton("", glib.Local("Remove the condition"), "", "ymuse-delete-symbolic", func() { onRemove(r) }), false, false, 0)
+
+	// Update widget state
+	r.updateWidgets()
+	return r, nil
+}
+
+// clause returns the condition as a FilterClause. The second return value is false if the condition is incomplete
+func (r *searchClauseRow) clause() (FilterClause, bool) {
+	value := util.EntryText(&r.valueEntry.Entry, "")
+	if value == "" {
+		return FilterClause{}, false
+	}
+	return FilterClause{
+		Attr:   r.attrCombo.GetActiveID(),
+		Op:     FilterOp(r.opCombo.GetActiveID()),
+		Value:  value,
+		Negate: r.negateCheck.GetActive(),
+		Or:     r.joinCombo.GetActiveID() == searchClauseJoinOr,
+	}, true
+}
+
+// grabFocus moves focus to the value entry
+func (r *searchClauseRow) grabFocus() {
+	r.valueEntry.GrabFocus()
+}
+
+// onAttrChanged updates the widgets after the attribute has been changed
+func (r *searchClauseRow) onAttrChanged() {
+	r.updateWidgets()
+	r.onChange()
+}
+
+// setFirst shows or hides the join combo box depending on whether the row is the first one
+func (r *searchClauseRow) setFirst(first bool) {
+	r.joinCombo.SetVisible(!first)
+}
+
+// updateWidgets updates the operator combo box and the value hint according to the selected attribute
+func (r *searchClauseRow) updateWidgets() {
+	attr := r.attrCombo.GetActiveID()
+
+	// Pseudo-attributes don't support operators
+	r.opCombo.SetSensitive(attr != FilterAttrBase && attr != FilterAttrModifiedSince)
+
+	// Update the value hint
+	hint := glib.Local("Value…")
+	switch attr {
+	case FilterAttrBase:
+		hint = glib.Local("Directory path…")
+	case FilterAttrModifiedSince:
+		hint = glib.Local("ISO 8601 time or UNIX timestamp…")
+	}
+	r.valueEntry.SetPlaceholderText(hint)
+}
@@ -0,0 +1,341 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"fmt"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/sleep"
+	"github.com/yktoo/ymuse/internal/util"
+	"strings"
+	"time"
+)
+
+const (
+	// Kinds of what the alarm plays, used as prefixes of its source
+	alarmSourcePlaylist = "playlist"
+	alarmSourceStream   = "stream"
+
+	// Volume the alarm fades in to if MPD's volume is zero or unknown
+	alarmDefaultVolume = 50
+)
+
+// onAlarmMinuteOutput formats the alarm minute spin button's value with two digits
+func (w *MainWindow) onAlarmMinuteOutput(sb *gtk.SpinButton) bool {
+	sb.SetText(fmt.Sprintf("%02d", sb.GetValueAsInt()))
+	return true
+}
+
+// onSleepModeChanged updates the sleep timer count according to the selected mode
+func (w *MainWindow) onSleepModeChanged() {
+	cfg := config.GetConfig()
+	switch w.SleepModeComboBox.GetActiveID() {
+	case config.SleepModeMinutes:
+		w.SleepCountSpinButton.SetValue(float64(cfg.SleepMinutes))
+		w.SleepCountSpinButton.SetSensitive(true)
+	case config.SleepModeTracks:
+		w.SleepCountSpinButton.SetValue(float64(cfg.SleepTracks))
+		w.SleepCountSpinButton.SetSensitive(true)
+	default:
+		w.SleepCountSpinButton.SetSensitive(false)
+	}
+}
+
+// onSleepPopoverShow fills in the sleep timer and alarm widgets
+func (w *MainWindow) onSleepPopoverShow() {
+	cfg := config.GetConfig()
+	w.SleepModeComboBox.SetActiveID(cfg.SleepMode)
+	w.onSleepModeChanged()
+	if cfg.SleepPause {
+		w.SleepActionComboBox.SetActiveID("pause")
+	} else {
+		w.SleepActionComboBox.SetActiveID("stop")
+	}
+	w.SleepFadeCheckButton.SetActive(cfg.SleepFade)
+
+	// Offer the playlists and the streams for the alarm
+	w.AlarmHourSpinButton.SetValue(float64(cfg.AlarmHour))
+	w.AlarmMinuteSpinButton.SetValue(float64(cfg.AlarmMinute))
+	w.AlarmSourceComboBox.RemoveAll()
+	for _, name := range w.connector.GetPlaylists() {
+		w.AlarmSourceComboBox.Append(alarmSourcePlaylist+":"+name, fmt.Sprintf(glib.Local("Playlist: %s"), name))
+	}
+	for _, stream := range cfg.Streams {
+		w.AlarmSourceComboBox.Append(alarmSourceStream+":"+stream.URI, fmt.Sprintf(glib.Local("Stream: %s"), stream.Name))
+	}
+
+	// Keep the last chosen source even if it can't be listed, for instance when MPD isn't connected
+	if cfg.AlarmSource != "" && !w.AlarmSourceComboBox.SetActiveID(cfg.AlarmSource) {
+		_, name, _ := strings.Cut(cfg.AlarmSource, ":")
+		w.AlarmSourceComboBox.Append(cfg.AlarmSource, name)
+		w.AlarmSourceComboBox.SetActiveID(cfg.AlarmSource)
+	}
+	w.AlarmFadeCheckButton.SetActive(cfg.AlarmFade)
+	w.updateSleepActions()
+}
+
+// onSleepTick runs the sleep timer and the alarm. Called once a second while either is active
+func (w *MainWindow) onSleepTick() bool {
+	if w.sleepTimer != nil {
+		w.sleepCheck()
+	}
+	if !w.alarmAt.IsZero() && !time.Now().Before(w.alarmAt) && w.alarmFire() {
+		config.GetConfig().AlarmEnabled = false
+		w.alarmAt = time.Time{}
+	}
+
+	// Stop ticking once there's nothing to run
+	active := w.sleepTimer != nil || !w.alarmAt.IsZero()
+	if !active {
+		w.sleepTickSource = 0
+	}
+	w.updateSleep()
+	return active
+}
+
+// alarmCancel disarms the wake-up alarm
+func (w *MainWindow) alarmCancel() {
+	config.GetConfig().AlarmEnabled = false
+	w.alarmAt = time.Time{}
+	w.updateSleep()
+}
+
+// alarmFire replaces the queue with what the alarm is set to play and starts the playback, fading it in if configured.
+// If MPD isn't connected, it starts connecting and returns false, so that the alarm is retried later
+func (w *MainWindow) alarmFire() bool {
+	connected, connecting := w.connector.ConnectStatus()
+	if !connected {
+		if !connecting {
+			w.connect()
+		}
+		return false
+	}
+
+	// Fade in to the current volume, unless it's muted
+	cfg := config.GetConfig()
+	log.Debugf("Alarm goes off, playing %s", cfg.AlarmSource)
+	vol := util.AtoiDef(w.connector.Status()["volume"], -1)
+	if vol <= 0 {
+		vol = alarmDefaultVolume
+	}
+
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		commands := client.BeginCommandList()
+		commands.Clear()
+		switch kind, value, _ := strings.Cut(cfg.AlarmSource, ":"); kind {
+		case alarmSourcePlaylist:
+			commands.PlaylistLoad(value, -1, -1)
+		case alarmSourceStream:
+			commands.Add(value)
+		}
+		if cfg.AlarmFade {
+			commands.SetVolume(0)
+		}
+		commands.Play(0)
+		err = commands.End()
+	})
+
+	// Check for error
+	if w.errCheckDialog(err, glib.Local("Failed to start alarm playback")) {
+		return true
+	}
+	if cfg.AlarmFade {
+		w.fader.Fade(0, vol, time.Duration(cfg.AlarmFadeDuration)*time.Second, nil)
+	}
+	return true
+}
+
+// alarmSchedule arms the alarm according to the configuration
+func (w *MainWindow) alarmSchedule() {
+	cfg := config.GetConfig()
+	if cfg.AlarmEnabled && cfg.AlarmSource != "" {
+		w.alarmAt = sleep.NextAlarm(time.Now(), cfg.AlarmHour, cfg.AlarmMinute)
+	} else {
+		w.alarmAt = time.Time{}
+	}
+	w.updateSleep()
+}
+
+// alarmSet arms the alarm with the settings from the popover
+func (w *MainWindow) alarmSet() {
+	cfg := config.GetConfig()
+	cfg.AlarmHour = w.AlarmHourSpinButton.GetValueAsInt()
+	cfg.AlarmMinute = w.AlarmMinuteSpinButton.GetValueAsInt()
+	cfg.AlarmSource = w.AlarmSourceComboBox.GetActiveID()
+	cfg.AlarmFade = w.AlarmFadeCheckButton.GetActive()
+	cfg.AlarmEnabled = true
+	w.alarmSchedule()
+	w.SleepPopoverMenu.Popdown()
+}
+
+// initSleepWidgets initialises sleep timer and alarm widgets and actions
+func (w *MainWindow) initSleepWidgets() {
+	w.addAction("sleep.start", "", w.sleepStart)
+	w.aSleepCancel = w.addAction("sleep.cancel", "", w.sleepCancel)
+	w.aAlarmSet = w.addAction("alarm.set", "", w.alarmSet)
+	w.aAlarmCancel = w.addAction("alarm.cancel", "", w.alarmCancel)
+	w.sleepVolume = -1
+	w.fadeVolume = -1
+	w.updateSleep()
+}
+
+// sleepCancel stops the sleep timer, restoring the volume if it's been faded out
+func (w *MainWindow) sleepCancel() {
+	if w.sleepTimer == nil {
+		return
+	}
+	w.sleepTimer = nil
+	if w.sleepVolume >= 0 {
+		w.fader.Cancel()
+		errCheck(w.setVolume(w.sleepVolume), "Failed to restore volume")
+		w.sleepVolume = -1
+	}
+	w.updateSleep()
+}
+
+// sleepCheck checks the sleep timer against the player state, and fades the volume out or stops the playback when it's
+// time to
+func (w *MainWindow) sleepCheck() {
+	// Tracks can only be counted while connected
+	connected, _ := w.connector.ConnectStatus()
+	if !connected && w.sleepTimer.Mode() != sleep.ModeMinutes {
+		return
+	}
+
+	status := w.connector.Status()
+	w.sleepStatus = w.sleepTimer.Check(sleep.PlayerState{
+		SongID:   status["songid"],
+		Elapsed:  util.SecondsToDuration(status["elapsed"]),
+		Duration: util.SecondsToDuration(status["duration"]),
+		Stopped:  status["state"] == "stop",
+	})
+	switch w.sleepStatus.Phase {
+	case sleep.PhaseFading:
+		// Start fading out, once
+		if vol := util.AtoiDef(status["volume"], -1); w.sleepVolume < 0 && vol > 0 && status["state"] == "play" {
+			w.sleepVolume = vol
+			w.fader.Fade(vol, 0, w.sleepStatus.Remaining, nil)
+		}
+	case sleep.PhaseExpired:
+		w.sleepExpire()
+	}
+}
+
+// sleepExpire stops or pauses the playback once the sleep timer has expired, and restores the volume if it's been
+// faded out
+func (w *MainWindow) sleepExpire() {
+	log.Debug("Sleep timer expired")
+	cfg := config.GetConfig()
+	if w.sleepVolume >= 0 {
+		w.fader.Cancel()
+	}
+	var err error
+	w.connector.IfConnected(func(client *mpd.Client) {
+		if cfg.SleepPause {
+			err = client.Pause(true)
+		} else {
+			err = client.Stop()
+		}
+		// Not every mixer allows changing the volume while stopped, so only log a failure
+		if err == nil && w.sleepVolume >= 0 {
+			errCheck(client.SetVolume(w.sleepVolume), "Failed to restore volume")
+		}
+	})
+
+	// There's nobody to show an error dialog to
+	errCheck(err, "Failed to stop playback on sleep timer")
+	w.sleepTimer, w.sleepVolume = nil, -1
+}
+
+// sleepStart starts the sleep timer with the settings from the popover
+func (w *MainWindow) sleepStart() {
+	// Save the settings
+	cfg := config.GetConfig()
+	cfg.SleepMode = w.SleepModeComboBox.GetActiveID()
+	count := w.SleepCountSpinButton.GetValueAsInt()
+	switch cfg.SleepMode {
+	case config.SleepModeMinutes:
+		cfg.SleepMinutes = count
+	case config.SleepModeTracks:
+		cfg.SleepTracks = count
+	}
+	cfg.SleepPause = w.SleepActionComboBox.GetActiveID() == "pause"
+	cfg.SleepFade = w.SleepFadeCheckButton.GetActive()
+
+	// Replace the running timer, if any
+	w.sleepCancel()
+	var fadeDuration time.Duration
+	if cfg.SleepFade {
+		fadeDuration = time.Duration(cfg.SleepFadeDuration) * time.Second
+	}
+	status := w.connector.Status()
+	w.sleepTimer = sleep.NewTimer(sleep.Mode(cfg.SleepMode), count, fadeDuration, sleep.PlayerState{
+		SongID:  status["songid"],
+		Elapsed: util.SecondsToDuration(status["elapsed"]),
+		Stopped: status["state"] == "stop",
+	})
+	w.sleepStatus = sleep.Status{Remaining: -1, Tracks: max(count, 1)}
+	if cfg.SleepMode == config.SleepModeTrackEnd {
+		w.sleepStatus.Tracks = 1
+	}
+	w.sleepCheck()
+	w.SleepPopoverMenu.Popdown()
+	w.updateSleep()
+}
+
+// updateSleep updates the sleep timer countdown and the alarm time in the header bar, and makes sure they're ticking
+// while active
+func (w *MainWindow) updateSleep() {
+	if (w.sleepTimer != nil || !w.alarmAt.IsZero()) && w.sleepTickSource == 0 {
+		w.sleepTickSource = glib.TimeoutAdd(1000, w.onSleepTick)
+	}
+
+	// Compose the countdown and the tooltip
+	var labels, tips []string
+	if w.sleepTimer != nil {
+		var left string
+		if w.sleepStatus.Remaining >= 0 {
+			left = util.FormatSeconds(w.sleepStatus.Remaining.Seconds())
+		} else {
+			left = fmt.Sprintf(glib.Local("%d tracks"), w.sleepStatus.Tracks)
+		}
+		labels = append(labels, left)
+		tips = append(tips, fmt.Sprintf(glib.Local("Sleep timer: %s left"), left))
+	}
+	if !w.alarmAt.IsZero() {
+		at := w.alarmAt.Format("15:04")
+		labels = append(labels, at)
+		tips = append(tips, fmt.Sprintf(glib.Local("Alarm set for %s"), at))
+	}
+	if len(tips) == 0 {
+		tips = append(tips, glib.Local("Sleep timer and wake-up alarm"))
+	}
+	w.SleepCountdownLabel.SetText(strings.Join(labels, " · "))
+	w.SleepCountdownLabel.SetVisible(len(labels) > 0)
+	w.SleepMenuButton.SetTooltipText(strings.Join(tips, "\n"))
+	w.updateSleepActions()
+}
+
+// updateSleepActions updates the sleep timer and alarm actions
+func (w *MainWindow) updateSleepActions() {
+	w.aSleepCancel.SetEnabled(w.sleepTimer != nil)
+	w.aAlarmSet.SetEnabled(w.AlarmSourceComboBox.GetActiveID() != "")
+	w.aAlarmCancel.SetEnabled(!w.alarmAt.IsZero())
+}
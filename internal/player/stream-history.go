@@ -0,0 +1,210 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/streamhistory"
+	"github.com/yktoo/ymuse/internal/util"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// Stream history list store columns
+	streamHistoryColTime  = 0
+	streamHistoryColTitle = 1
+)
+
+func (w *MainWindow) onStreamHistoryClear() {
+	if w.streamHistoryURI != "" &&
+		util.ConfirmDialog(w.AppWindow, glib.Local("Clear history"), glib.Local("Are you sure you want to clear the title history of this stream?")) {
+		w.streamHistory.Clear(w.streamHistoryURI)
+		errCheck(w.streamHistory.Save(), "Failed to save stream history")
+		w.updateStreamHistory()
+	}
+}
+
+func (w *MainWindow) onStreamHistoryCopy() {
+	titles := w.getStreamHistorySelectedTitles()
+	if len(titles) == 0 {
+		return
+	}
+	if clipboard, err := gtk.ClipboardGet(gdk.SELECTION_CLIPBOARD); !errCheck(err, "ClipboardGet() failed") {
+		clipboard.SetText(strings.Join(titles, "\n"))
+	}
+}
+
+func (w *MainWindow) onStreamHistoryExport() {
+	idx := w.getSelectedStreamIndex()
+	if idx < 0 {
+		return
+	}
+	stream := config.GetConfig().Streams[idx]
+
+	// Ask for the file name
+	filters := []util.FileFilterSpec{{Name: glib.Local("CSV files"), Patterns: []string{"*.csv", "*.CSV"}}}
+	fileName, ok := util.SaveFileDialog(w.AppWindow, glib.Local("Export stream history"), stream.Name+".csv", glib.Local("Export"), filters)
+	if !ok {
+		return
+	}
+
+	// Write out the history, oldest entries first
+	var buf bytes.Buffer
+	err := streamhistory.WriteCSV(&buf, stream.Name, w.streamHistory.Entries(stream.URI))
+	if err == nil {
+		err = os.WriteFile(fileName, buf.Bytes(), 0644)
+	}
+	w.errCheckDialog(err, glib.Local("Failed to export stream history"))
+}
+
+func (w *MainWindow) onStreamHistorySearch() {
+	if titles := w.getStreamHistorySelectedTitles(); len(titles) > 0 {
+		// Artist names in stream titles are often spelled differently, so only look for the song title
+		_, song := streamhistory.SplitTitle(titles[0])
+		w.librarySearchFor(song)
+	}
+}
+
+// getStreamHistorySelectedTitles returns the titles selected in the stream history panel
+func (w *MainWindow) getStreamHistorySelectedTitles() []string {
+	var titles []string
+	w.StreamHistoryTreeSelection.SelectedForEach(func(_ *gtk.TreeModel, path *gtk.TreePath, _ *gtk.TreeIter) {
+		if ix := path.GetIndices(); len(ix) > 0 && ix[0] < len(w.streamHistoryEntries) {
+			titles = append(titles, w.streamHistoryEntries[ix[0]].Title)
+		}
+	})
+	return titles
+}
+
+// streamHistoryRecord adds the given title of the stream with the given URI to the stream history
+func (w *MainWindow) streamHistoryRecord(uri, title string) {
+	if !w.streamHistory.Add(uri, title, time.Now()) {
+		return
+	}
+	w.streamHistoryScheduleSave()
+
+	// Refresh the panel if it displays this stream
+	if uri == w.streamHistoryURI {
+		w.updateStreamHistory()
+	}
+}
+
+// streamHistoryScheduleSave writes the stream history out in the background after a delay, unless that's already
+// scheduled
+func (w *MainWindow) streamHistoryScheduleSave() {
+	if w.streamHistorySaveSource != 0 {
+		return
+	}
+	w.streamHistorySaveSource = glib.TimeoutAdd(streamHistorySaveDelay, func() bool {
+		w.streamHistorySaveSource = 0
+		go func() {
+			errCheck(w.streamHistory.Save(), "Failed to save stream history")
+		}()
+		return false
+	})
+}
+
+// streamHistoryFlush writes the stream history out right away if there's a save pending
+func (w *MainWindow) streamHistoryFlush() {
+	if w.streamHistorySaveSource != 0 {
+		glib.SourceRemove(w.streamHistorySaveSource)
+		w.streamHistorySaveSource = 0
+		errCheck(w.streamHistory.Save(), "Failed to save stream history")
+	}
+}
+
+// streamHistoryToggle shows or hides the stream history panel
+func (w *MainWindow) streamHistoryToggle() {
+	// Ignore if the state of the button is being updated programmatically
+	if w.streamHistoryUpdating {
+		return
+	}
+	cfg := config.GetConfig()
+	cfg.StreamsHistory = !cfg.StreamsHistory
+	w.updateStreamHistoryPanel()
+}
+
+// updateStreamHistory fills the stream history panel with the titles played by the selected stream
+func (w *MainWindow) updateStreamHistory() {
+	w.StreamHistoryListStore.Clear()
+	w.streamHistoryEntries = nil
+	w.streamHistoryURI = ""
+
+	// Fetch the selected stream's entries, newest first
+	idx := w.getSelectedStreamIndex()
+	if idx >= 0 {
+		stream := config.GetConfig().Streams[idx]
+		w.streamHistoryURI = stream.URI
+		entries := w.streamHistory.Entries(stream.URI)
+		now := time.Now()
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			w.streamHistoryEntries = append(w.streamHistoryEntries, e)
+
+			// Omit the date for today's entries
+			t := e.Time.Local()
+			layout := "2006-01-02 15:04"
+			if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+				layout = "15:04"
+			}
+			errCheck(
+				w.StreamHistoryListStore.InsertWithValues(
+					nil,
+					-1,
+					[]int{streamHistoryColTime, streamHistoryColTitle},
+					[]interface{}{t.Format(layout), e.Title}),
+				"StreamHistoryListStore.InsertWithValues() failed")
+		}
+	}
+
+	// Update info
+	var info string
+	switch n := len(w.streamHistoryEntries); {
+	case idx < 0:
+		info = glib.Local("No stream selected")
+	case n == 0:
+		info = glib.Local("No titles recorded yet")
+	default:
+		info = fmt.Sprintf(glib.Local("%d titles"), n)
+	}
+	w.StreamHistoryInfoLabel.SetText(info)
+	w.updateStreamHistoryActions()
+}
+
+// updateStreamHistoryActions updates the actions of the stream history panel
+func (w *MainWindow) updateStreamHistoryActions() {
+	selected := w.StreamHistoryTreeSelection.CountSelectedRows() > 0
+	w.aStreamHistoryCopy.SetEnabled(selected)
+	w.aStreamHistorySearch.SetEnabled(selected)
+	w.aStreamHistoryExport.SetEnabled(len(w.streamHistoryEntries) > 0)
+	w.aStreamHistoryClear.SetEnabled(len(w.streamHistoryEntries) > 0)
+}
+
+// updateStreamHistoryPanel shows or hides the stream history panel according to the configuration
+func (w *MainWindow) updateStreamHistoryPanel() {
+	show := config.GetConfig().StreamsHistory
+	w.streamHistoryUpdating = true
+	w.StreamsHistoryToolButton.SetActive(show)
+	w.streamHistoryUpdating = false
+	w.StreamHistoryRevealer.SetRevealChild(show)
+}
@@ -0,0 +1,93 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/yktoo/ymuse/internal/config"
+	"sort"
+	"strings"
+)
+
+// streamsFavouritesGroupID is the ID of the group of favourite streams, which can't clash with a group name
+const streamsFavouritesGroupID = "\u0001favourites"
+
+// StreamGroup is a group of streams displayed together on the Streams page
+type StreamGroup struct {
+	ID      string // Group ID: the group name, streamsFavouritesGroupID for favourites or an empty string for ungrouped streams
+	Indices []int  // Indices of the group's streams in the stream list, ordered by name
+}
+
+// GroupStreams groups the streams matching the given search query, pinning favourites on top and placing ungrouped
+// streams last. Groups are ordered by name, and empty groups are omitted
+func GroupStreams(streams []config.StreamSpec, query string) []StreamGroup {
+	words := strings.Fields(strings.ToLower(query))
+	var favourites, ungrouped StreamGroup
+	favourites.ID = streamsFavouritesGroupID
+	groups := make(map[string]*StreamGroup)
+	for i, s := range streams {
+		if !StreamMatches(s, words) {
+			continue
+		}
+		g := &ungrouped
+		switch {
+		case s.Favourite:
+			g = &favourites
+		case s.Group != "":
+			if g = groups[s.Group]; g == nil {
+				g = &StreamGroup{ID: s.Group}
+				groups[s.Group] = g
+			}
+		}
+		g.Indices = append(g.Indices, i)
+	}
+
+	// Sort named groups by name
+	named := make([]StreamGroup, 0, len(groups))
+	for _, g := range groups {
+		named = append(named, *g)
+	}
+	sort.Slice(named, func(i, j int) bool {
+		a, b := strings.ToUpper(named[i].ID), strings.ToUpper(named[j].ID)
+		return a < b || a == b && named[i].ID < named[j].ID
+	})
+
+	// Sort streams in every group by name, and assemble the result
+	var result []StreamGroup
+	for _, g := range append(append([]StreamGroup{favourites}, named...), ungrouped) {
+		if len(g.Indices) == 0 {
+			continue
+		}
+		sort.SliceStable(g.Indices, func(i, j int) bool {
+			return strings.ToUpper(streams[g.Indices[i]].Name) < strings.ToUpper(streams[g.Indices[j]].Name)
+		})
+		result = append(result, g)
+	}
+	return result
+}
+
+// StreamMatches returns whether the stream's name, URI, group or tags contain every one of the given lowercase words
+func StreamMatches(s config.StreamSpec, words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join(append([]string{s.Name, s.URI, s.Group}, s.Tags...), "\n"))
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
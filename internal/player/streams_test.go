@@ -0,0 +1,65 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"github.com/yktoo/ymuse/internal/config"
+	"reflect"
+	"testing"
+)
+
+func TestGroupStreams(t *testing.T) {
+	streams := []config.StreamSpec{
+		{Name: "Jazz FM", URI: "http://jazz", Group: "Music", Tags: []string{"smooth"}},
+		{Name: "BBC World", URI: "http://bbc", Group: "news"},
+		{Name: "ambient", URI: "http://ambient", Group: "Music", Favourite: true},
+		{Name: "Local", URI: "http://local"},
+		{Name: "Classic", URI: "http://classic", Group: "Music"},
+		{Name: "CNN", URI: "http://cnn", Group: "news", Tags: []string{"talk", "us"}},
+		{Name: "Alpha", URI: "http://alpha", Favourite: true},
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []StreamGroup
+	}{
+		{"no query", "", []StreamGroup{
+			{ID: streamsFavouritesGroupID, Indices: []int{6, 2}},
+			{ID: "Music", Indices: []int{4, 0}},
+			{ID: "news", Indices: []int{1, 5}},
+			{ID: "", Indices: []int{3}},
+		}},
+		{"by name", "jazz", []StreamGroup{{ID: "Music", Indices: []int{0}}}},
+		{"by tag", "TALK", []StreamGroup{{ID: "news", Indices: []int{5}}}},
+		{"by group", "music", []StreamGroup{
+			{ID: streamsFavouritesGroupID, Indices: []int{2}},
+			{ID: "Music", Indices: []int{4, 0}},
+		}},
+		{"by URI", "http://local", []StreamGroup{{ID: "", Indices: []int{3}}}},
+		{"all words", "news us", []StreamGroup{{ID: "news", Indices: []int{5}}}},
+		{"no match", "rock", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupStreams(streams, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupStreams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := GroupStreams(nil, ""); got != nil {
+		t.Errorf("GroupStreams(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package player
+
+import (
+	"bytes"
+	"github.com/fhs/gompd/v2/mpd"
+	"github.com/gotk3/gotk3/glib"
+	"github.com/yktoo/ymuse/internal/config"
+	"github.com/yktoo/ymuse/internal/notify"
+	"github.com/yktoo/ymuse/internal/tray"
+	"github.com/yktoo/ymuse/internal/util"
+	"html"
+	"path"
+	"strings"
+)
+
+// Volume change per step of scrolling over the tray icon
+const trayVolumeStep = 5
+
+// onTrayActivate shows or hides the window on clicking the tray icon. Called from a separate goroutine
+func (w *MainWindow) onTrayActivate() {
+	glib.IdleAdd(w.windowToggle)
+}
+
+// onTrayScroll changes the volume on scrolling over the tray icon. Called from a separate goroutine
+func (w *MainWindow) onTrayScroll(delta int) {
+	glib.IdleAdd(func() {
+		// Scrolling up raises the volume; the adjustment takes care of the limits and of applying the value
+		step := trayVolumeStep
+		if delta < 0 {
+			step = -step
+		}
+		w.VolumeAdjustment.SetValue(w.VolumeAdjustment.GetValue() + float64(step))
+	})
+}
+
+// trayMenuAction returns a tray menu callback activating the given action
+func (w *MainWindow) trayMenuAction(action *glib.SimpleAction) func() {
+	return func() {
+		glib.IdleAdd(func() { action.Activate(nil) })
+	}
+}
+
+// updateTray updates the tooltip of the tray icon, describing the given current song, and the tray menu
+func (w *MainWindow) updateTray(connected bool, status, song mpd.Attrs) {
+	if w.trayIcon == nil {
+		return
+	}
+
+	// Describe the song with the notification template, falling back to the track title
+	title, body := config.AppMetadata.Name, ""
+	state := status["state"]
+	switch {
+	case !connected:
+		body = html.EscapeString(glib.Local("Not connected to MPD"))
+	case song == nil || state == "stop":
+		body = html.EscapeString(glib.Local("Stopped"))
+	default:
+		title = util.Default(path.Base(song["file"]), song["Title"])
+		var buffer bytes.Buffer
+		if w.notifyTemplate != nil && !errCheck(w.notifyTemplate.Execute(&buffer, song), "Notification template error") {
+			if summary, text := notify.SplitText(buffer.String()); summary != "" {
+				title, body = notify.StripMarkup(summary), text
+			}
+		}
+		if state == "pause" {
+			body = strings.TrimSpace(body + "\n<i>" + html.EscapeString(glib.Local("Paused")) + "</i>")
+		}
+	}
+	w.trayIcon.SetToolTip(title, body)
+	w.updateTrayMenu()
+}
+
+// updateTrayMenu updates the items of the tray menu
+func (w *MainWindow) updateTrayMenu() {
+	if w.trayIcon == nil {
+		return
+	}
+	playPause := tray.MenuItem{Label: glib.Local("_Play"), IconName: "media-playback-start"}
+	if w.connector.Status()["state"] == "play" {
+		playPause = tray.MenuItem{Label: glib.Local("P_ause"), IconName: "media-playback-pause"}
+	}
+	playPause.Disabled = !w.aPlayerPlayPause.GetEnabled()
+	playPause.OnClick = w.trayMenuAction(w.aPlayerPlayPause)
+	window := glib.Local("_Show window")
+	if w.AppWindow.GetVisible() {
+		window = glib.Local("_Hide window")
+	}
+	w.trayIcon.SetMenu([]tray.MenuItem{
+		playPause,
+		{
+			Label:    glib.Local("Pre_vious"),
+			IconName: "media-skip-backward",
+			Disabled: !w.aPlayerPrevious.GetEnabled(),
+			OnClick:  w.trayMenuAction(w.aPlayerPrevious),
+		},
+		{
+			Label:    glib.Local("_Next"),
+			IconName: "media-skip-forward",
+			Disabled: !w.aPlayerNext.GetEnabled(),
+			OnClick:  w.trayMenuAction(w.aPlayerNext),
+		},
+		{Separator: true},
+		{Label: window, OnClick: func() { glib.IdleAdd(w.windowToggle) }},
+		{Separator: true},
+		{Label: glib.Local("_Quit"), IconName: "application-exit", OnClick: func() { glib.IdleAdd(w.quit) }},
+	})
+}
@@ -0,0 +1,178 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package playhistory keeps a local record of the tracks played by MPD and computes listening statistics
+package playhistory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Play is a single playback of a track
+type Play struct {
+	URI         string    `json:"uri"`                   // Track URI
+	Artist      string    `json:"artist,omitempty"`      // Track artist
+	AlbumArtist string    `json:"albumArtist,omitempty"` // Album artist
+	Album       string    `json:"album,omitempty"`       // Album title
+	Title       string    `json:"title,omitempty"`       // Track title
+	Genre       string    `json:"genre,omitempty"`       // Track genre
+	Duration    int       `json:"duration,omitempty"`    // Track duration in seconds, 0 if unknown
+	Start       time.Time `json:"start"`                 // Time the playback started
+	Listened    int       `json:"listened"`              // Number of seconds the track's been actually played for
+	Completed   bool      `json:"completed"`             // Whether the track was played till the end, as opposed to skipped
+}
+
+// Store is a persistent list of plays, kept in a JSON Lines file that new plays are appended to
+type Store struct {
+	file  string     // Full path of the file the plays are stored in
+	mutex sync.Mutex // Plays access mutex
+	plays []Play     // Recorded plays, oldest first
+}
+
+// Open creates a new Store instance and reads the plays from the given file. A missing file results in an empty store.
+// Lines that can't be parsed are skipped, and on a read error the store is still usable
+func Open(file string) (*Store, error) {
+	s := &Store{file: file}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var p Play
+		if errCheck(json.Unmarshal(scanner.Bytes(), &p), "Failed to parse play history line "+strconv.Itoa(line)) {
+			continue
+		}
+		s.plays = append(s.plays, p)
+	}
+	return s, scanner.Err()
+}
+
+// Add records the given play and appends it to the store file
+func (s *Store) Add(p Play) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.plays = append(s.plays, p)
+	if err := os.MkdirAll(filepath.Dir(s.file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Clear removes all plays from the store and its file
+func (s *Store) Clear() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.plays = nil
+	if err := os.Remove(s.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Len returns the number of recorded plays
+func (s *Store) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.plays)
+}
+
+// Plays returns a copy of all recorded plays, oldest first
+func (s *Store) Plays() []Play {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Play(nil), s.plays...)
+}
+
+// Since returns the plays started at or after the given time, oldest first. A zero time returns all plays
+func Since(plays []Play, t time.Time) []Play {
+	var res []Play
+	for _, p := range plays {
+		if !p.Start.Before(t) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// WriteCSV writes the given plays out in the CSV format, with a header row
+func WriteCSV(w io.Writer, plays []Play) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"Start", "URI", "Artist", "Album Artist", "Album", "Title", "Genre", "Duration", "Listened", "Completed",
+	}); err != nil {
+		return err
+	}
+	for _, p := range plays {
+		if err := cw.Write([]string{
+			p.Start.Format(time.RFC3339),
+			p.URI,
+			p.Artist,
+			p.AlbumArtist,
+			p.Album,
+			p.Title,
+			p.Genre,
+			strconv.Itoa(p.Duration),
+			strconv.Itoa(p.Listened),
+			strconv.FormatBool(p.Completed),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the given plays out as an indented JSON array
+func WriteJSON(w io.Writer, plays []Play) error {
+	if plays == nil {
+		plays = []Play{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plays)
+}
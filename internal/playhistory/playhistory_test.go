@@ -0,0 +1,152 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playhistory
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "plays.jsonl")
+
+	// Opening a missing file gives an empty store
+	s, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := s.Plays(); len(got) != 0 {
+		t.Errorf("Plays() = %v, want none", got)
+	}
+
+	// Add plays and read them back
+	plays := []Play{
+		{URI: "a.flac", Artist: "Artist", Title: "One", Duration: 200, Start: t0, Listened: 200, Completed: true},
+		{URI: "b.flac", Artist: "Artist", Title: "Two", Start: t0.Add(time.Hour), Listened: 12},
+	}
+	for _, p := range plays {
+		if err := s.Add(p); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if got := s.Plays(); !reflect.DeepEqual(got, plays) {
+		t.Errorf("Plays() = %v, want %v", got, plays)
+	}
+	if got := s.Len(); got != len(plays) {
+		t.Errorf("Len() = %d, want %d", got, len(plays))
+	}
+	s2, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := s2.Plays(); !reflect.DeepEqual(got, plays) {
+		t.Errorf("Plays() after Open() = %v, want %v", got, plays)
+	}
+
+	// Broken lines, e.g. a partially written one, are skipped
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("\n{\"uri\":\"c.fl")
+	_ = f.Close()
+	s3, err := Open(file)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := s3.Plays(); !reflect.DeepEqual(got, plays) {
+		t.Errorf("Plays() with a broken line = %v, want %v", got, plays)
+	}
+
+	// Clear the store
+	if err := s3.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("file exists after Clear(), error = %v", err)
+	}
+	if got := s3.Plays(); len(got) != 0 {
+		t.Errorf("Plays() after Clear() = %v, want none", got)
+	}
+}
+
+func TestSince(t *testing.T) {
+	plays := []Play{{URI: "a", Start: t0}, {URI: "b", Start: t0.Add(time.Hour)}, {URI: "c", Start: t0.Add(2 * time.Hour)}}
+	tests := []struct {
+		name string
+		t    time.Time
+		want []Play
+	}{
+		{"zero", time.Time{}, plays},
+		{"exact", t0.Add(time.Hour), plays[1:]},
+		{"between", t0.Add(time.Minute), plays[1:]},
+		{"after all", t0.Add(3 * time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Since(plays, tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Since() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	plays := []Play{
+		{URI: "a.flac", Artist: "Artist", Album: "Album, Vol. 1", Title: "One", Genre: "Jazz", Duration: 200, Start: t0, Listened: 198, Completed: true},
+		{URI: "http://radio", Title: `Quoted "Title"`, Start: t0.Add(90 * time.Second), Listened: 30},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, plays); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "Start,URI,Artist,Album Artist,Album,Title,Genre,Duration,Listened,Completed\n" +
+		"2026-03-01T12:00:00Z,a.flac,Artist,,\"Album, Vol. 1\",One,Jazz,200,198,true\n" +
+		"2026-03-01T12:01:30Z,http://radio,,,,\"Quoted \"\"Title\"\"\",,0,30,false\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		plays []Play
+		want  string
+	}{
+		{"none", nil, "[]\n"},
+		{"one", []Play{{URI: "a.flac", Title: "One", Start: t0, Listened: 5}},
+			"[\n  {\n    \"uri\": \"a.flac\",\n    \"title\": \"One\",\n    \"start\": \"2026-03-01T12:00:00Z\",\n" +
+				"    \"listened\": 5,\n    \"completed\": false\n  }\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteJSON(&buf, tt.plays); err != nil {
+				t.Fatalf("WriteJSON() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playhistory
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Category is what plays are grouped by in statistics
+type Category string
+
+const (
+	CategoryArtist Category = "artist"
+	CategoryAlbum  Category = "album"
+	CategoryGenre  Category = "genre"
+)
+
+// Period is a time span statistics are computed for
+type Period string
+
+const (
+	PeriodToday Period = "today"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+	PeriodYear  Period = "year"
+	PeriodAll   Period = "all"
+)
+
+// StatItem is a statistics entry for a single artist, album or genre
+type StatItem struct {
+	Name      string        // Artist, album or genre name
+	Detail    string        // Album artist for albums, empty otherwise
+	Plays     int           // Number of plays
+	Completed int           // Number of plays that weren't skipped
+	Listened  time.Duration // Total time listened
+}
+
+// PeriodStart returns the start of the given period ending at the given time. Today starts at local midnight, other
+// periods span the given number of days back; PeriodAll, as well as an unknown period, yields a zero time
+func PeriodStart(period Period, now time.Time) time.Time {
+	days := 0
+	switch period {
+	case PeriodToday:
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	case PeriodWeek:
+		days = 7
+	case PeriodMonth:
+		days = 30
+	case PeriodYear:
+		days = 365
+	default:
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -days)
+}
+
+// Stats groups the given plays by the given category, and returns the groups ordered by the number of plays, then by
+// the time listened, descending. Plays lacking the grouping tag are left out
+func Stats(plays []Play, category Category) []StatItem {
+	type key struct{ name, detail string }
+	groups := make(map[key]*StatItem)
+	for _, p := range plays {
+		var k key
+		switch category {
+		case CategoryArtist:
+			k.name = p.Artist
+		case CategoryAlbum:
+			k.name, k.detail = p.Album, p.AlbumArtist
+			if k.detail == "" {
+				k.detail = p.Artist
+			}
+		case CategoryGenre:
+			k.name = p.Genre
+		}
+		if strings.TrimSpace(k.name) == "" {
+			continue
+		}
+		item := groups[k]
+		if item == nil {
+			item = &StatItem{Name: k.name, Detail: k.detail}
+			groups[k] = item
+		}
+		item.Plays++
+		if p.Completed {
+			item.Completed++
+		}
+		item.Listened += time.Duration(p.Listened) * time.Second
+	}
+
+	// Sort the groups
+	res := make([]StatItem, 0, len(groups))
+	for _, item := range groups {
+		res = append(res, *item)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		switch {
+		case a.Plays != b.Plays:
+			return a.Plays > b.Plays
+		case a.Listened != b.Listened:
+			return a.Listened > b.Listened
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		}
+		return a.Detail < b.Detail
+	})
+	return res
+}
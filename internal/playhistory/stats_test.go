@@ -0,0 +1,81 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playhistory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period Period
+		want   time.Time
+	}{
+		{PeriodToday, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeek, time.Date(2026, 3, 3, 15, 30, 0, 0, time.UTC)},
+		{PeriodMonth, time.Date(2026, 2, 8, 15, 30, 0, 0, time.UTC)},
+		{PeriodYear, time.Date(2025, 3, 10, 15, 30, 0, 0, time.UTC)},
+		{PeriodAll, time.Time{}},
+		{"bogus", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			if got := PeriodStart(tt.period, now); !got.Equal(tt.want) {
+				t.Errorf("PeriodStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	plays := []Play{
+		{Artist: "B", Album: "X", Genre: "Rock", Listened: 100, Completed: true},
+		{Artist: "A", AlbumArtist: "Various", Album: "Y", Genre: "Jazz", Listened: 50},
+		{Artist: "B", Album: "X", Genre: "Rock", Listened: 200, Completed: true},
+		{Artist: "C", AlbumArtist: "Various", Album: "Y", Listened: 300, Completed: true},
+		{Artist: "C", Album: "Y", Genre: "Jazz", Listened: 10},
+		{Title: "Untagged", Listened: 1000},
+	}
+	tests := []struct {
+		category Category
+		want     []StatItem
+	}{
+		{CategoryArtist, []StatItem{
+			{Name: "C", Plays: 2, Completed: 1, Listened: 310 * time.Second},
+			{Name: "B", Plays: 2, Completed: 2, Listened: 300 * time.Second},
+			{Name: "A", Plays: 1, Completed: 0, Listened: 50 * time.Second},
+		}},
+		{CategoryAlbum, []StatItem{
+			{Name: "Y", Detail: "Various", Plays: 2, Completed: 1, Listened: 350 * time.Second},
+			{Name: "X", Detail: "B", Plays: 2, Completed: 2, Listened: 300 * time.Second},
+			{Name: "Y", Detail: "C", Plays: 1, Completed: 0, Listened: 10 * time.Second},
+		}},
+		{CategoryGenre, []StatItem{
+			{Name: "Rock", Plays: 2, Completed: 2, Listened: 300 * time.Second},
+			{Name: "Jazz", Plays: 2, Completed: 0, Listened: 60 * time.Second},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.category), func(t *testing.T) {
+			if got := Stats(plays, tt.category); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playhistory
+
+import (
+	"github.com/yktoo/ymuse/internal/playtime"
+	"sync"
+	"time"
+)
+
+const (
+	// A track whose playback got this close to its end is considered completed rather than skipped
+	completedMargin = 10 * time.Second
+	// Plays shorter than this aren't recorded
+	minListened = time.Second
+)
+
+// Tracker follows the player state and records a play in the store every time a track stops playing
+type Tracker struct {
+	store    *Store           // Store plays are recorded in
+	onRecord func(Play)       // Callback invoked after a play's been recorded, can be nil
+	now      func() time.Time // Returns the current time, replaceable for testing
+
+	mutex    sync.Mutex       // Protects the fields below
+	counter  playtime.Counter // Accounts for the time played
+	cur      *Play            // Current play, nil if none
+	listened time.Duration    // Time the current track's been played for
+}
+
+// NewTracker creates and returns a new Tracker instance recording plays in the given store
+func NewTracker(store *Store, onRecord func(Play)) *Tracker {
+	return &Tracker{store: store, onRecord: onRecord, now: time.Now}
+}
+
+// Finish records the current play, if any, and forgets it. To be called when the player state is no longer known,
+// for instance on disconnecting from MPD
+func (t *Tracker) Finish() {
+	t.mutex.Lock()
+	p := t.finish(t.counter.Elapsed())
+	t.counter.Reset()
+	t.mutex.Unlock()
+	t.record(p)
+}
+
+// Update processes the current player state: the ID of the current song in the queue (empty if none), the play
+// template describing the song, whether it's playing and the playback position. It's supposed to be called on every
+// player change and also periodically during playback
+func (t *Tracker) Update(songID string, song Play, playing bool, elapsed time.Duration) {
+	now := t.now()
+	t.mutex.Lock()
+
+	// Account for the time played since the last update
+	lastElapsed := t.counter.Elapsed()
+	progress := t.counter.Update(now, songID, time.Duration(song.Duration)*time.Second, playing, elapsed)
+	if t.cur != nil {
+		t.listened += progress.Played
+	}
+
+	// Finish the current play if the song changed or was restarted, and start a new one
+	var finished *Play
+	if progress.Changed {
+		finished = t.finish(lastElapsed)
+		if songID != "" {
+			t.cur = &song
+			t.cur.Start = now.Add(-elapsed)
+			t.listened = progress.Carried
+		}
+	}
+	t.mutex.Unlock()
+	t.record(finished)
+}
+
+// finish completes the current play, last seen at the given playback position, and returns it, or nil if there's
+// nothing worth recording. Must be called with the mutex locked
+func (t *Tracker) finish(lastElapsed time.Duration) *Play {
+	p := t.cur
+	listened := t.listened
+	t.cur, t.listened = nil, 0
+	if p == nil || listened < minListened {
+		return nil
+	}
+	p.Listened = int(listened.Round(time.Second).Seconds())
+
+	// Tracks of unknown duration, such as streams, can't be skipped as such
+	p.Completed = p.Duration <= 0 || lastElapsed >= time.Duration(p.Duration)*time.Second-completedMargin
+	return p
+}
+
+// record stores the given play, if any, and notifies the callback
+func (t *Tracker) record(p *Play) {
+	if p == nil {
+		return
+	}
+	if !errCheck(t.store.Add(*p), "Failed to record play") && t.onRecord != nil {
+		t.onRecord(*p)
+	}
+}
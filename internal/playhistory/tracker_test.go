@@ -0,0 +1,108 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playhistory
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// trackerStep is a player state passed to the tracker at a certain time
+type trackerStep struct {
+	at      time.Duration // Time offset from t0
+	songID  string
+	playing bool
+	elapsed time.Duration
+}
+
+func TestTracker(t *testing.T) {
+	song := func(id string) Play { return Play{URI: id + ".flac", Title: id, Duration: 100} }
+	tests := []struct {
+		name  string
+		steps []trackerStep
+		want  []Play
+	}{
+		{"played to the end",
+			[]trackerStep{{0, "A", true, 0}, {50 * time.Second, "A", true, 50 * time.Second}, {99 * time.Second, "A", true, 99 * time.Second}, {100 * time.Second, "B", true, 0}},
+			[]Play{{URI: "A.flac", Title: "A", Duration: 100, Start: t0, Listened: 100, Completed: true}}},
+		{"skipped",
+			[]trackerStep{{0, "A", true, 0}, {30 * time.Second, "A", true, 30 * time.Second}, {31 * time.Second, "B", true, 0}},
+			[]Play{{URI: "A.flac", Title: "A", Duration: 100, Start: t0, Listened: 31}}},
+		{"joined midway",
+			[]trackerStep{{0, "A", true, 60 * time.Second}, {35 * time.Second, "A", true, 95 * time.Second}, {40 * time.Second, "", false, 0}},
+			[]Play{{URI: "A.flac", Title: "A", Duration: 100, Start: t0.Add(-time.Minute), Listened: 40, Completed: true}}},
+		{"paused",
+			[]trackerStep{{0, "A", true, 0}, {10 * time.Second, "A", false, 10 * time.Second}, {time.Hour, "A", true, 10 * time.Second}, {time.Hour + 5*time.Second, "B", true, 0}},
+			[]Play{{URI: "A.flac", Title: "A", Duration: 100, Start: t0, Listened: 15}}},
+		{"restarted",
+			[]trackerStep{{0, "A", true, 0}, {20 * time.Second, "A", true, 20 * time.Second}, {21 * time.Second, "A", true, time.Second}, {40 * time.Second, "B", true, 0}},
+			[]Play{
+				{URI: "A.flac", Title: "A", Duration: 100, Start: t0, Listened: 20},
+				{URI: "A.flac", Title: "A", Duration: 100, Start: t0.Add(20 * time.Second), Listened: 20},
+			}},
+		{"too short",
+			[]trackerStep{{0, "A", true, 0}, {500 * time.Millisecond, "B", true, 0}},
+			nil},
+		{"never played",
+			[]trackerStep{{0, "A", false, 0}, {time.Minute, "B", false, 0}},
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := Open(filepath.Join(t.TempDir(), "plays.jsonl"))
+			var recorded []Play
+			tr := NewTracker(store, func(p Play) { recorded = append(recorded, p) })
+			for _, step := range tt.steps {
+				tr.now = func() time.Time { return t0.Add(step.at) }
+				var p Play
+				if step.songID != "" {
+					p = song(step.songID)
+				}
+				tr.Update(step.songID, p, step.playing, step.elapsed)
+			}
+			if !reflect.DeepEqual(recorded, tt.want) {
+				t.Errorf("recorded plays = %+v, want %+v", recorded, tt.want)
+			}
+			if got := store.Plays(); len(got) != len(tt.want) {
+				t.Errorf("Plays() = %+v, want %d plays", got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestTracker_Finish(t *testing.T) {
+	store, _ := Open(filepath.Join(t.TempDir(), "plays.jsonl"))
+	tr := NewTracker(store, nil)
+	tr.now = func() time.Time { return t0 }
+	tr.Update("1", Play{URI: "http://radio"}, true, 0)
+	tr.now = func() time.Time { return t0.Add(time.Minute) }
+	tr.Update("1", Play{URI: "http://radio"}, true, time.Minute)
+
+	// Plays of unknown duration count as completed
+	tr.Finish()
+	want := []Play{{URI: "http://radio", Start: t0, Listened: 60, Completed: true}}
+	if got := store.Plays(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Plays() = %+v, want %+v", got, want)
+	}
+
+	// Nothing is left to record
+	tr.Finish()
+	if got := store.Plays(); len(got) != 1 {
+		t.Errorf("Plays() after second Finish() = %+v, want 1 play", got)
+	}
+}
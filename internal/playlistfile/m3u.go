@@ -0,0 +1,93 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playlistfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	m3uHeader = "#EXTM3U"
+	m3uInfo   = "#EXTINF:"
+)
+
+// ReadM3U parses a playlist in the (extended) M3U format
+func ReadM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	name := ""
+	scanner := bufio.NewScanner(r)
+	for first := true; scanner.Scan(); first = false {
+		s := strings.TrimSpace(scanner.Text())
+		if first {
+			s = strings.TrimPrefix(s, "\ufeff")
+		}
+		switch {
+		case s == "":
+			continue
+
+		// Entry info: "#EXTINF:<duration> [<attributes>],<title>"
+		case strings.HasPrefix(s, m3uInfo):
+			name = m3uInfoTitle(s[len(m3uInfo):])
+
+		// Skip the header and other directives or comments
+		case strings.HasPrefix(s, "#"):
+			continue
+
+		// Entry location, which the preceding info refers to
+		default:
+			entries = append(entries, Entry{Name: name, URI: s})
+			name = ""
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// WriteM3U outputs a playlist in the extended M3U format
+func WriteM3U(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintln(bw, m3uHeader)
+	for _, e := range entries {
+		if e.Name != "" {
+			_, _ = fmt.Fprintf(bw, "%s-1,%s\n", m3uInfo, oneLine(e.Name))
+		}
+		_, _ = fmt.Fprintln(bw, oneLine(e.URI))
+	}
+	return bw.Flush()
+}
+
+// m3uInfoTitle extracts the title from an entry info, which follows the first comma outside quoted attribute values
+func m3uInfoTitle(info string) string {
+	quoted := false
+	for i, c := range info {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return strings.TrimSpace(info[i+1:])
+			}
+		}
+	}
+	return ""
+}
+
+// oneLine replaces line breaks in the given string with spaces
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,133 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package playlistfile reads and writes playlist files in the M3U, PLS and XSPF formats
+package playlistfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry is a single playlist entry
+type Entry struct {
+	Name string // Entry title, may be empty
+	URI  string // Entry location
+}
+
+// Format is a playlist file format
+type Format int
+
+const (
+	FormatUnknown Format = iota // Unrecognised format
+	FormatM3U                   // (Extended) M3U, including its UTF-8 variant M3U8
+	FormatPLS                   // PLS (Winamp/Shoutcast)
+	FormatXSPF                  // XML Shareable Playlist Format
+)
+
+// Formats lists all supported formats
+var Formats = []Format{FormatM3U, FormatPLS, FormatXSPF}
+
+// String returns the human-readable name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatM3U:
+		return "M3U"
+	case FormatPLS:
+		return "PLS"
+	case FormatXSPF:
+		return "XSPF"
+	}
+	return "unknown"
+}
+
+// Extensions returns the file name extensions (including the leading dot) of the format, the preferred one first
+func (f Format) Extensions() []string {
+	switch f {
+	case FormatM3U:
+		return []string{".m3u", ".m3u8"}
+	case FormatPLS:
+		return []string{".pls"}
+	case FormatXSPF:
+		return []string{".xspf"}
+	}
+	return nil
+}
+
+// FormatOf returns the format of the file with the given name, based on its extension
+func FormatOf(fileName string) Format {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, f := range Formats {
+		for _, e := range f.Extensions() {
+			if e == ext {
+				return f
+			}
+		}
+	}
+	return FormatUnknown
+}
+
+// Read parses a playlist in the given format
+func Read(r io.Reader, format Format) ([]Entry, error) {
+	switch format {
+	case FormatM3U:
+		return ReadM3U(r)
+	case FormatPLS:
+		return ReadPLS(r)
+	case FormatXSPF:
+		return ReadXSPF(r)
+	}
+	return nil, fmt.Errorf("unsupported playlist format: %v", format)
+}
+
+// Write outputs a playlist in the given format
+func Write(w io.Writer, format Format, entries []Entry) error {
+	switch format {
+	case FormatM3U:
+		return WriteM3U(w, entries)
+	case FormatPLS:
+		return WritePLS(w, entries)
+	case FormatXSPF:
+		return WriteXSPF(w, entries)
+	}
+	return fmt.Errorf("unsupported playlist format: %v", format)
+}
+
+// ReadFile parses the playlist file with the given name, whose format is determined by its extension
+func ReadFile(fileName string) ([]Entry, error) {
+	format := FormatOf(fileName)
+	if format == FormatUnknown {
+		return nil, fmt.Errorf("unsupported playlist file type: %s", filepath.Base(fileName))
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f, format)
+}
+
+// WriteFile saves a playlist in the given format to a file with the given name
+func WriteFile(fileName string, format Format, entries []Entry) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, format, entries); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
+}
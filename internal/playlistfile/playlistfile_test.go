@@ -0,0 +1,163 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playlistfile
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		want     Format
+	}{
+		{"m3u", "radio.m3u", FormatM3U},
+		{"m3u8", "/tmp/radio.M3U8", FormatM3U},
+		{"pls", "radio.pls", FormatPLS},
+		{"xspf", "dir.d/radio.xspf", FormatXSPF},
+		{"unknown", "radio.txt", FormatUnknown},
+		{"no extension", "radio", FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatOf(tt.fileName); got != tt.want {
+				t.Errorf("FormatOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		text    string
+		want    []Entry
+		wantErr bool
+	}{
+		{"m3u empty", FormatM3U, "", nil, false},
+		{"m3u plain", FormatM3U, "http://a/1\n\nhttp://a/2\n", []Entry{{URI: "http://a/1"}, {URI: "http://a/2"}}, false},
+		{"m3u extended", FormatM3U,
+			"\ufeff#EXTM3U\r\n#EXTINF:-1 tvg-name=\"x,y\",Radio One\r\n# comment\r\nhttp://a/1\r\n#EXTINF:123\r\nhttp://a/2\r\n",
+			[]Entry{{Name: "Radio One", URI: "http://a/1"}, {URI: "http://a/2"}}, false},
+		{"pls", FormatPLS,
+			"[playlist]\nFile2=http://a/2\nTitle1=Radio One\nfile1=http://a/1\nLength1=-1\nTitle3=Orphan\nNumberOfEntries=2\nVersion=2\n",
+			[]Entry{{Name: "Radio One", URI: "http://a/1"}, {URI: "http://a/2"}}, false},
+		{"pls other sections", FormatPLS,
+			"; comment\n[other]\nFile1=http://x\n[Playlist]\nFile1=http://a/1\n",
+			[]Entry{{URI: "http://a/1"}}, false},
+		{"pls empty playlist", FormatPLS, "[playlist]\nNumberOfEntries=0\n", nil, false},
+		{"pls invalid", FormatPLS, "File1=http://a/1\n", nil, true},
+		{"xspf", FormatXSPF, `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title>Ignored</title>
+  <trackList>
+    <track><location> http://a/1 </location><location>http://b/1</location><title>Radio One</title></track>
+    <track><title>No location</title></track>
+    <track><location>http://a/2</location></track>
+  </trackList>
+</playlist>`,
+			[]Entry{{Name: "Radio One", URI: "http://a/1"}, {URI: "http://a/2"}}, false},
+		{"xspf no namespace", FormatXSPF, `<playlist version="1"><trackList><track><location>http://a/1</location></track></trackList></playlist>`,
+			[]Entry{{URI: "http://a/1"}}, false},
+		{"xspf wrong namespace", FormatXSPF, `<playlist xmlns="urn:other"><trackList/></playlist>`, nil, true},
+		{"xspf wrong root", FormatXSPF, `<html></html>`, nil, true},
+		{"xspf malformed", FormatXSPF, `<playlist><trackList>`, nil, true},
+		{"unknown format", FormatUnknown, "http://a/1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.text), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{{Name: "Radio One", URI: "http://a/1"}, {URI: "http://a/2"}}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatM3U, "#EXTM3U\n#EXTINF:-1,Radio One\nhttp://a/1\nhttp://a/2\n"},
+		{FormatPLS, "[playlist]\nFile1=http://a/1\nTitle1=Radio One\nLength1=-1\nFile2=http://a/2\nLength2=-1\nNumberOfEntries=2\nVersion=2\n"},
+		{FormatXSPF, `<?xml version="1.0" encoding="UTF-8"?>
+<playlist xmlns="http://xspf.org/ns/0/" version="1">
+  <trackList>
+    <track>
+      <location>http://a/1</location>
+      <title>Radio One</title>
+    </track>
+    <track>
+      <location>http://a/2</location>
+    </track>
+  </trackList>
+</playlist>
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, entries); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Name: "Radio One", URI: "http://a/1"},
+		{URI: "https://a/2?x=1&y=2"},
+		{Name: "Ünïcödé, <special> & \"quoted\"", URI: "http://a/3;stream"},
+		{Name: "Multi\nline", URI: "http://a/4"},
+	}
+	// Line breaks only survive in XSPF
+	oneLine := append([]Entry(nil), entries...)
+	oneLine[3].Name = "Multi line"
+	for _, format := range Formats {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, entries); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := Read(&buf, format)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			want := oneLine
+			if format == FormatXSPF {
+				want = entries
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Read() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playlistfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const plsSection = "[playlist]"
+
+// ReadPLS parses a playlist in the PLS format
+func ReadPLS(r io.Reader) ([]Entry, error) {
+	// Collect files and titles by their entry number, which don't need to be contiguous or ordered
+	files, titles := make(map[int]string), make(map[int]string)
+	inSection := false
+	scanner := bufio.NewScanner(r)
+	for first := true; scanner.Scan(); first = false {
+		s := strings.TrimSpace(scanner.Text())
+		if first {
+			s = strings.TrimPrefix(s, "\ufeff")
+		}
+		switch {
+		case s == "", strings.HasPrefix(s, ";"):
+			continue
+		case strings.HasPrefix(s, "["):
+			inSection = strings.EqualFold(s, plsSection)
+			continue
+		case !inSection:
+			continue
+		}
+
+		// Parse a "<Key><N>=<value>" line
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		var m map[int]string
+		switch {
+		case strings.HasPrefix(key, "file"):
+			key, m = key[4:], files
+		case strings.HasPrefix(key, "title"):
+			key, m = key[5:], titles
+		default:
+			continue
+		}
+		if n, err := strconv.Atoi(key); err == nil {
+			m[n] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !inSection && len(files) == 0 {
+		return nil, errors.New("not a PLS playlist: no [playlist] section")
+	}
+
+	// Order the entries by number
+	nums := make([]int, 0, len(files))
+	for n := range files {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	var entries []Entry
+	for _, n := range nums {
+		entries = append(entries, Entry{Name: titles[n], URI: files[n]})
+	}
+	return entries, nil
+}
+
+// WritePLS outputs a playlist in the PLS format
+func WritePLS(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintln(bw, plsSection)
+	for i, e := range entries {
+		n := i + 1
+		_, _ = fmt.Fprintf(bw, "File%d=%s\n", n, oneLine(e.URI))
+		if e.Name != "" {
+			_, _ = fmt.Fprintf(bw, "Title%d=%s\n", n, oneLine(e.Name))
+		}
+		_, _ = fmt.Fprintf(bw, "Length%d=-1\n", n)
+	}
+	_, _ = fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(entries))
+	_, _ = fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
@@ -0,0 +1,80 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playlistfile
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// xspfPlaylist is the root element of an XSPF document
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Version string      `xml:"version,attr"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfTrack is a single track of an XSPF playlist
+type xspfTrack struct {
+	Locations []string `xml:"location"`
+	Title     string   `xml:"title,omitempty"`
+}
+
+// ReadXSPF parses a playlist in the XSPF format
+func ReadXSPF(r io.Reader) ([]Entry, error) {
+	var pl xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&pl); err != nil {
+		return nil, err
+	}
+	if pl.XMLName.Space != "" && pl.XMLName.Space != xspfNamespace {
+		return nil, errors.New("not an XSPF playlist: unexpected namespace " + pl.XMLName.Space)
+	}
+
+	// Take the first location of every track, as the rest are alternatives
+	var entries []Entry
+	for _, t := range pl.Tracks {
+		for _, loc := range t.Locations {
+			if loc = strings.TrimSpace(loc); loc != "" {
+				entries = append(entries, Entry{Name: strings.TrimSpace(t.Title), URI: loc})
+				break
+			}
+		}
+	}
+	return entries, nil
+}
+
+// WriteXSPF outputs a playlist in the XSPF format
+func WriteXSPF(w io.Writer, entries []Entry) error {
+	pl := xspfPlaylist{Xmlns: xspfNamespace, Version: "1", Tracks: make([]xspfTrack, len(entries))}
+	for i, e := range entries {
+		pl.Tracks[i] = xspfTrack{Locations: []string{e.URI}, Title: e.Name}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(pl); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
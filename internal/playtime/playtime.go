@@ -0,0 +1,78 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package playtime accounts for the time tracks are played for, based on periodic player state updates
+package playtime
+
+import "time"
+
+// Going back to a position earlier than this from past it is considered a restart of the track
+const RestartPosition = 5 * time.Second
+
+// Progress describes the outcome of a player state update
+type Progress struct {
+	Played  time.Duration // Time the track played before the update has been played for since the previous update
+	Changed bool          // Whether the song has changed or been restarted, which ends the current play and starts a new one
+	Carried time.Duration // Time the new play has already been played for, if the song has changed or been restarted
+}
+
+// Counter follows the player state and accounts for the time played. It isn't safe for concurrent use
+type Counter struct {
+	songID     string        // ID of the current song in the queue, empty if there's none
+	duration   time.Duration // Duration of the current song, zero if unknown
+	playing    bool          // Whether the song was playing as of the last update
+	elapsed    time.Duration // Playback position as of the last update
+	lastUpdate time.Time     // Time of the last update
+}
+
+// Update processes the current player state at the given time: the ID of the current song in the queue (empty if
+// none), its duration (zero if unknown), whether it's playing and the playback position
+func (c *Counter) Update(now time.Time, songID string, duration time.Duration, playing bool, elapsed time.Duration) Progress {
+	var p Progress
+	if c.songID != "" && c.playing {
+		p.Played = now.Sub(c.lastUpdate)
+	}
+	restarted := c.songID != "" && c.songID == songID && elapsed < RestartPosition && c.elapsed-elapsed > RestartPosition
+	switch {
+	case c.songID == "":
+	// A restarted track is played anew from the start
+	case restarted:
+		p.Played, p.Carried = 0, min(p.Played, elapsed)
+	// The same track continues: no more than the position advanced by, in case it was paused in the meantime
+	case c.songID == songID:
+		if adv := elapsed - c.elapsed; adv >= 0 && adv < p.Played {
+			p.Played = adv
+		}
+	// Another track: the previous one was playing until either skipped or finished
+	default:
+		if rest := c.duration - c.elapsed; c.duration > 0 && rest < p.Played {
+			p.Played = max(rest, 0)
+		}
+	}
+	p.Changed = c.songID != songID || restarted
+
+	c.songID, c.duration, c.playing, c.elapsed, c.lastUpdate = songID, duration, playing, elapsed, now
+	return p
+}
+
+// Elapsed returns the playback position as of the last update
+func (c *Counter) Elapsed() time.Duration {
+	return c.elapsed
+}
+
+// Reset forgets the player state, so that the next update starts a new play
+func (c *Counter) Reset() {
+	*c = Counter{}
+}
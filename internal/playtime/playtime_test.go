@@ -0,0 +1,102 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package playtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounter_Update(t *testing.T) {
+	t0 := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		at      time.Duration // Time offset from t0
+		songID  string
+		playing bool
+		elapsed time.Duration
+		want    Progress
+	}
+	const dur = 100 * time.Second
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"start", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{10 * time.Second, "A", true, 10 * time.Second, Progress{Played: 10 * time.Second}},
+		}},
+		{"paused", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{10 * time.Second, "A", false, 10 * time.Second, Progress{Played: 10 * time.Second}},
+			{time.Hour, "A", true, 10 * time.Second, Progress{}},
+			{time.Hour + 5*time.Second, "A", true, 15 * time.Second, Progress{Played: 5 * time.Second}},
+		}},
+		{"paused between updates", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{time.Minute, "A", true, 20 * time.Second, Progress{Played: 20 * time.Second}},
+		}},
+		{"played to the end", []step{
+			{0, "A", true, 95 * time.Second, Progress{Changed: true}},
+			{10 * time.Second, "B", true, 0, Progress{Played: 5 * time.Second, Changed: true}},
+		}},
+		{"skipped", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{30 * time.Second, "B", true, 0, Progress{Played: 30 * time.Second, Changed: true}},
+		}},
+		{"restarted", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{20 * time.Second, "A", true, 20 * time.Second, Progress{Played: 20 * time.Second}},
+			{22 * time.Second, "A", true, time.Second, Progress{Changed: true, Carried: time.Second}},
+		}},
+		{"seeked back", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{20 * time.Second, "A", true, 20 * time.Second, Progress{Played: 20 * time.Second}},
+			{21 * time.Second, "A", true, 10 * time.Second, Progress{Played: time.Second}},
+		}},
+		{"stopped", []step{
+			{0, "A", true, 0, Progress{Changed: true}},
+			{10 * time.Second, "", false, 0, Progress{Played: 10 * time.Second, Changed: true}},
+			{20 * time.Second, "", false, 0, Progress{}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Counter
+			for i, s := range tt.steps {
+				if got := c.Update(t0.Add(s.at), s.songID, dur, s.playing, s.elapsed); got != s.want {
+					t.Errorf("step %d: Update() = %+v, want %+v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCounter_Reset(t *testing.T) {
+	t0 := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	var c Counter
+	c.Update(t0, "A", 0, true, 0)
+	c.Update(t0.Add(10*time.Second), "A", 0, true, 10*time.Second)
+	if got := c.Elapsed(); got != 10*time.Second {
+		t.Errorf("Elapsed() = %v, want %v", got, 10*time.Second)
+	}
+
+	// The same song starts a new play after a reset
+	c.Reset()
+	want := Progress{Changed: true}
+	if got := c.Update(t0.Add(20*time.Second), "A", 0, true, 20*time.Second); got != want {
+		t.Errorf("Update() after Reset() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrobbler
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AudioscrobblerURL is the URL of the Last.fm Audioscrobbler 2.0 API
+const AudioscrobblerURL = "https://ws.audioscrobbler.com/2.0/"
+
+// asErrInvalidParameters is the Audioscrobbler error code for a request that's invalid as such
+const asErrInvalidParameters = 6
+
+// Audioscrobbler is a Service talking to an Audioscrobbler 2.0 API, such as the ones of Last.fm or Libre.fm
+type Audioscrobbler struct {
+	URL        string       // API URL, AudioscrobblerURL if empty
+	APIKey     string       // Application's API key
+	APISecret  string       // Application's shared secret, used for signing requests
+	SessionKey string       // User's session key
+	Client     *http.Client // HTTP client to use, http.DefaultClient if nil
+}
+
+// NowPlaying notifies the service of the track playing now
+func (as *Audioscrobbler) NowPlaying(ctx context.Context, track Track) error {
+	params := url.Values{}
+	as.addTrack(params, track, "")
+	return as.call(ctx, "track.updateNowPlaying", params)
+}
+
+// Submit scrobbles the given listens
+func (as *Audioscrobbler) Submit(ctx context.Context, listens []Listen) error {
+	params := url.Values{}
+	for i, l := range listens {
+		suffix := fmt.Sprintf("[%d]", i)
+		as.addTrack(params, l.Track, suffix)
+		params.Set("timestamp"+suffix, strconv.FormatInt(l.Time.Unix(), 10))
+	}
+	return as.call(ctx, "track.scrobble", params)
+}
+
+// addTrack adds the parameters describing the given track, with the given suffix appended to their names
+func (as *Audioscrobbler) addTrack(params url.Values, track Track, suffix string) {
+	set := func(name, value string) {
+		if value != "" {
+			params.Set(name+suffix, value)
+		}
+	}
+	set("artist", track.Artist)
+	set("track", track.Title)
+	set("album", track.Album)
+	set("albumArtist", track.AlbumArtist)
+	set("trackNumber", track.TrackNumber)
+	set("mbid", track.MBID)
+	if track.Duration > 0 {
+		set("duration", strconv.Itoa(int(track.Duration.Seconds())))
+	}
+}
+
+// call invokes the given API method, signing the request
+func (as *Audioscrobbler) call(ctx context.Context, method string, params url.Values) error {
+	params.Set("method", method)
+	params.Set("api_key", as.APIKey)
+	params.Set("sk", as.SessionKey)
+	params.Set("api_sig", asSignature(params, as.APISecret))
+	params.Set("format", "json")
+
+	apiURL := as.URL
+	if apiURL == "" {
+		apiURL = AudioscrobblerURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient(as.Client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Errors may come with any HTTP status
+	var asErr struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if json.Unmarshal(data, &asErr) == nil && asErr.Error != 0 {
+		if asErr.Error == asErrInvalidParameters {
+			return &RejectedError{Message: asErr.Message}
+		}
+		return fmt.Errorf("Audioscrobbler error %d: %s", asErr.Error, asErr.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Audioscrobbler request failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// asSignature returns the signature of the given request parameters: the MD5 hash of all parameter names and values,
+// ordered by name, followed by the shared secret
+func asSignature(params url.Values, secret string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if name != "format" && name != "callback" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(params.Get(name))
+	}
+	sb.WriteString(secret)
+	sum := md5.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
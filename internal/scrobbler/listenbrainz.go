@@ -0,0 +1,151 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrobbler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ListenBrainzURL is the root URL of the public ListenBrainz API
+const ListenBrainzURL = "https://api.listenbrainz.org"
+
+// ListenBrainz is a Service talking to a ListenBrainz-compatible API
+type ListenBrainz struct {
+	URL           string       // API root URL, ListenBrainzURL if empty
+	Token         string       // User token
+	ClientVersion string       // Version of the application, reported along with listens
+	Client        *http.Client // HTTP client to use, http.DefaultClient if nil
+}
+
+// lbSubmission is the payload of the submit-listens request
+type lbSubmission struct {
+	ListenType string     `json:"listen_type"`
+	Payload    []lbListen `json:"payload"`
+}
+
+type lbListen struct {
+	ListenedAt    int64           `json:"listened_at,omitempty"`
+	TrackMetadata lbTrackMetadata `json:"track_metadata"`
+}
+
+type lbTrackMetadata struct {
+	ArtistName     string           `json:"artist_name"`
+	TrackName      string           `json:"track_name"`
+	ReleaseName    string           `json:"release_name,omitempty"`
+	AdditionalInfo lbAdditionalInfo `json:"additional_info"`
+}
+
+type lbAdditionalInfo struct {
+	DurationMs              int64  `json:"duration_ms,omitempty"`
+	TrackNumber             string `json:"tracknumber,omitempty"`
+	RecordingMBID           string `json:"recording_mbid,omitempty"`
+	MediaPlayer             string `json:"media_player"`
+	SubmissionClient        string `json:"submission_client"`
+	SubmissionClientVersion string `json:"submission_client_version,omitempty"`
+}
+
+// NowPlaying notifies ListenBrainz of the track playing now
+func (lb *ListenBrainz) NowPlaying(ctx context.Context, track Track) error {
+	return lb.submit(ctx, "playing_now", []lbListen{{TrackMetadata: lb.metadata(track)}})
+}
+
+// Submit submits the given listens to ListenBrainz
+func (lb *ListenBrainz) Submit(ctx context.Context, listens []Listen) error {
+	listenType := "import"
+	if len(listens) == 1 {
+		listenType = "single"
+	}
+	payload := make([]lbListen, len(listens))
+	for i, l := range listens {
+		payload[i] = lbListen{ListenedAt: l.Time.Unix(), TrackMetadata: lb.metadata(l.Track)}
+	}
+	return lb.submit(ctx, listenType, payload)
+}
+
+// metadata converts the given track into ListenBrainz track metadata
+func (lb *ListenBrainz) metadata(track Track) lbTrackMetadata {
+	return lbTrackMetadata{
+		ArtistName:  track.Artist,
+		TrackName:   track.Title,
+		ReleaseName: track.Album,
+		AdditionalInfo: lbAdditionalInfo{
+			DurationMs:              track.Duration.Milliseconds(),
+			TrackNumber:             track.TrackNumber,
+			RecordingMBID:           track.MBID,
+			MediaPlayer:             "MPD",
+			SubmissionClient:        "Ymuse",
+			SubmissionClientVersion: lb.ClientVersion,
+		},
+	}
+}
+
+// submit posts a submit-listens request of the given type
+func (lb *ListenBrainz) submit(ctx context.Context, listenType string, payload []lbListen) error {
+	body, err := json.Marshal(lbSubmission{ListenType: listenType, Payload: payload})
+	if err != nil {
+		return err
+	}
+	url := lb.URL
+	if url == "" {
+		url = ListenBrainzURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/1/submit-listens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+lb.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient(lb.Client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	// Extract the error message, if any
+	var lbErr struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	msg := resp.Status
+	if json.Unmarshal(data, &lbErr) == nil && lbErr.Error != "" {
+		msg = lbErr.Error
+	}
+
+	// Bad requests won't succeed on retry, unlike authorisation errors (until the token is fixed) or server failures
+	if resp.StatusCode == http.StatusBadRequest {
+		return &RejectedError{Message: msg}
+	}
+	return fmt.Errorf("ListenBrainz request failed: %s", msg)
+}
+
+// httpClient returns the given HTTP client, or the default one if it's nil
+func httpClient(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
@@ -0,0 +1,317 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scrobbler submits played tracks to ListenBrainz or Audioscrobbler-compatible services
+package scrobbler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/yktoo/ymuse/internal/playtime"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Tracks not longer than this are never scrobbled
+	minTrackDuration = 30 * time.Second
+	// A track is scrobbled once it's been played for half its duration, or for this long, whichever comes first
+	maxPlayedRequired = 4 * time.Minute
+
+	// Maximum number of listens submitted in a single request
+	batchSize = 50
+	// Default maximum number of listens kept in the queue; older ones are dropped
+	defaultMaxQueued = 10000
+	// Timeout for a single request to the service
+	requestTimeout = 30 * time.Second
+
+	// Default delays between retries of a failed submission
+	defaultMinBackoff = 30 * time.Second
+	defaultMaxBackoff = time.Hour
+)
+
+// Track describes a played song
+type Track struct {
+	Artist      string        // Track artist
+	Title       string        // Track title
+	Album       string        // Album title, optional
+	AlbumArtist string        // Album artist, optional
+	TrackNumber string        // Track number, optional
+	Duration    time.Duration // Track duration, zero if unknown
+	MBID        string        // MusicBrainz recording ID, optional
+}
+
+// Scrobbable returns whether the track can be scrobbled at all
+func (t Track) Scrobbable() bool {
+	return t.Artist != "" && t.Title != "" && t.Duration > minTrackDuration
+}
+
+// Listen is a track played at a certain time
+type Listen struct {
+	Track
+	Time time.Time // Time the playback of the track started
+}
+
+// Service is a scrobbling service
+type Service interface {
+	// NowPlaying notifies the service of the track that has started playing
+	NowPlaying(ctx context.Context, track Track) error
+	// Submit submits the given listens, up to batchSize at a time
+	Submit(ctx context.Context, listens []Listen) error
+}
+
+// RejectedError is returned by a Service when it refuses to accept the submitted listens, so that retrying is pointless
+type RejectedError struct {
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return "listens rejected: " + e.Message
+}
+
+// PlayerState is a snapshot of the player's state
+type PlayerState struct {
+	SongID  string        // ID of the current song in the queue, empty if there's none
+	Track   Track         // Current track
+	Playing bool          // Whether the player is playing (as opposed to paused or stopped)
+	Elapsed time.Duration // Playback position in the current track
+}
+
+// play is a single playback of a track
+type play struct {
+	track     Track         // Track being played
+	start     time.Time     // Time the playback started
+	played    time.Duration // Time the track's been actually played for
+	announced bool          // Whether the service has been notified of the track playing now
+	scrobbled bool          // Whether the listen has been queued for submission
+}
+
+// Scrobbler tracks the player state, decides which tracks have been listened to, and submits them to the service in
+// the background. Listens that couldn't be submitted are kept in a queue file and retried with an increasing delay
+type Scrobbler struct {
+	service    Service          // Service listens are submitted to
+	queueFile  string           // Full path of the file storing pending listens
+	now        func() time.Time // Returns the current time, replaceable for testing
+	minBackoff time.Duration    // Delay before the first retry of a failed submission
+	maxBackoff time.Duration    // Maximum delay between retries
+	maxQueued  int              // Maximum number of listens kept in the queue, replaceable for testing
+
+	mutex   sync.Mutex       // Protects counter, cur, queue and dropped
+	counter playtime.Counter // Accounts for the time played
+	cur     *play            // Current playback, nil if none
+	queue   []Listen         // Listens pending submission, oldest first
+	dropped int              // Number of listens dropped off the head of the queue since the current batch was taken from it
+
+	ctx    context.Context    // Context of the background submissions
+	cancel context.CancelFunc // Cancels ctx
+	kick   chan struct{}      // Signals the submission goroutine there are new listens
+	done   chan struct{}      // Closed when the submission goroutine quits
+}
+
+// New creates and starts a new Scrobbler instance submitting listens to the given service, and keeping pending ones in
+// the given file
+func New(service Service, queueFile string) *Scrobbler {
+	return newScrobbler(service, queueFile, defaultMinBackoff, defaultMaxBackoff)
+}
+
+func newScrobbler(service Service, queueFile string, minBackoff, maxBackoff time.Duration) *Scrobbler {
+	s := &Scrobbler{
+		service:    service,
+		queueFile:  queueFile,
+		now:        time.Now,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		maxQueued:  defaultMaxQueued,
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.loadQueue()
+	go s.run()
+
+	// Submit whatever is left over from the last time
+	if len(s.queue) > 0 {
+		s.signal()
+	}
+	return s
+}
+
+// Close stops the background submissions and waits for them to finish
+func (s *Scrobbler) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Pending returns the number of listens waiting to be submitted
+func (s *Scrobbler) Pending() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.queue)
+}
+
+// Update processes the current player state. It's supposed to be called on every player change and also periodically
+// during playback
+func (s *Scrobbler) Update(state PlayerState) {
+	now := s.now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Account for the time played since the last update
+	progress := s.counter.Update(now, state.SongID, state.Track.Duration, state.Playing, state.Elapsed)
+	p := s.cur
+	if p != nil && !progress.Changed {
+		p.played += progress.Played
+	}
+
+	// Start a new playback if the song changed, or the same song was restarted (e.g. repeated)
+	if progress.Changed {
+		p = nil
+		if state.SongID != "" {
+			p = &play{track: state.Track, start: now.Add(-state.Elapsed), played: progress.Carried}
+		}
+		s.cur = p
+	}
+	if p == nil {
+		return
+	}
+	if !p.track.Scrobbable() {
+		return
+	}
+
+	// Announce the track once it starts playing
+	if state.Playing && !p.announced {
+		p.announced = true
+		go s.nowPlaying(p.track)
+	}
+
+	// Queue the listen once it's been played long enough
+	if !p.scrobbled && p.played >= min(p.track.Duration/2, maxPlayedRequired) {
+		p.scrobbled = true
+		s.queue = append(s.queue, Listen{Track: p.track, Time: p.start})
+		if n := len(s.queue); n > s.maxQueued {
+			s.queue = append([]Listen(nil), s.queue[n-s.maxQueued:]...)
+			s.dropped += n - s.maxQueued
+		}
+		s.saveQueue()
+		s.signal()
+	}
+}
+
+// loadQueue reads pending listens from the queue file
+func (s *Scrobbler) loadQueue() {
+	data, err := os.ReadFile(s.queueFile)
+	if errors.Is(err, os.ErrNotExist) || errCheck(err, "Failed to read scrobbler queue") {
+		return
+	}
+	if !errCheck(json.Unmarshal(data, &s.queue), "Failed to parse scrobbler queue") {
+		log.Debugf("Loaded %d pending listens from %s", len(s.queue), s.queueFile)
+	}
+}
+
+// nowPlaying notifies the service of the track playing now
+func (s *Scrobbler) nowPlaying(track Track) {
+	ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+	defer cancel()
+	errCheck(s.service.NowPlaying(ctx, track), "Failed to send now playing track")
+}
+
+// run submits queued listens whenever signalled, until the scrobbler is closed. A failed submission is retried after
+// a delay, which doubles with every subsequent failure
+func (s *Scrobbler) run() {
+	defer close(s.done)
+	var backoff time.Duration
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.kick:
+			// Wait for the retry if there's one scheduled
+			if retry != nil {
+				continue
+			}
+		case <-retry:
+			retry = nil
+		}
+
+		// Submit pending listens batch by batch
+		if err := s.submitAll(); err != nil {
+			backoff = min(max(2*backoff, s.minBackoff), s.maxBackoff)
+			log.Warningf("Failed to submit listens, retrying in %v: %v", backoff, err)
+			retry = time.After(backoff)
+		} else {
+			backoff = 0
+		}
+	}
+}
+
+// saveQueue writes pending listens out to the queue file. Must be called with the mutex locked
+func (s *Scrobbler) saveQueue() {
+	if errCheck(os.MkdirAll(filepath.Dir(s.queueFile), 0755), "MkdirAll() failed") {
+		return
+	}
+	data, err := json.Marshal(s.queue)
+	if !errCheck(err, "json.Marshal() failed") {
+		errCheck(os.WriteFile(s.queueFile, data, 0600), "Failed to write scrobbler queue")
+	}
+}
+
+// signal wakes the submission goroutine up, unless it's been signalled already
+func (s *Scrobbler) signal() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// submitAll submits all pending listens, and returns the first error that's worth retrying on. Listens rejected by the
+// service are dropped
+func (s *Scrobbler) submitAll() error {
+	for {
+		s.mutex.Lock()
+		batch := append([]Listen(nil), s.queue[:min(len(s.queue), batchSize)]...)
+		s.dropped = 0
+		s.mutex.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(s.ctx, requestTimeout)
+		err := s.service.Submit(ctx, batch)
+		cancel()
+		var rejected *RejectedError
+		switch {
+		case err == nil:
+			log.Debugf("Submitted %d listens", len(batch))
+		case errors.As(err, &rejected):
+			log.Warningf("Dropping %d listens: %v", len(batch), err)
+		default:
+			return err
+		}
+
+		// Remove the submitted listens from the queue. New ones may have been appended in the meantime, pushing the
+		// oldest ones, possibly from this batch, out of the queue
+		s.mutex.Lock()
+		if n := len(batch) - s.dropped; n > 0 {
+			s.queue = s.queue[n:]
+		}
+		s.dropped = 0
+		s.saveQueue()
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,382 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrobbler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// offlineService is a Service that always fails
+type offlineService struct {
+	mutex      sync.Mutex
+	nowPlaying []Track
+}
+
+func (o *offlineService) NowPlaying(_ context.Context, track Track) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.nowPlaying = append(o.nowPlaying, track)
+	return errors.New("offline")
+}
+
+func (o *offlineService) Submit(context.Context, []Listen) error {
+	return errors.New("offline")
+}
+
+// heldService is a Service that holds the first submission until released
+type heldService struct {
+	mutex     sync.Mutex
+	submitted []Listen
+	held      chan struct{} // Closed once the first submission is in flight
+	release   chan struct{} // Closed to let submissions through
+}
+
+func (h *heldService) NowPlaying(context.Context, Track) error {
+	return nil
+}
+
+func (h *heldService) Submit(_ context.Context, listens []Listen) error {
+	h.mutex.Lock()
+	if len(h.submitted) == 0 {
+		close(h.held)
+	}
+	h.submitted = append(h.submitted, listens...)
+	h.mutex.Unlock()
+	<-h.release
+	return nil
+}
+
+// step is a player state observed at a certain time, in seconds since t0
+type step struct {
+	at    int
+	state PlayerState
+}
+
+// playing returns steps of the given song playing from position from to position to, reported every 10 seconds,
+// starting at the given time
+func playing(at int, songID string, track Track, from, to int) []step {
+	var steps []step
+	for pos := from; pos <= to; pos += 10 {
+		steps = append(steps, step{at + pos - from, PlayerState{SongID: songID, Track: track, Playing: true, Elapsed: time.Duration(pos) * time.Second}})
+	}
+	return steps
+}
+
+func seq(parts ...[]step) []step {
+	var steps []step
+	for _, p := range parts {
+		steps = append(steps, p...)
+	}
+	return steps
+}
+
+func TestTrack_Scrobbable(t *testing.T) {
+	tests := []struct {
+		name  string
+		track Track
+		want  bool
+	}{
+		{"full", Track{Artist: "A", Title: "T", Duration: 3 * time.Minute}, true},
+		{"no artist", Track{Title: "T", Duration: 3 * time.Minute}, false},
+		{"no title", Track{Artist: "A", Duration: 3 * time.Minute}, false},
+		{"30 seconds", Track{Artist: "A", Title: "T", Duration: 30 * time.Second}, false},
+		{"31 seconds", Track{Artist: "A", Title: "T", Duration: 31 * time.Second}, true},
+		{"no duration", Track{Artist: "A", Title: "T"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.track.Scrobbable(); got != tt.want {
+				t.Errorf("Scrobbable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrobbler_Update(t *testing.T) {
+	song := Track{Artist: "Artist", Title: "Song", Duration: 200 * time.Second}
+	long := Track{Artist: "Artist", Title: "Long", Duration: 20 * time.Minute}
+	short := Track{Artist: "Artist", Title: "Short", Duration: 30 * time.Second}
+	untagged := Track{Title: "Song", Duration: 200 * time.Second}
+	at := func(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+	paused := func(at int, songID string, track Track, pos int) step {
+		return step{at, PlayerState{SongID: songID, Track: track, Elapsed: time.Duration(pos) * time.Second}}
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []Listen
+	}{
+		{"half played", playing(0, "1", song, 0, 100), []Listen{{song, at(0)}}},
+		{"not enough", seq(playing(0, "1", song, 0, 90), playing(100, "2", short, 0, 30)), nil},
+		{"joined midway", playing(0, "1", song, 50, 150), []Listen{{song, at(-50)}}},
+		{"four minutes", playing(0, "1", long, 0, 240), []Listen{{long, at(0)}}},
+		{"short track", playing(0, "1", short, 0, 30), nil},
+		{"untagged", playing(0, "1", untagged, 0, 200), nil},
+		{"pause not counted", seq(
+			playing(0, "1", song, 0, 60),
+			[]step{paused(70, "1", song, 60), paused(170, "1", song, 60)},
+			playing(180, "1", song, 60, 90)),
+			nil},
+		{"pause then enough", seq(
+			playing(0, "1", song, 0, 60),
+			[]step{paused(70, "1", song, 60), paused(170, "1", song, 60)},
+			playing(180, "1", song, 60, 100)),
+			[]Listen{{song, at(0)}}},
+		{"seek forward not counted", seq(playing(0, "1", song, 0, 10), playing(20, "1", song, 150, 190)), nil},
+		{"repeated", seq(playing(0, "1", song, 0, 190), playing(200, "1", song, 0, 100)), []Listen{{song, at(0)}, {song, at(200)}}},
+		{"seek back within track", seq(playing(0, "1", song, 0, 60), playing(70, "1", song, 20, 60)), []Listen{{song, at(0)}}},
+		{"stopped", seq(
+			playing(0, "1", song, 0, 60),
+			[]step{{70, PlayerState{}}},
+			playing(80, "1", song, 0, 60)),
+			nil},
+		{"queue changed", seq(playing(0, "1", song, 0, 100), playing(110, "2", long, 0, 240)), []Listen{{song, at(0)}, {long, at(110)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScrobbler(&offlineService{}, filepath.Join(t.TempDir(), "queue.json"), time.Hour, time.Hour)
+			defer s.Close()
+			for _, st := range tt.steps {
+				s.now = func() time.Time { return at(st.at) }
+				s.Update(st.state)
+			}
+			s.mutex.Lock()
+			got := s.queue
+			s.mutex.Unlock()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrobbler_NowPlaying(t *testing.T) {
+	svc := &offlineService{}
+	s := newScrobbler(svc, filepath.Join(t.TempDir(), "queue.json"), time.Hour, time.Hour)
+	song := Track{Artist: "Artist", Title: "Song", Duration: 200 * time.Second}
+	s.Update(PlayerState{SongID: "1", Track: song})
+	s.Update(PlayerState{SongID: "1", Track: song, Playing: true})
+	s.Update(PlayerState{SongID: "1", Track: song, Playing: true, Elapsed: time.Second})
+	s.Update(PlayerState{SongID: "2", Track: Track{Title: "Untagged", Duration: time.Minute}, Playing: true})
+
+	// Wait for the notifications to be sent
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		svc.mutex.Lock()
+		got := append([]Track(nil), svc.nowPlaying...)
+		svc.mutex.Unlock()
+		if len(got) > 0 || time.Now().After(deadline) {
+			if !reflect.DeepEqual(got, []Track{song}) {
+				t.Errorf("NowPlaying() calls = %v, want %v", got, []Track{song})
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Close()
+}
+
+// lbStandIn is a local stand-in for a ListenBrainz server, failing the given number of submissions (not counting now playing notifications) first
+type lbStandIn struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	failures int            // Number of submissions left to fail
+	status   int            // Status code of failing requests
+	requests int            // Total number of requests received
+	listens  []lbSubmission // Submissions accepted
+	accepted chan struct{}  // Receives a value on every accepted submission
+}
+
+func newLBStandIn(failures, status int) *lbStandIn {
+	sb := &lbStandIn{failures: failures, status: status, accepted: make(chan struct{}, 100)}
+	sb.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sb.mutex.Lock()
+		defer sb.mutex.Unlock()
+		sb.requests++
+		var sub lbSubmission
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// Only fail submissions, as now playing notifications are sent concurrently with them
+		if sub.ListenType != "playing_now" {
+			if sb.failures != 0 {
+				sb.failures--
+				w.WriteHeader(sb.status)
+				_, _ = w.Write([]byte(`{"code":0,"error":"failed on purpose"}`))
+				return
+			}
+			sb.listens = append(sb.listens, sub)
+			sb.accepted <- struct{}{}
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	return sb
+}
+
+// queueListen makes the scrobbler queue a listen of a song with the given title
+func queueListen(s *Scrobbler, title string, at time.Time) {
+	track := Track{Artist: "Artist", Title: title, Duration: 100 * time.Second}
+	s.now = func() time.Time { return at }
+	s.Update(PlayerState{SongID: title, Track: track})
+	s.Update(PlayerState{SongID: title, Track: track, Playing: true})
+	s.now = func() time.Time { return at.Add(time.Minute) }
+	s.Update(PlayerState{SongID: title, Track: track, Playing: true, Elapsed: time.Minute})
+}
+
+// waitFor waits until the condition is met, failing the test after a while
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestScrobbler_Retry(t *testing.T) {
+	server := newLBStandIn(2, http.StatusServiceUnavailable)
+	defer server.Close()
+	s := newScrobbler(&ListenBrainz{URL: server.URL}, filepath.Join(t.TempDir(), "queue.json"), 10*time.Millisecond, 20*time.Millisecond)
+	defer s.Close()
+
+	queueListen(s, "One", t0)
+	select {
+	case <-server.accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listen not submitted")
+	}
+	waitFor(t, "empty queue", func() bool { return s.Pending() == 0 })
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if len(server.listens) != 1 || server.listens[0].ListenType != "single" || server.listens[0].Payload[0].TrackMetadata.TrackName != "One" {
+		t.Errorf("submissions = %+v, want a single listen of One", server.listens)
+	}
+}
+
+func TestScrobbler_Rejected(t *testing.T) {
+	server := newLBStandIn(1, http.StatusBadRequest)
+	defer server.Close()
+	s := newScrobbler(&ListenBrainz{URL: server.URL}, filepath.Join(t.TempDir(), "queue.json"), time.Hour, time.Hour)
+	defer s.Close()
+
+	// The rejected listen must be dropped rather than retried
+	queueListen(s, "One", t0)
+	waitFor(t, "empty queue", func() bool { return s.Pending() == 0 })
+	queueListen(s, "Two", t0.Add(time.Hour))
+	select {
+	case <-server.accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listen not submitted")
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if len(server.listens) != 1 || server.listens[0].Payload[0].TrackMetadata.TrackName != "Two" {
+		t.Errorf("submissions = %+v, want a single listen of Two", server.listens)
+	}
+}
+
+func TestScrobbler_OfflineQueue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "queue.json")
+
+	// Queue listens while the service is unavailable
+	down := newLBStandIn(-1, http.StatusBadGateway)
+	s := newScrobbler(&ListenBrainz{URL: down.URL}, file, time.Hour, time.Hour)
+	queueListen(s, "One", t0)
+	queueListen(s, "Two", t0.Add(time.Hour))
+	s.Close()
+	down.Close()
+	if got := s.Pending(); got != 2 {
+		t.Fatalf("Pending() = %d, want 2", got)
+	}
+
+	// The listens are submitted in one go as soon as a new scrobbler starts
+	up := newLBStandIn(0, 0)
+	defer up.Close()
+	s = newScrobbler(&ListenBrainz{URL: up.URL}, file, time.Hour, time.Hour)
+	defer s.Close()
+	select {
+	case <-up.accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listens not submitted")
+	}
+	waitFor(t, "empty queue", func() bool { return s.Pending() == 0 })
+
+	up.mutex.Lock()
+	defer up.mutex.Unlock()
+	if len(up.listens) != 1 || up.listens[0].ListenType != "import" || len(up.listens[0].Payload) != 2 {
+		t.Fatalf("submissions = %+v, want one import of 2 listens", up.listens)
+	}
+	if got, want := up.listens[0].Payload[1].ListenedAt, t0.Add(time.Hour).Unix(); got != want {
+		t.Errorf("listened_at = %d, want %d", got, want)
+	}
+}
+
+func TestScrobbler_TrimWhileSubmitting(t *testing.T) {
+	svc := &heldService{held: make(chan struct{}), release: make(chan struct{})}
+	s := newScrobbler(svc, filepath.Join(t.TempDir(), "queue.json"), time.Hour, time.Hour)
+	defer s.Close()
+	s.maxQueued = 2 * batchSize
+
+	// Fill the queue up and let the first batch get stuck in submission
+	s.mutex.Lock()
+	for i := 0; i < s.maxQueued; i++ {
+		s.queue = append(s.queue, Listen{Track: Track{Artist: "Artist", Title: strconv.Itoa(i)}, Time: t0})
+	}
+	s.mutex.Unlock()
+	s.signal()
+	select {
+	case <-svc.held:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch not submitted")
+	}
+
+	// New listens push the oldest ones, which are in flight, out of the queue
+	for _, title := range []string{"a", "b", "c"} {
+		queueListen(s, title, t0.Add(time.Hour))
+	}
+	close(svc.release)
+	waitFor(t, "empty queue", func() bool { return s.Pending() == 0 })
+
+	// Every listen must be submitted exactly once
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	seen := make(map[string]bool)
+	for _, l := range svc.submitted {
+		if seen[l.Track.Title] {
+			t.Errorf("listen %q submitted more than once", l.Track.Title)
+		}
+		seen[l.Track.Title] = true
+	}
+	if got, want := len(seen), s.maxQueued+3; got != want {
+		t.Errorf("submitted %d distinct listens, want %d", got, want)
+	}
+}
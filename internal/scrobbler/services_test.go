@@ -0,0 +1,202 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scrobbler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var testTrack = Track{
+	Artist:      "Artist",
+	Title:       "Song",
+	Album:       "Album",
+	AlbumArtist: "Various",
+	TrackNumber: "3",
+	Duration:    200500 * time.Millisecond,
+	MBID:        "mbid-1",
+}
+
+// request is an HTTP request captured by a stand-in server
+type request struct {
+	method, path, auth, contentType string
+	body                            []byte
+}
+
+// standIn starts a local HTTP server capturing the last request and replying with the given status and body
+func standIn(t *testing.T, status int, body string) (*httptest.Server, *request) {
+	t.Helper()
+	var req request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req = request{r.Method, r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Content-Type"), data}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &req
+}
+
+func TestListenBrainz(t *testing.T) {
+	server, req := standIn(t, http.StatusOK, `{"status":"ok"}`)
+	lb := &ListenBrainz{URL: server.URL + "/", Token: "secret", ClientVersion: "1.2"}
+
+	// Submit a listen
+	if err := lb.Submit(context.Background(), []Listen{{testTrack, t0}}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if req.method != http.MethodPost || req.path != "/1/submit-listens" || req.auth != "Token secret" || req.contentType != "application/json" {
+		t.Errorf("Submit() request = %s %s, auth %q, type %q", req.method, req.path, req.auth, req.contentType)
+	}
+	want := `{"listen_type":"single","payload":[{"listened_at":1772366400,"track_metadata":{"artist_name":"Artist",` +
+		`"track_name":"Song","release_name":"Album","additional_info":{"duration_ms":200500,"tracknumber":"3",` +
+		`"recording_mbid":"mbid-1","media_player":"MPD","submission_client":"Ymuse","submission_client_version":"1.2"}}}]}`
+	if got := string(req.body); got != want {
+		t.Errorf("Submit() body = %s, want %s", got, want)
+	}
+
+	// Notify of the playing track
+	if err := lb.NowPlaying(context.Background(), Track{Artist: "Artist", Title: "Song"}); err != nil {
+		t.Fatalf("NowPlaying() error = %v", err)
+	}
+	want = `{"listen_type":"playing_now","payload":[{"track_metadata":{"artist_name":"Artist","track_name":"Song",` +
+		`"additional_info":{"media_player":"MPD","submission_client":"Ymuse","submission_client_version":"1.2"}}}]}`
+	if got := string(req.body); got != want {
+		t.Errorf("NowPlaying() body = %s, want %s", got, want)
+	}
+}
+
+func TestListenBrainz_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantRejected bool
+		wantMessage  string
+	}{
+		{"bad request", http.StatusBadRequest, `{"code":400,"error":"Invalid listen"}`, true, "listens rejected: Invalid listen"},
+		{"unauthorised", http.StatusUnauthorized, `{"code":401,"error":"Invalid authorization token."}`, false, "ListenBrainz request failed: Invalid authorization token."},
+		{"server error", http.StatusBadGateway, `<html></html>`, false, "ListenBrainz request failed: 502 Bad Gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := standIn(t, tt.status, tt.body)
+			err := (&ListenBrainz{URL: server.URL}).Submit(context.Background(), []Listen{{testTrack, t0}})
+			var rejected *RejectedError
+			if err == nil || errors.As(err, &rejected) != tt.wantRejected || err.Error() != tt.wantMessage {
+				t.Errorf("Submit() error = %v, want %q (rejected %v)", err, tt.wantMessage, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestAudioscrobbler(t *testing.T) {
+	server, req := standIn(t, http.StatusOK, `{"scrobbles":{"@attr":{"accepted":2,"ignored":0}}}`)
+	as := &Audioscrobbler{URL: server.URL, APIKey: "key", APISecret: "secret", SessionKey: "session"}
+
+	// Scrobble two listens
+	listens := []Listen{{testTrack, t0}, {Track{Artist: "A2", Title: "T2", Duration: time.Minute}, t0.Add(time.Hour)}}
+	if err := as.Submit(context.Background(), listens); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if req.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Submit() content type = %q", req.contentType)
+	}
+	params, err := url.ParseQuery(string(req.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"method":         "track.scrobble",
+		"api_key":        "key",
+		"sk":             "session",
+		"format":         "json",
+		"artist[0]":      "Artist",
+		"track[0]":       "Song",
+		"album[0]":       "Album",
+		"albumArtist[0]": "Various",
+		"trackNumber[0]": "3",
+		"duration[0]":    "200",
+		"mbid[0]":        "mbid-1",
+		"timestamp[0]":   "1772366400",
+		"artist[1]":      "A2",
+		"track[1]":       "T2",
+		"duration[1]":    "60",
+		"timestamp[1]":   "1772370000",
+	}
+	for name, value := range want {
+		if got := params.Get(name); got != value {
+			t.Errorf("Submit() parameter %s = %q, want %q", name, got, value)
+		}
+	}
+	if len(params) != len(want)+1 {
+		t.Errorf("Submit() parameters = %v, want %d", params, len(want)+1)
+	}
+
+	// Verify the signature
+	sig := params.Get("api_sig")
+	params.Del("api_sig")
+	if want := asSignature(params, "secret"); sig != want {
+		t.Errorf("api_sig = %q, want %q", sig, want)
+	}
+
+	// Notify of the playing track
+	if err := as.NowPlaying(context.Background(), Track{Artist: "Artist", Title: "Song"}); err != nil {
+		t.Fatalf("NowPlaying() error = %v", err)
+	}
+	if params, _ = url.ParseQuery(string(req.body)); params.Get("method") != "track.updateNowPlaying" || params.Get("track") != "Song" {
+		t.Errorf("NowPlaying() parameters = %v", params)
+	}
+}
+
+func TestAudioscrobbler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantRejected bool
+		wantMessage  string
+	}{
+		{"invalid parameters", http.StatusBadRequest, `{"error":6,"message":"Invalid parameters"}`, true, "listens rejected: Invalid parameters"},
+		{"invalid session", http.StatusForbidden, `{"error":9,"message":"Invalid session key"}`, false, "Audioscrobbler error 9: Invalid session key"},
+		{"offline in OK response", http.StatusOK, `{"error":11,"message":"Service Offline"}`, false, "Audioscrobbler error 11: Service Offline"},
+		{"server error", http.StatusInternalServerError, `oops`, false, "Audioscrobbler request failed: 500 Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := standIn(t, tt.status, tt.body)
+			err := (&Audioscrobbler{URL: server.URL}).Submit(context.Background(), []Listen{{testTrack, t0}})
+			var rejected *RejectedError
+			if err == nil || errors.As(err, &rejected) != tt.wantRejected || err.Error() != tt.wantMessage {
+				t.Errorf("Submit() error = %v, want %q (rejected %v)", err, tt.wantMessage, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestAsSignature(t *testing.T) {
+	params := url.Values{"method": {"auth.getSession"}, "api_key": {"xxx"}, "token": {"yyy"}, "format": {"json"}}
+	// MD5 of "api_keyxxxmethodauth.getSessiontokenyyyzzz"; format isn't signed
+	if got, want := asSignature(params, "zzz"), "75df1fdb6b738160924a52b1732fdde7"; got != want {
+		t.Errorf("asSignature() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secret stores secrets in the keyring using the freedesktop Secret Service D-Bus API
+package secret
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"time"
+)
+
+const (
+	busName           = "org.freedesktop.secrets"
+	servicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	serviceIface      = "org.freedesktop.Secret.Service"
+	collectionIface   = "org.freedesktop.Secret.Collection"
+	itemIface         = "org.freedesktop.Secret.Item"
+	sessionIface      = "org.freedesktop.Secret.Session"
+	promptIface       = "org.freedesktop.Secret.Prompt"
+
+	// noPrompt is the path returned instead of a prompt when none is needed
+	noPrompt = dbus.ObjectPath("/")
+
+	// How long the user is given to complete a prompt
+	defaultPromptTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrDismissed is returned when the user dismisses a prompt, for instance one to unlock the keyring
+	ErrDismissed = errors.New("prompt dismissed")
+	// ErrTimeout is returned when the user doesn't complete a prompt in time
+	ErrTimeout = errors.New("prompt timed out")
+)
+
+// secret is the Secret Service's representation of a secret value
+type secret struct {
+	Session     dbus.ObjectPath // Session the secret is transferred in
+	Parameters  []byte          // Algorithm-dependent parameters, empty for the plain algorithm
+	Value       []byte          // Secret value
+	ContentType string          // Content type of the value
+}
+
+// Keyring is a session with the Secret Service
+type Keyring struct {
+	conn          *dbus.Conn      // Session bus connection
+	service       dbus.BusObject  // Secret Service object
+	session       dbus.ObjectPath // Path of the open session
+	promptTimeout time.Duration   // How long to wait for the user to complete a prompt, replaceable for testing
+}
+
+// New connects to the session bus and opens a session with the Secret Service. Fails if there's no Secret Service
+// running
+func New() (*Keyring, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	k, err := newKeyring(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+func newKeyring(conn *dbus.Conn) (*Keyring, error) {
+	k := &Keyring{conn: conn, service: conn.Object(busName, servicePath), promptTimeout: defaultPromptTimeout}
+
+	// The plain algorithm transfers secrets unencrypted, which is fine over the local session bus
+	var output dbus.Variant
+	if err := k.service.Call(serviceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &k.session); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Close closes the session and disconnects from the bus
+func (k *Keyring) Close() error {
+	errCheck(k.conn.Object(busName, k.session).Call(sessionIface+".Close", 0).Err, "Session.Close() failed")
+	return k.conn.Close()
+}
+
+// Delete removes all items having the given attributes
+func (k *Keyring) Delete(attrs map[string]string) error {
+	items, err := k.search(attrs)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := k.conn.Object(busName, item).Call(itemIface+".Delete", 0).Store(&prompt); err != nil {
+			return err
+		}
+		if _, err := k.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the secret of an item having the given attributes, and whether there's such an item
+func (k *Keyring) Lookup(attrs map[string]string) (string, bool, error) {
+	items, err := k.search(attrs)
+	if err != nil || len(items) == 0 {
+		return "", false, err
+	}
+	var s secret
+	if err := k.conn.Object(busName, items[0]).Call(itemIface+".GetSecret", 0, k.session).Store(&s); err != nil {
+		return "", false, err
+	}
+	return string(s.Value), true, nil
+}
+
+// Store saves the secret in the default collection, replacing the item having the same attributes, if any. The label
+// is what keyring managers display for the item
+func (k *Keyring) Store(label string, attrs map[string]string, value string) error {
+	if _, err := k.unlock(defaultCollection); err != nil {
+		return err
+	}
+	props := map[string]dbus.Variant{
+		itemIface + ".Label":      dbus.MakeVariant(label),
+		itemIface + ".Attributes": dbus.MakeVariant(attrs),
+	}
+	s := secret{Session: k.session, Value: []byte(value), ContentType: "text/plain; charset=utf8"}
+	var item, prompt dbus.ObjectPath
+	err := k.conn.Object(busName, defaultCollection).
+		Call(collectionIface+".CreateItem", 0, props, s, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return err
+	}
+	_, err = k.prompt(prompt)
+	return err
+}
+
+// prompt shows the given prompt, if any, and waits for the user to complete it. Returns the prompt's result, or
+// ErrTimeout if the prompt isn't completed in time
+func (k *Keyring) prompt(prompt dbus.ObjectPath) (dbus.Variant, error) {
+	if prompt == noPrompt || prompt == "" {
+		return dbus.Variant{}, nil
+	}
+
+	// Subscribe to the completion before showing the prompt so as not to miss it
+	opts := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(promptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := k.conn.AddMatchSignal(opts...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer func() { errCheck(k.conn.RemoveMatchSignal(opts...), "RemoveMatchSignal() failed") }()
+	signals := make(chan *dbus.Signal, 10)
+	k.conn.Signal(signals)
+	defer k.conn.RemoveSignal(signals)
+
+	// Show the prompt and wait for it to complete
+	if err := k.conn.Object(busName, prompt).Call(promptIface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, err
+	}
+	timeout := time.NewTimer(k.promptTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case sig, ok := <-signals:
+			if !ok {
+				return dbus.Variant{}, errors.New("connection closed while prompting")
+			}
+			if sig.Path != prompt || sig.Name != promptIface+".Completed" || len(sig.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, ErrDismissed
+			}
+			result, _ := sig.Body[1].(dbus.Variant)
+			return result, nil
+
+		case <-timeout.C:
+			// Take the abandoned prompt off the screen
+			errCheck(k.conn.Object(busName, prompt).Call(promptIface+".Dismiss", 0).Err, "Prompt.Dismiss() failed")
+			return dbus.Variant{}, ErrTimeout
+		}
+	}
+}
+
+// search returns the items having the given attributes, unlocking the locked ones
+func (k *Keyring) search(attrs map[string]string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := k.service.Call(serviceIface+".SearchItems", 0, attrs).Store(&unlocked, &locked); err != nil {
+		return nil, err
+	}
+	if len(locked) > 0 {
+		items, err := k.unlock(locked...)
+		if err != nil {
+			return nil, err
+		}
+		unlocked = append(unlocked, items...)
+	}
+	return unlocked, nil
+}
+
+// unlock unlocks the given items or collections, prompting the user if necessary, and returns those unlocked
+func (k *Keyring) unlock(objects ...dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := k.service.Call(serviceIface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return nil, err
+	}
+	result, err := k.prompt(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if items, ok := result.Value().([]dbus.ObjectPath); ok {
+		unlocked = append(unlocked, items...)
+	}
+	return unlocked, nil
+}
@@ -0,0 +1,289 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/yktoo/ymuse/internal/dbustest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeItem is an item stored in fakeService
+type fakeItem struct {
+	attrs  map[string]string
+	value  []byte
+	locked bool
+}
+
+// fakeService is a stand-in for the Secret Service, keeping all items in the default collection
+type fakeService struct {
+	conn      *dbus.Conn
+	mutex     sync.Mutex
+	items     map[dbus.ObjectPath]*fakeItem
+	lastID    int
+	dismiss   bool // Whether the user dismisses unlock prompts
+	ignore    bool // Whether the user ignores unlock prompts
+	prompted  int  // Number of prompts shown
+	dismissed int  // Number of prompts dismissed by the client
+}
+
+// fakeObject serves the methods of a single object of fakeService
+type fakeObject struct {
+	s    *fakeService
+	path dbus.ObjectPath
+}
+
+func (s *fakeService) OpenSession(algorithm string, _ dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", nil)
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (s *fakeService) SearchItems(attrs map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var unlocked, locked []dbus.ObjectPath
+	for path, item := range s.items {
+		if matches(item.attrs, attrs) {
+			if item.locked {
+				locked = append(locked, path)
+			} else {
+				unlocked = append(unlocked, path)
+			}
+		}
+	}
+	return unlocked, locked, nil
+}
+
+func (s *fakeService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var locked []dbus.ObjectPath
+	for _, path := range objects {
+		if item, ok := s.items[path]; ok && item.locked {
+			locked = append(locked, path)
+		}
+	}
+	if len(locked) == 0 {
+		return objects, noPrompt, nil
+	}
+
+	// Locked items need a prompt
+	s.prompted++
+	prompt := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/prompt/%d", s.prompted))
+	if err := s.conn.Export(&fakePrompt{s: s, path: prompt, items: locked}, prompt, promptIface); err != nil {
+		return nil, "", dbus.MakeFailedError(err)
+	}
+	return nil, prompt, nil
+}
+
+func (c *fakeObject) CreateItem(props map[string]dbus.Variant, s secret, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	c.s.mutex.Lock()
+	defer c.s.mutex.Unlock()
+	attrs, _ := props[itemIface+".Attributes"].Value().(map[string]string)
+	if replace {
+		for path, item := range c.s.items {
+			if reflect.DeepEqual(item.attrs, attrs) {
+				item.value = s.Value
+				return path, noPrompt, nil
+			}
+		}
+	}
+	c.s.lastID++
+	path := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", c.s.lastID))
+	c.s.items[path] = &fakeItem{attrs: attrs, value: s.Value}
+	if err := c.s.conn.Export(&fakeObject{s: c.s, path: path}, path, itemIface); err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+	return path, noPrompt, nil
+}
+
+func (c *fakeObject) Delete() (dbus.ObjectPath, *dbus.Error) {
+	c.s.mutex.Lock()
+	defer c.s.mutex.Unlock()
+	delete(c.s.items, c.path)
+	return noPrompt, nil
+}
+
+func (c *fakeObject) GetSecret(session dbus.ObjectPath) (secret, *dbus.Error) {
+	c.s.mutex.Lock()
+	defer c.s.mutex.Unlock()
+	item, ok := c.s.items[c.path]
+	if !ok || item.locked {
+		return secret{}, dbus.NewError("org.freedesktop.Secret.Error.IsLocked", nil)
+	}
+	return secret{Session: session, Value: item.value, ContentType: "text/plain"}, nil
+}
+
+// fakePrompt is an unlock prompt of fakeService
+type fakePrompt struct {
+	s     *fakeService
+	path  dbus.ObjectPath
+	items []dbus.ObjectPath
+}
+
+func (p *fakePrompt) Prompt(string) *dbus.Error {
+	p.s.mutex.Lock()
+	if p.s.ignore {
+		p.s.mutex.Unlock()
+		return nil
+	}
+	dismiss := p.s.dismiss
+	if !dismiss {
+		for _, path := range p.items {
+			p.s.items[path].locked = false
+		}
+	}
+	p.s.mutex.Unlock()
+	go func() {
+		_ = p.s.conn.Emit(p.path, promptIface+".Completed", dismiss, dbus.MakeVariant(p.items))
+	}()
+	return nil
+}
+
+func (p *fakePrompt) Dismiss() *dbus.Error {
+	p.s.mutex.Lock()
+	p.s.dismissed++
+	p.s.mutex.Unlock()
+	go func() {
+		_ = p.s.conn.Emit(p.path, promptIface+".Completed", true, dbus.MakeVariant([]dbus.ObjectPath{}))
+	}()
+	return nil
+}
+
+// matches returns whether attrs contains all of the wanted attributes
+func matches(attrs, want map[string]string) bool {
+	for k, v := range want {
+		if attrs[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// startService starts a private session bus with a stand-in Secret Service on it, and returns a keyring connected to
+// the service
+func startService(t *testing.T) (*fakeService, *Keyring) {
+	t.Helper()
+	connect := dbustest.StartBus(t)
+
+	// Put the service on the bus
+	s := &fakeService{conn: connect(), items: map[dbus.ObjectPath]*fakeItem{}}
+	if err := s.conn.Export(s, servicePath, serviceIface); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.conn.Export(&fakeObject{s: s, path: defaultCollection}, defaultCollection, collectionIface); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := s.conn.RequestName(busName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName() = %v, %v", reply, err)
+	}
+
+	k, err := newKeyring(connect())
+	if err != nil {
+		t.Fatalf("newKeyring() error = %v", err)
+	}
+	return s, k
+}
+
+func TestKeyring(t *testing.T) {
+	_, k := startService(t)
+	attrs := map[string]string{"server": "localhost", "port": "6600"}
+
+	// Nothing stored yet
+	if got, ok, err := k.Lookup(attrs); got != "" || ok || err != nil {
+		t.Errorf("Lookup() = %q, %v, %v, want \"\", false, nil", got, ok, err)
+	}
+
+	// Store and replace
+	for _, value := range []string{"secret", "another"} {
+		if err := k.Store("MPD password", attrs, value); err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		if got, ok, err := k.Lookup(attrs); got != value || !ok || err != nil {
+			t.Errorf("Lookup() = %q, %v, %v, want %q, true, nil", got, ok, err, value)
+		}
+	}
+
+	// Other attributes don't match
+	if _, ok, _ := k.Lookup(map[string]string{"server": "localhost", "port": "6601"}); ok {
+		t.Error("Lookup() found an item with other attributes")
+	}
+
+	// Delete
+	if err := k.Delete(attrs); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, err := k.Lookup(attrs); ok || err != nil {
+		t.Errorf("Lookup() after Delete() = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestKeyring_Locked(t *testing.T) {
+	s, k := startService(t)
+	attrs := map[string]string{"server": "localhost"}
+	if err := k.Store("MPD password", attrs, "secret"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	lock := func(dismiss, ignore bool) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, item := range s.items {
+			item.locked = true
+		}
+		s.dismiss, s.ignore = dismiss, ignore
+	}
+
+	// The user dismisses the unlock prompt
+	lock(true, false)
+	if _, _, err := k.Lookup(attrs); !errors.Is(err, ErrDismissed) {
+		t.Errorf("Lookup() error = %v, want %v", err, ErrDismissed)
+	}
+
+	// The user ignores the unlock prompt, which gets dismissed after a while
+	lock(false, true)
+	k.promptTimeout = 50 * time.Millisecond
+	if _, _, err := k.Lookup(attrs); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Lookup() error = %v, want %v", err, ErrTimeout)
+	}
+
+	// The user unlocks the keyring
+	lock(false, false)
+	if got, ok, err := k.Lookup(attrs); got != "secret" || !ok || err != nil {
+		t.Errorf("Lookup() = %q, %v, %v, want \"secret\", true, nil", got, ok, err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.prompted != 3 {
+		t.Errorf("prompted %d times, want 3", s.prompted)
+	}
+	if s.dismissed != 1 {
+		t.Errorf("dismissed %d prompts, want 1", s.dismissed)
+	}
+}
+
+func TestNewKeyring_NoService(t *testing.T) {
+	connect := dbustest.StartBus(t)
+	if _, err := newKeyring(connect()); err == nil {
+		t.Error("newKeyring() error = nil, want one")
+	}
+}
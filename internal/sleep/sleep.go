@@ -0,0 +1,156 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sleep implements the scheduling logic of the sleep timer and the wake-up alarm
+package sleep
+
+import (
+	"github.com/yktoo/ymuse/internal/playtime"
+	"time"
+)
+
+// Mode defines when a sleep timer expires
+type Mode string
+
+const (
+	ModeMinutes  Mode = "minutes" // After a number of minutes
+	ModeTrackEnd Mode = "track"   // At the end of the current track
+	ModeTracks   Mode = "tracks"  // After a number of tracks, the current one included
+)
+
+// Phase is the stage a sleep timer is at
+type Phase int
+
+const (
+	PhaseRunning Phase = iota // Counting down
+	PhaseFading               // Counting down, and it's time to fade the volume out
+	PhaseExpired              // Time to stop the playback
+)
+
+// PlayerState is the player state a sleep timer is checked against
+type PlayerState struct {
+	SongID   string        // ID of the current song in the queue, empty if there's none
+	Elapsed  time.Duration // Playback position in the current song
+	Duration time.Duration // Duration of the current song, 0 if unknown
+	Stopped  bool          // Whether the player is stopped
+}
+
+// Status is the result of a sleep timer check
+type Status struct {
+	Phase     Phase         // Stage the timer is at
+	Remaining time.Duration // Time left until the timer expires, -1 if unknown
+	Tracks    int           // Number of tracks left to play, the current one included; 0 for ModeMinutes
+}
+
+// Timer is a sleep timer, stopping the playback after a time or a number of tracks
+type Timer struct {
+	mode     Mode             // Timer mode
+	fade     time.Duration    // Fade-out duration before the expiry, 0 for none
+	now      func() time.Time // Returns the current time, replaceable for testing
+	deadline time.Time        // Expiry time for ModeMinutes
+	songID   string           // ID of the last seen song for the track modes
+	elapsed  time.Duration    // Playback position as of the last check, for the track modes
+	stopped  bool             // Whether the player was stopped as of the last check, for the track modes
+	tracks   int              // Number of tracks left for the track modes, the current one included
+}
+
+// NewTimer creates and returns a new Timer instance. count is the number of minutes for ModeMinutes and the number
+// of tracks for ModeTracks; it's ignored for ModeTrackEnd. fade is the duration of the volume fade-out preceding the
+// expiry, 0 for none
+func NewTimer(mode Mode, count int, fade time.Duration, state PlayerState) *Timer {
+	return newTimer(mode, count, fade, state, time.Now)
+}
+
+func newTimer(mode Mode, count int, fade time.Duration, state PlayerState, now func() time.Time) *Timer {
+	t := &Timer{
+		mode:    mode,
+		fade:    fade,
+		now:     now,
+		songID:  state.SongID,
+		elapsed: state.Elapsed,
+		stopped: state.Stopped,
+		tracks:  max(count, 1),
+	}
+	switch mode {
+	case ModeMinutes:
+		t.deadline = now().Add(time.Duration(count) * time.Minute)
+	case ModeTrackEnd:
+		t.tracks = 1
+	}
+	return t
+}
+
+// Mode returns the timer's mode
+func (t *Timer) Mode() Mode {
+	return t.mode
+}
+
+// Check updates the timer with the given player state and returns its status. It's supposed to be called regularly,
+// at least once a second
+func (t *Timer) Check(state PlayerState) Status {
+	if t.mode == ModeMinutes {
+		return t.status(t.deadline.Sub(t.now()), 0)
+	}
+
+	// Count the tracks that have been played. A track is over once another one starts, or the same one starts over, as
+	// in single or repeat mode
+	restarted := state.SongID == t.songID &&
+		state.Elapsed < playtime.RestartPosition && t.elapsed-state.Elapsed > playtime.RestartPosition
+	if state.SongID != t.songID || restarted {
+		if t.songID != "" {
+			t.tracks--
+		}
+		t.songID = state.SongID
+	}
+	stopped := state.Stopped && !t.stopped
+	t.elapsed, t.stopped = state.Elapsed, state.Stopped
+
+	// The timer expires once the tracks are over, or the player has stopped or run out of the queue
+	if t.tracks <= 0 || stopped || state.SongID == "" {
+		return Status{Phase: PhaseExpired}
+	}
+
+	// The remaining time is only known for the last track. The track is only over once the player moves on, as its
+	// duration may be slightly off, so the timer never expires on time alone
+	if t.tracks > 1 || state.Duration <= 0 {
+		return Status{Phase: PhaseRunning, Remaining: -1, Tracks: t.tracks}
+	}
+	remaining := max(state.Duration-state.Elapsed, 0)
+	if remaining <= t.fade && t.fade > 0 {
+		return Status{Phase: PhaseFading, Remaining: remaining, Tracks: 1}
+	}
+	return Status{Phase: PhaseRunning, Remaining: remaining, Tracks: 1}
+}
+
+// status returns the status given the remaining time
+func (t *Timer) status(remaining time.Duration, tracks int) Status {
+	switch {
+	case remaining <= 0:
+		return Status{Phase: PhaseExpired}
+	case remaining <= t.fade:
+		return Status{Phase: PhaseFading, Remaining: remaining, Tracks: tracks}
+	}
+	return Status{Phase: PhaseRunning, Remaining: remaining, Tracks: tracks}
+}
+
+// NextAlarm returns the first moment after the given time when the clock shows the given hour and minute
+func NextAlarm(now time.Time, hour, minute int) time.Time {
+	y, m, d := now.Date()
+	t := time.Date(y, m, d, hour, minute, 0, 0, now.Location())
+	if !t.After(now) {
+		t = time.Date(y, m, d+1, hour, minute, 0, 0, now.Location())
+	}
+	return t
+}
@@ -0,0 +1,243 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sleep
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a source of time that only moves when told to
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+// check is a step of a timer test: the clock is advanced by the given duration, then the timer is checked with the
+// given player state
+type check struct {
+	advance time.Duration
+	state   PlayerState
+	want    Status
+}
+
+func runChecks(t *testing.T, mode Mode, count int, fade time.Duration, initial PlayerState, checks []check) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)}
+	timer := newTimer(mode, count, fade, initial, clock.now)
+	for i, c := range checks {
+		clock.t = clock.t.Add(c.advance)
+		if got := timer.Check(c.state); got != c.want {
+			t.Errorf("step %d: Check() = %+v, want %+v", i, got, c.want)
+		}
+	}
+}
+
+func song(id string, elapsed, duration int) PlayerState {
+	return PlayerState{SongID: id, Elapsed: time.Duration(elapsed) * time.Second, Duration: time.Duration(duration) * time.Second}
+}
+
+func TestTimer_Minutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		fade   time.Duration
+		checks []check
+	}{
+		{
+			"no fade",
+			0,
+			[]check{
+				{0, song("1", 0, 100), Status{PhaseRunning, 10 * time.Minute, 0}},
+				{9 * time.Minute, song("2", 0, 100), Status{PhaseRunning, time.Minute, 0}},
+				{59 * time.Second, PlayerState{}, Status{PhaseRunning, time.Second, 0}},
+				{time.Second, PlayerState{}, Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"fade",
+			30 * time.Second,
+			[]check{
+				{9 * time.Minute, song("1", 0, 100), Status{PhaseRunning, time.Minute, 0}},
+				{30 * time.Second, song("1", 30, 100), Status{PhaseFading, 30 * time.Second, 0}},
+				{29 * time.Second, song("1", 59, 100), Status{PhaseFading, time.Second, 0}},
+				{2 * time.Second, song("1", 61, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runChecks(t, ModeMinutes, 10, tt.fade, song("1", 0, 100), tt.checks)
+		})
+	}
+}
+
+func TestTimer_Tracks(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    Mode
+		count   int
+		fade    time.Duration
+		initial PlayerState
+		checks  []check
+	}{
+		{
+			"end of track",
+			ModeTrackEnd, 5, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("1", 11, 100), Status{PhaseRunning, 89 * time.Second, 1}},
+				{88 * time.Second, song("1", 99, 100), Status{PhaseRunning, time.Second, 1}},
+				{time.Second, song("1", 100, 100), Status{PhaseRunning, 0, 1}},
+				{time.Second, song("2", 0, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"end of track missed",
+			ModeTrackEnd, 0, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("2", 0, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"end of track with fade",
+			ModeTrackEnd, 0, 20 * time.Second,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("1", 70, 100), Status{PhaseRunning, 30 * time.Second, 1}},
+				{time.Second, song("1", 80, 100), Status{PhaseFading, 20 * time.Second, 1}},
+				{time.Second, song("1", 99, 100), Status{PhaseFading, time.Second, 1}},
+				{time.Second, song("2", 0, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"end of stream",
+			ModeTrackEnd, 0, 20 * time.Second,
+			song("1", 10, 0),
+			[]check{
+				{time.Hour, song("1", 3610, 0), Status{PhaseRunning, -1, 1}},
+				{time.Second, song("2", 0, 0), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"tracks",
+			ModeTracks, 3, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("1", 11, 100), Status{PhaseRunning, -1, 3}},
+				{time.Second, song("2", 0, 100), Status{PhaseRunning, -1, 2}},
+				{time.Second, song("3", 0, 200), Status{PhaseRunning, 200 * time.Second, 1}},
+				{time.Second, song("3", 199, 200), Status{PhaseRunning, time.Second, 1}},
+				{time.Second, song("4", 0, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"tracks skipped",
+			ModeTracks, 2, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("5", 0, 100), Status{PhaseRunning, 100 * time.Second, 1}},
+				{time.Second, song("9", 0, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"queue ran out",
+			ModeTracks, 5, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, PlayerState{}, Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"started without a song",
+			ModeTracks, 2, 0,
+			PlayerState{},
+			[]check{
+				{time.Second, song("1", 0, 100), Status{PhaseRunning, -1, 2}},
+				{time.Second, song("2", 0, 100), Status{PhaseRunning, 100 * time.Second, 1}},
+			},
+		},
+		{
+			"end of track in single mode",
+			ModeTrackEnd, 0, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("1", 99, 100), Status{PhaseRunning, time.Second, 1}},
+				{time.Second, PlayerState{SongID: "1", Duration: 100 * time.Second, Stopped: true}, Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"started while stopped",
+			ModeTrackEnd, 0, 0,
+			PlayerState{SongID: "1", Duration: 100 * time.Second, Stopped: true},
+			[]check{
+				{time.Second, PlayerState{SongID: "1", Duration: 100 * time.Second, Stopped: true}, Status{PhaseRunning, 100 * time.Second, 1}},
+				{time.Second, song("1", 1, 100), Status{PhaseRunning, 99 * time.Second, 1}},
+			},
+		},
+		{
+			"tracks in repeat single mode",
+			ModeTracks, 2, 0,
+			song("1", 10, 100),
+			[]check{
+				{time.Second, song("1", 99, 100), Status{PhaseRunning, -1, 2}},
+				{time.Second, song("1", 0, 100), Status{PhaseRunning, 100 * time.Second, 1}},
+				{time.Second, song("1", 99, 100), Status{PhaseRunning, time.Second, 1}},
+				{time.Second, song("1", 1, 100), Status{PhaseExpired, 0, 0}},
+			},
+		},
+		{
+			"seeking forward",
+			ModeTracks, 2, 0,
+			song("1", 2, 100),
+			[]check{
+				{time.Second, song("1", 90, 100), Status{PhaseRunning, -1, 2}},
+				{time.Second, song("1", 50, 100), Status{PhaseRunning, -1, 2}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runChecks(t, tt.mode, tt.count, tt.fade, tt.initial, tt.checks)
+		})
+	}
+}
+
+func TestNextAlarm(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	tests := []struct {
+		name         string
+		now          time.Time
+		hour, minute int
+		want         time.Time
+	}{
+		{"later today", time.Date(2026, 3, 1, 6, 0, 0, 0, loc), 7, 30, time.Date(2026, 3, 1, 7, 30, 0, 0, loc)},
+		{"tomorrow", time.Date(2026, 3, 1, 23, 0, 0, 0, loc), 7, 30, time.Date(2026, 3, 2, 7, 30, 0, 0, loc)},
+		{"right now", time.Date(2026, 3, 1, 7, 30, 0, 0, loc), 7, 30, time.Date(2026, 3, 2, 7, 30, 0, 0, loc)},
+		{"a second ago", time.Date(2026, 3, 1, 7, 30, 1, 0, loc), 7, 30, time.Date(2026, 3, 2, 7, 30, 0, 0, loc)},
+		{"end of month", time.Date(2026, 2, 28, 8, 0, 0, 0, loc), 6, 0, time.Date(2026, 3, 1, 6, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextAlarm(tt.now, tt.hour, tt.minute); !got.Equal(tt.want) {
+				t.Errorf("NextAlarm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
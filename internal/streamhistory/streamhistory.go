@@ -0,0 +1,178 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package streamhistory keeps a persistent log of the titles announced by radio streams
+package streamhistory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLimit is the default maximum number of entries kept per stream
+	DefaultLimit = 500
+	// Maximum number of streams kept. Any stream URI can be played, so the least recently updated streams are dropped
+	maxStreams = 200
+)
+
+// Entry is a single title change of a stream
+type Entry struct {
+	Time  time.Time // Time the title was first seen
+	Title string    // Stream title, usually in the "Artist - Title" form
+}
+
+// History is a per-stream log of titles, stored in a JSON file
+type History struct {
+	file      string             // Full path of the file the history is stored in
+	limit     int                // Maximum number of entries kept per stream, 0 for unlimited
+	mutex     sync.Mutex         // Streams access mutex
+	saveMutex sync.Mutex         // Serialises saves, so that an older state never overwrites a newer one
+	streams   map[string][]Entry // Entries keyed by stream URI, oldest first
+}
+
+// New creates and returns a new, empty History instance stored in the given file, which keeps up to limit entries per
+// stream (0 means no limit)
+func New(file string, limit int) *History {
+	return &History{file: file, limit: limit, streams: make(map[string][]Entry)}
+}
+
+// Load creates a new History instance and reads its content from the given file. A missing file results in an empty
+// history
+func Load(file string, limit int) (*History, error) {
+	h := New(file, limit)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h.streams); err != nil {
+		return h, err
+	}
+	if h.streams == nil {
+		h.streams = make(map[string][]Entry)
+	}
+	return h, nil
+}
+
+// Add records the given title of the stream with the given URI, seen at the given time. Returns whether an entry was
+// added, which is only the case if the title is not empty and differs from the stream's last recorded title
+func (h *History) Add(uri, title string, t time.Time) bool {
+	title = strings.TrimSpace(title)
+	if uri == "" || title == "" {
+		return false
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	entries := h.streams[uri]
+	if n := len(entries); n > 0 && entries[n-1].Title == title {
+		return false
+	}
+	entries = append(entries, Entry{Time: t, Title: title})
+
+	// Drop the oldest entries beyond the limit
+	if h.limit > 0 && len(entries) > h.limit {
+		entries = append([]Entry(nil), entries[len(entries)-h.limit:]...)
+	}
+
+	// Make room for a new stream
+	if _, ok := h.streams[uri]; !ok {
+		for len(h.streams) >= maxStreams {
+			h.dropStalest()
+		}
+	}
+	h.streams[uri] = entries
+	return true
+}
+
+// dropStalest removes the stream whose title changed the longest time ago. Must be called with the mutex locked
+func (h *History) dropStalest() {
+	var stalest string
+	var stalestTime time.Time
+	for uri, entries := range h.streams {
+		var t time.Time
+		if n := len(entries); n > 0 {
+			t = entries[n-1].Time
+		}
+		if stalest == "" || t.Before(stalestTime) {
+			stalest, stalestTime = uri, t
+		}
+	}
+	delete(h.streams, stalest)
+}
+
+// Clear removes all entries of the stream with the given URI
+func (h *History) Clear(uri string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.streams, uri)
+}
+
+// Entries returns a copy of the entries of the stream with the given URI, oldest first
+func (h *History) Entries(uri string) []Entry {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]Entry(nil), h.streams[uri]...)
+}
+
+// Save writes the history out to its file, creating the containing directory if necessary. It's safe to call from
+// several goroutines
+func (h *History) Save() error {
+	h.saveMutex.Lock()
+	defer h.saveMutex.Unlock()
+	h.mutex.Lock()
+	data, err := json.Marshal(h.streams)
+	h.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.file, data, 0600)
+}
+
+// SplitTitle splits a stream title in the common "Artist - Title" form into the artist and the song title. If the title
+// can't be split, the artist is empty and the song title is the whole title
+func SplitTitle(title string) (artist, song string) {
+	title = strings.TrimSpace(title)
+	if a, s, ok := strings.Cut(title, " - "); ok {
+		if a, s = strings.TrimSpace(a), strings.TrimSpace(s); a != "" && s != "" {
+			return a, s
+		}
+	}
+	return "", title
+}
+
+// WriteCSV outputs the given entries of the named stream as CSV, with a header line
+func WriteCSV(w io.Writer, streamName string, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Time", "Stream", "Title"})
+	for _, e := range entries {
+		_ = cw.Write([]string{e.Time.Format(time.RFC3339), streamName, e.Title})
+	}
+	cw.Flush()
+	return cw.Error()
+}
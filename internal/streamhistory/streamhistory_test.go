@@ -0,0 +1,173 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package streamhistory
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestHistory_Add(t *testing.T) {
+	h := New("", 3)
+	tests := []struct {
+		name  string
+		uri   string
+		title string
+		want  bool
+	}{
+		{"first", "http://a", "Artist - One", true},
+		{"same title", "http://a", "Artist - One", false},
+		{"same title padded", "http://a", " Artist - One ", false},
+		{"empty title", "http://a", "  ", false},
+		{"empty URI", "", "Artist - Two", false},
+		{"other stream", "http://b", "Artist - One", true},
+		{"second", "http://a", "Artist - Two", true},
+		{"back to first", "http://a", "Artist - One", true},
+		{"over limit", "http://a", "Artist - Three", true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Add(tt.uri, tt.title, t0.Add(time.Duration(i)*time.Minute)); got != tt.want {
+				t.Errorf("Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	want := []Entry{
+		{Time: t0.Add(6 * time.Minute), Title: "Artist - Two"},
+		{Time: t0.Add(7 * time.Minute), Title: "Artist - One"},
+		{Time: t0.Add(8 * time.Minute), Title: "Artist - Three"},
+	}
+	if got := h.Entries("http://a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %v, want %v", got, want)
+	}
+	h.Clear("http://a")
+	if got := h.Entries("http://a"); got != nil {
+		t.Errorf("Entries() after Clear() = %v, want nil", got)
+	}
+	if got := h.Entries("http://b"); len(got) != 1 {
+		t.Errorf("Entries() of another stream = %v, want 1 entry", got)
+	}
+}
+
+func TestHistory_AddMaxStreams(t *testing.T) {
+	h := New("", 0)
+	uri := func(i int) string { return fmt.Sprintf("http://%d", i) }
+	for i := 0; i < maxStreams; i++ {
+		h.Add(uri(i), "Title", t0.Add(time.Duration(i)*time.Minute))
+	}
+
+	// Updating the oldest stream makes the next one the stalest
+	h.Add(uri(0), "Other title", t0.Add(time.Hour*24))
+	h.Add("http://new", "Title", t0.Add(time.Hour*25))
+	if got := len(h.streams); got != maxStreams {
+		t.Errorf("stream count = %d, want %d", got, maxStreams)
+	}
+	if got := h.Entries(uri(1)); got != nil {
+		t.Errorf("Entries() of the stalest stream = %v, want nil", got)
+	}
+	for _, u := range []string{uri(0), uri(2), "http://new"} {
+		if got := h.Entries(u); len(got) == 0 {
+			t.Errorf("Entries(%q) = %v, want entries", u, got)
+		}
+	}
+}
+
+func TestHistory_SaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "history.json")
+
+	// Loading a missing file gives an empty history
+	h, err := Load(file, 0)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := h.Entries("http://a"); got != nil {
+		t.Errorf("Entries() = %v, want nil", got)
+	}
+
+	// Save and load back
+	h.Add("http://a", "One", t0)
+	h.Add("http://a", "Two", t0.Add(time.Minute))
+	if err := h.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	h2, err := Load(file, 0)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, want := h2.Entries("http://a"), h.Entries("http://a"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries() = %v, want %v", got, want)
+	}
+
+	// A corrupted file results in an error, but still in a usable history
+	if err := os.WriteFile(file, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	h3, err := Load(file, 0)
+	if err == nil {
+		t.Errorf("Load() error = nil, want error")
+	}
+	if !h3.Add("http://a", "One", t0) {
+		t.Errorf("Add() = false, want true")
+	}
+}
+
+func TestSplitTitle(t *testing.T) {
+	tests := []struct {
+		title      string
+		wantArtist string
+		wantSong   string
+	}{
+		{"", "", ""},
+		{"Just a title", "", "Just a title"},
+		{"Artist - Song", "Artist", "Song"},
+		{" Artist  -  Song - Remix ", "Artist", "Song - Remix"},
+		{"Artist-Song", "", "Artist-Song"},
+		{" - Song", "", "- Song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			artist, song := SplitTitle(tt.title)
+			if artist != tt.wantArtist || song != tt.wantSong {
+				t.Errorf("SplitTitle() = (%q, %q), want (%q, %q)", artist, song, tt.wantArtist, tt.wantSong)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []Entry{
+		{Time: t0, Title: "Artist - One"},
+		{Time: t0.Add(90 * time.Second), Title: `Quoted "Title", with comma`},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, "Jazz FM", entries); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "Time,Stream,Title\n" +
+		"2026-03-01T12:00:00Z,Jazz FM,Artist - One\n" +
+		"2026-03-01T12:01:30Z,Jazz FM,\"Quoted \"\"Title\"\", with comma\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() = %q, want %q", got, want)
+	}
+}
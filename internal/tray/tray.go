@@ -0,0 +1,480 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tray shows an icon in the system tray using the StatusNotifierItem D-Bus protocol, with a menu served over
+// the com.canonical.dbusmenu protocol
+package tray
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	watcherName  = "org.kde.StatusNotifierWatcher"
+	watcherPath  = dbus.ObjectPath("/StatusNotifierWatcher")
+	itemPath     = dbus.ObjectPath("/StatusNotifierItem")
+	itemIface    = "org.kde.StatusNotifierItem"
+	menuPath     = dbus.ObjectPath("/MenuBar")
+	menuIface    = "com.canonical.dbusmenu"
+	propsIface   = "org.freedesktop.DBus.Properties"
+	introIface   = "org.freedesktop.DBus.Introspectable"
+	menuRootID   = 0 // ID of the invisible root menu item
+	menuVersion  = 3 // Implemented version of the dbusmenu protocol
+	menuClicked  = "clicked"
+	itemCategory = "ApplicationStatus"
+	itemStatus   = "Active"
+)
+
+// Sequence number of items created by the process, which makes their bus names unique
+var itemSeq atomic.Int32
+
+// MenuItem is an item of the tray icon's menu
+type MenuItem struct {
+	Label     string // Item label, with an underscore before the mnemonic character
+	IconName  string // Icon name, optional
+	Disabled  bool   // Whether the item is greyed out
+	Separator bool   // Whether the item is a separator, which makes other fields irrelevant
+	OnClick   func() // Callback for clicks, called from a separate goroutine
+}
+
+// Item is a tray icon. Its callbacks are invoked from a separate goroutine
+type Item struct {
+	conn       *dbus.Conn        // Session bus connection
+	busName    string            // Well-known bus name the item is registered under
+	id         string            // Application identifier
+	onActivate func()            // Callback for clicking the icon
+	onScroll   func(delta int)   // Callback for scrolling over the icon, positive delta for scrolling down
+	signals    chan *dbus.Signal // Channel receiving bus signals
+
+	mutex    sync.Mutex // Protects the fields below
+	title    string     // Item title
+	iconName string     // Icon name
+	tipTitle string     // Tooltip title
+	tipBody  string     // Tooltip body, which may contain simple markup
+	menu     []MenuItem // Menu items, the ID of each being its index plus one
+	revision uint32     // Menu layout revision
+}
+
+// New connects to the session bus and registers a new tray icon with the given application ID, title and icon name.
+// It fails if there's no system tray (that is, no StatusNotifierWatcher) around
+func New(id, title, iconName string, onActivate func(), onScroll func(delta int)) (*Item, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	i, err := newItem(conn, id, title, iconName, onActivate, onScroll)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return i, nil
+}
+
+func newItem(conn *dbus.Conn, id, title, iconName string, onActivate func(), onScroll func(delta int)) (*Item, error) {
+	i := &Item{
+		conn:       conn,
+		busName:    fmt.Sprintf("org.kde.StatusNotifierItem-%d-%d", os.Getpid(), itemSeq.Add(1)),
+		id:         id,
+		onActivate: onActivate,
+		onScroll:   onScroll,
+		signals:    make(chan *dbus.Signal, 10),
+		title:      title,
+		iconName:   iconName,
+		revision:   1,
+	}
+
+	// Export the objects
+	sni, menu := &sniObject{i}, &menuObject{i}
+	for _, e := range []struct {
+		obj   interface{}
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{sni, itemPath, itemIface},
+		{propsObject(i.itemProps), itemPath, propsIface},
+		{introspect.NewIntrospectable(introspection(sni, itemIface, itemSignals, i.itemProps())), itemPath, introIface},
+		{menu, menuPath, menuIface},
+		{propsObject(menuProps), menuPath, propsIface},
+		{introspect.NewIntrospectable(introspection(menu, menuIface, menuSignals, menuProps())), menuPath, introIface},
+	} {
+		if err := conn.Export(e.obj, e.path, e.iface); err != nil {
+			return nil, err
+		}
+	}
+	if reply, err := conn.RequestName(i.busName, dbus.NameFlagDoNotQueue); err != nil {
+		return nil, err
+	} else if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("bus name %s is already taken", i.busName)
+	}
+
+	// Register again whenever the watcher restarts, for instance along with the desktop panel
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, watcherName),
+	); err != nil {
+		return nil, err
+	}
+	conn.Signal(i.signals)
+	go i.listen()
+	if err := i.register(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Close removes the icon from the tray and disconnects from the bus
+func (i *Item) Close() error {
+	return i.conn.Close()
+}
+
+// SetMenu replaces the items of the icon's menu
+func (i *Item) SetMenu(items []MenuItem) {
+	i.mutex.Lock()
+	changed := len(items) != len(i.menu)
+	for idx := 0; !changed && idx < len(items); idx++ {
+		a, b := items[idx], i.menu[idx]
+		changed = a.Label != b.Label || a.IconName != b.IconName || a.Disabled != b.Disabled || a.Separator != b.Separator
+	}
+	i.menu = append([]MenuItem(nil), items...)
+	if changed {
+		i.revision++
+	}
+	revision := i.revision
+	i.mutex.Unlock()
+
+	// Only announce the layout if there's anything new to show
+	if changed {
+		errCheck(i.conn.Emit(menuPath, menuIface+".LayoutUpdated", revision, int32(menuRootID)), "Failed to emit LayoutUpdated")
+	}
+}
+
+// SetToolTip updates the icon's tooltip. The body may contain simple markup
+func (i *Item) SetToolTip(title, body string) {
+	i.mutex.Lock()
+	changed := title != i.tipTitle || body != i.tipBody
+	i.tipTitle, i.tipBody = title, body
+	i.mutex.Unlock()
+	if changed {
+		errCheck(i.conn.Emit(itemPath, itemIface+".NewToolTip"), "Failed to emit NewToolTip")
+	}
+}
+
+// register registers the item with the watcher
+func (i *Item) register() error {
+	return i.conn.Object(watcherName, watcherPath).Call(watcherName+".RegisterStatusNotifierItem", 0, i.busName).Err
+}
+
+// listen processes bus signals until the connection is closed
+func (i *Item) listen() {
+	for sig := range i.signals {
+		if sig.Name != "org.freedesktop.DBus.NameOwnerChanged" || len(sig.Body) < 3 {
+			continue
+		}
+		if name, _ := sig.Body[0].(string); name == watcherName {
+			if owner, _ := sig.Body[2].(string); owner != "" {
+				errCheck(i.register(), "Failed to register with the new StatusNotifierWatcher")
+			}
+		}
+	}
+}
+
+// pixmap is an image in the ARGB32 format
+type pixmap struct {
+	Width, Height int32
+	Data          []byte
+}
+
+// toolTip is the value of the ToolTip property
+type toolTip struct {
+	IconName   string
+	IconPixmap []pixmap
+	Title      string
+	Body       string
+}
+
+// itemProps returns the properties of the StatusNotifierItem object
+func (i *Item) itemProps() map[string]dbus.Variant {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return map[string]dbus.Variant{
+		"Category":            dbus.MakeVariant(itemCategory),
+		"Id":                  dbus.MakeVariant(i.id),
+		"Title":               dbus.MakeVariant(i.title),
+		"Status":              dbus.MakeVariant(itemStatus),
+		"WindowId":            dbus.MakeVariant(int32(0)),
+		"IconName":            dbus.MakeVariant(i.iconName),
+		"IconPixmap":          dbus.MakeVariant([]pixmap{}),
+		"OverlayIconName":     dbus.MakeVariant(""),
+		"OverlayIconPixmap":   dbus.MakeVariant([]pixmap{}),
+		"AttentionIconName":   dbus.MakeVariant(""),
+		"AttentionIconPixmap": dbus.MakeVariant([]pixmap{}),
+		"AttentionMovieName":  dbus.MakeVariant(""),
+		"ToolTip":             dbus.MakeVariant(toolTip{IconPixmap: []pixmap{}, Title: i.tipTitle, Body: i.tipBody}),
+		"ItemIsMenu":          dbus.MakeVariant(false),
+		"Menu":                dbus.MakeVariant(menuPath),
+	}
+}
+
+// menuProps returns the properties of the dbusmenu object
+func menuProps() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Version":       dbus.MakeVariant(uint32(menuVersion)),
+		"TextDirection": dbus.MakeVariant("ltr"),
+		"Status":        dbus.MakeVariant("normal"),
+		"IconThemePath": dbus.MakeVariant([]string{}),
+	}
+}
+
+var (
+	itemSignals = []introspect.Signal{
+		{Name: "NewTitle"},
+		{Name: "NewIcon"},
+		{Name: "NewToolTip"},
+		{Name: "NewStatus", Args: []introspect.Arg{{Name: "status", Type: "s"}}},
+	}
+	menuSignals = []introspect.Signal{
+		{Name: "LayoutUpdated", Args: []introspect.Arg{{Name: "revision", Type: "u"}, {Name: "parent", Type: "i"}}},
+		{Name: "ItemsPropertiesUpdated", Args: []introspect.Arg{{Name: "updatedProps", Type: "a(ia{sv})"}, {Name: "removedProps", Type: "a(ias)"}}},
+	}
+)
+
+// introspection returns the introspection data of an exported object
+func introspection(obj interface{}, iface string, signals []introspect.Signal, props map[string]dbus.Variant) *introspect.Node {
+	var properties []introspect.Property
+	for name, v := range props {
+		properties = append(properties, introspect.Property{Name: name, Type: v.Signature().String(), Access: "read"})
+	}
+	sort.Slice(properties, func(i, j int) bool { return properties[i].Name < properties[j].Name })
+	return &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: propsIface, Methods: introspect.Methods(propsObject(nil))},
+			{Name: iface, Methods: introspect.Methods(obj), Signals: signals, Properties: properties},
+		},
+	}
+}
+
+// propsObject implements the org.freedesktop.DBus.Properties interface for read-only properties returned by the
+// function
+type propsObject func() map[string]dbus.Variant
+
+func (p propsObject) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if v, ok := p()[name]; ok {
+		return v, nil
+	}
+	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("no property %s in interface %s", name, iface))
+}
+
+func (p propsObject) GetAll(string) (map[string]dbus.Variant, *dbus.Error) {
+	return p(), nil
+}
+
+func (p propsObject) Set(_, name string, _ dbus.Variant) *dbus.Error {
+	return dbus.MakeFailedError(fmt.Errorf("property %s is read-only", name))
+}
+
+// sniObject implements the org.kde.StatusNotifierItem interface
+type sniObject struct {
+	i *Item
+}
+
+func (s *sniObject) Activate(_, _ int32) *dbus.Error {
+	if s.i.onActivate != nil {
+		s.i.onActivate()
+	}
+	return nil
+}
+
+func (s *sniObject) SecondaryActivate(_, _ int32) *dbus.Error {
+	return nil
+}
+
+func (s *sniObject) ContextMenu(_, _ int32) *dbus.Error {
+	// The menu is shown by the host through the Menu object
+	return nil
+}
+
+func (s *sniObject) Scroll(delta int32, orientation string) *dbus.Error {
+	if s.i.onScroll != nil && strings.EqualFold(orientation, "vertical") && delta != 0 {
+		s.i.onScroll(int(delta))
+	}
+	return nil
+}
+
+// menuLayout is a node of the dbusmenu layout tree
+type menuLayout struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+	Children   []dbus.Variant
+}
+
+// menuItemProps is a set of properties of a dbusmenu item
+type menuItemProps struct {
+	ID         int32
+	Properties map[string]dbus.Variant
+}
+
+// menuEvent is an event reported via EventGroup
+type menuEvent struct {
+	ID        int32
+	EventID   string
+	Data      dbus.Variant
+	Timestamp uint32
+}
+
+// menuObject implements the com.canonical.dbusmenu interface
+type menuObject struct {
+	i *Item
+}
+
+func (m *menuObject) GetLayout(parentID, _ int32, names []string) (uint32, menuLayout, *dbus.Error) {
+	m.i.mutex.Lock()
+	defer m.i.mutex.Unlock()
+	switch {
+	// There are no submenus, so only the root has children
+	case parentID == menuRootID:
+		layout := menuLayout{ID: menuRootID, Properties: filterProps(map[string]dbus.Variant{"children-display": dbus.MakeVariant("submenu")}, names)}
+		for idx := range m.i.menu {
+			layout.Children = append(layout.Children, dbus.MakeVariant(m.i.menuItemLayout(int32(idx+1), names)))
+		}
+		return m.i.revision, layout, nil
+	case m.i.menuItem(parentID) != nil:
+		return m.i.revision, m.i.menuItemLayout(parentID, names), nil
+	}
+	return 0, menuLayout{}, dbus.MakeFailedError(fmt.Errorf("no menu item with ID %d", parentID))
+}
+
+func (m *menuObject) GetGroupProperties(ids []int32, names []string) ([]menuItemProps, *dbus.Error) {
+	m.i.mutex.Lock()
+	defer m.i.mutex.Unlock()
+	// No IDs means all items
+	if len(ids) == 0 {
+		for idx := range m.i.menu {
+			ids = append(ids, int32(idx+1))
+		}
+	}
+	res := []menuItemProps{}
+	for _, id := range ids {
+		if item := m.i.menuItem(id); item != nil {
+			res = append(res, menuItemProps{id, filterProps(item.props(), names)})
+		}
+	}
+	return res, nil
+}
+
+func (m *menuObject) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	m.i.mutex.Lock()
+	defer m.i.mutex.Unlock()
+	if item := m.i.menuItem(id); item != nil {
+		if v, ok := item.props()[name]; ok {
+			return v, nil
+		}
+	}
+	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("no property %s in menu item %d", name, id))
+}
+
+func (m *menuObject) Event(id int32, eventID string, _ dbus.Variant, _ uint32) *dbus.Error {
+	if !m.i.menuEvent(id, eventID) {
+		return dbus.MakeFailedError(fmt.Errorf("no menu item with ID %d", id))
+	}
+	return nil
+}
+
+func (m *menuObject) EventGroup(events []menuEvent) ([]int32, *dbus.Error) {
+	idErrors := []int32{}
+	for _, e := range events {
+		if !m.i.menuEvent(e.ID, e.EventID) {
+			idErrors = append(idErrors, e.ID)
+		}
+	}
+	return idErrors, nil
+}
+
+func (m *menuObject) AboutToShow(int32) (bool, *dbus.Error) {
+	// The layout is always up to date
+	return false, nil
+}
+
+func (m *menuObject) AboutToShowGroup([]int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
+
+// menuItem returns the menu item with the given ID, or nil if there's none. Must be called with the mutex locked
+func (i *Item) menuItem(id int32) *MenuItem {
+	if id < 1 || int(id) > len(i.menu) {
+		return nil
+	}
+	return &i.menu[id-1]
+}
+
+// menuItemLayout returns the layout of the menu item with the given ID. Must be called with the mutex locked
+func (i *Item) menuItemLayout(id int32, names []string) menuLayout {
+	return menuLayout{ID: id, Properties: filterProps(i.menuItem(id).props(), names), Children: []dbus.Variant{}}
+}
+
+// menuEvent handles an event of the menu item with the given ID, and returns whether the item exists
+func (i *Item) menuEvent(id int32, eventID string) bool {
+	i.mutex.Lock()
+	item := i.menuItem(id)
+	var onClick func()
+	if item != nil && !item.Disabled {
+		onClick = item.OnClick
+	}
+	i.mutex.Unlock()
+	if item == nil && id != menuRootID {
+		return false
+	}
+	if eventID == menuClicked && onClick != nil {
+		onClick()
+	}
+	return true
+}
+
+// props returns the dbusmenu properties of the item
+func (item *MenuItem) props() map[string]dbus.Variant {
+	if item.Separator {
+		return map[string]dbus.Variant{"type": dbus.MakeVariant("separator")}
+	}
+	p := map[string]dbus.Variant{"label": dbus.MakeVariant(item.Label)}
+	if item.IconName != "" {
+		p["icon-name"] = dbus.MakeVariant(item.IconName)
+	}
+	if item.Disabled {
+		p["enabled"] = dbus.MakeVariant(false)
+	}
+	return p
+}
+
+// filterProps returns the given properties limited to the given names. No names means all properties
+func filterProps(props map[string]dbus.Variant, names []string) map[string]dbus.Variant {
+	if len(names) == 0 {
+		return props
+	}
+	res := make(map[string]dbus.Variant, len(names))
+	for _, name := range names {
+		if v, ok := props[name]; ok {
+			res[name] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,278 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tray
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/yktoo/ymuse/internal/dbustest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// watcher is a stand-in for a StatusNotifierWatcher
+type watcher struct {
+	conn       *dbus.Conn
+	registered chan string
+}
+
+func (w *watcher) RegisterStatusNotifierItem(service string) *dbus.Error {
+	w.registered <- service
+	return nil
+}
+
+// startWatcher puts a stand-in watcher on the bus
+func startWatcher(t *testing.T, conn *dbus.Conn) *watcher {
+	t.Helper()
+	w := &watcher{conn: conn, registered: make(chan string, 10)}
+	if err := conn.Export(w, watcherPath, watcherName); err != nil {
+		t.Fatal(err)
+	}
+	if reply, err := conn.RequestName(watcherName, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName() = %v, %v", reply, err)
+	}
+	return w
+}
+
+// expectRegistered waits for the watcher to get the item registered
+func (w *watcher) expectRegistered(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-w.registered:
+		if got != want {
+			t.Errorf("registered service = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("item not registered")
+	}
+}
+
+func TestItem(t *testing.T) {
+	connect := dbustest.StartBus(t)
+	w := startWatcher(t, connect())
+	activated := make(chan bool, 10)
+	scrolled := make(chan int, 10)
+	i, err := newItem(connect(), "ymuse", "Ymuse", "ymuse", func() { activated <- true }, func(delta int) { scrolled <- delta })
+	if err != nil {
+		t.Fatalf("newItem() error = %v", err)
+	}
+	defer i.Close()
+	w.expectRegistered(t, i.busName)
+
+	// Properties are served
+	client := connect()
+	obj := client.Object(i.busName, itemPath)
+	var props map[string]dbus.Variant
+	if err := obj.Call(propsIface+".GetAll", 0, itemIface).Store(&props); err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	for name, want := range map[string]interface{}{
+		"Id":         "ymuse",
+		"Title":      "Ymuse",
+		"IconName":   "ymuse",
+		"Status":     itemStatus,
+		"ItemIsMenu": false,
+		"Menu":       menuPath,
+	} {
+		if got := props[name].Value(); got != want {
+			t.Errorf("property %s = %v, want %v", name, got, want)
+		}
+	}
+
+	// Tooltip updates are announced once
+	if err := client.AddMatchSignal(dbus.WithMatchSender(i.busName)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	client.Signal(signals)
+	i.SetToolTip("Song", "by <b>Artist</b>")
+	i.SetToolTip("Song", "by <b>Artist</b>")
+	expectSignal(t, signals, itemIface+".NewToolTip")
+	v, err := obj.GetProperty(itemIface + ".ToolTip")
+	if err != nil {
+		t.Fatalf("GetProperty() error = %v", err)
+	}
+	if tip := v.Value().([]interface{}); tip[2] != "Song" || tip[3] != "by <b>Artist</b>" {
+		t.Errorf("ToolTip = %v", tip)
+	}
+
+	// Clicks and vertical scrolls are passed on
+	if err := obj.Call(itemIface+".Activate", 0, int32(10), int32(20)).Err; err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	for _, orientation := range []string{"horizontal", "vertical"} {
+		if err := obj.Call(itemIface+".Scroll", 0, int32(-120), orientation).Err; err != nil {
+			t.Fatalf("Scroll() error = %v", err)
+		}
+	}
+	if !<-activated {
+		t.Error("item not activated")
+	}
+	if delta := <-scrolled; delta != -120 {
+		t.Errorf("scroll delta = %d, want -120", delta)
+	}
+	if len(scrolled) != 0 {
+		t.Error("horizontal scroll passed on")
+	}
+
+	// The item registers with a restarted watcher
+	if _, err := w.conn.ReleaseName(watcherName); err != nil {
+		t.Fatal(err)
+	}
+	w2 := startWatcher(t, connect())
+	w2.expectRegistered(t, i.busName)
+	expectNoSignal(t, signals)
+}
+
+func TestItem_Menu(t *testing.T) {
+	connect := dbustest.StartBus(t)
+	w := startWatcher(t, connect())
+	i, err := newItem(connect(), "ymuse", "Ymuse", "ymuse", nil, nil)
+	if err != nil {
+		t.Fatalf("newItem() error = %v", err)
+	}
+	defer i.Close()
+	w.expectRegistered(t, i.busName)
+
+	client := connect()
+	if err := client.AddMatchSignal(dbus.WithMatchSender(i.busName)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	client.Signal(signals)
+
+	var mutex sync.Mutex
+	var clicked []string
+	click := func(name string) func() {
+		return func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			clicked = append(clicked, name)
+		}
+	}
+	items := []MenuItem{
+		{Label: "_Play", IconName: "media-playback-start", OnClick: click("play")},
+		{Separator: true},
+		{Label: "_Next", Disabled: true, OnClick: click("next")},
+	}
+	i.SetMenu(items)
+	expectSignal(t, signals, menuIface+".LayoutUpdated")
+
+	// The same menu again doesn't change the layout
+	i.SetMenu(items)
+	expectNoSignal(t, signals)
+
+	// Fetch the layout
+	obj := client.Object(i.busName, menuPath)
+	var revision uint32
+	var layout menuLayout
+	if err := obj.Call(menuIface+".GetLayout", 0, int32(0), int32(-1), []string{}).Store(&revision, &layout); err != nil {
+		t.Fatalf("GetLayout() error = %v", err)
+	}
+	if revision != 2 || layout.ID != 0 || len(layout.Children) != 3 {
+		t.Fatalf("GetLayout() = %d, %+v", revision, layout)
+	}
+	var got []map[string]interface{}
+	for _, c := range layout.Children {
+		var child menuLayout
+		if err := dbus.Store([]interface{}{c.Value()}, &child); err != nil {
+			t.Fatal(err)
+		}
+		p := map[string]interface{}{}
+		for name, v := range child.Properties {
+			p[name] = v.Value()
+		}
+		got = append(got, p)
+	}
+	want := []map[string]interface{}{
+		{"label": "_Play", "icon-name": "media-playback-start"},
+		{"type": "separator"},
+		{"label": "_Next", "enabled": false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("menu items = %v, want %v", got, want)
+	}
+
+	// Properties are limited to the requested ones
+	var groups []menuItemProps
+	if err := obj.Call(menuIface+".GetGroupProperties", 0, []int32{1, 5}, []string{"icon-name"}).Store(&groups); err != nil {
+		t.Fatalf("GetGroupProperties() error = %v", err)
+	}
+	if len(groups) != 1 || groups[0].ID != 1 || len(groups[0].Properties) != 1 ||
+		groups[0].Properties["icon-name"].Value() != "media-playback-start" {
+		t.Errorf("GetGroupProperties() = %+v", groups)
+	}
+
+	// Clicks on enabled items invoke their callbacks
+	for _, id := range []int32{3, 1} {
+		if err := obj.Call(menuIface+".Event", 0, int32(id), menuClicked, dbus.MakeVariant(""), uint32(0)).Err; err != nil {
+			t.Fatalf("Event() error = %v", err)
+		}
+	}
+	if err := obj.Call(menuIface+".Event", 0, int32(9), menuClicked, dbus.MakeVariant(""), uint32(0)).Err; err == nil {
+		t.Error("Event() on a missing item succeeded")
+	}
+	var idErrors []int32
+	err = obj.Call(menuIface+".EventGroup", 0, []menuEvent{{1, "hovered", dbus.MakeVariant(""), 0}, {7, menuClicked, dbus.MakeVariant(""), 0}}).
+		Store(&idErrors)
+	if err != nil || !reflect.DeepEqual(idErrors, []int32{7}) {
+		t.Errorf("EventGroup() = %v, %v, want [7]", idErrors, err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !reflect.DeepEqual(clicked, []string{"play"}) {
+		t.Errorf("clicked = %v, want [play]", clicked)
+	}
+}
+
+func TestNewItem_NoWatcher(t *testing.T) {
+	connect := dbustest.StartBus(t)
+	if _, err := newItem(connect(), "ymuse", "Ymuse", "ymuse", nil, nil); err == nil {
+		t.Error("newItem() without a watcher succeeded")
+	}
+}
+
+// expectSignal waits for a signal with the given name
+func expectSignal(t *testing.T, signals chan *dbus.Signal, name string) {
+	t.Helper()
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Name == name {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("signal %s not received", name)
+		}
+	}
+}
+
+// expectNoSignal makes sure no item signal arrives for a while
+func expectNoSignal(t *testing.T, signals chan *dbus.Signal) {
+	t.Helper()
+	for {
+		select {
+		case sig := <-signals:
+			if strings.HasPrefix(sig.Name, itemIface) || strings.HasPrefix(sig.Name, menuIface) {
+				t.Errorf("unexpected signal %s", sig.Name)
+			}
+		case <-time.After(200 * time.Millisecond):
+			return
+		}
+	}
+}
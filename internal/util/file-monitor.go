@@ -0,0 +1,58 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+// #cgo pkg-config: gio-2.0
+// #include <gio/gio.h>
+import "C"
+
+import (
+	"errors"
+	"github.com/gotk3/gotk3/glib"
+	"unsafe"
+)
+
+// FileMonitor watches a file for changes. It's a wrapper around GFileMonitor, which gotk3 lacks
+type FileMonitor struct {
+	*glib.Object
+}
+
+// MonitorFile starts watching the file with the given path, which doesn't need to exist. onChange is called on the GTK
+// main loop whenever the file is changed, created, deleted or renamed; a single update usually results in several calls
+func MonitorFile(path string, onChange func()) (*FileMonitor, error) {
+	file, err := glib.FileNewForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a monitor
+	var gErr *C.GError
+	c := C.g_file_monitor_file((*C.GFile)(unsafe.Pointer(file.GObject)), C.G_FILE_MONITOR_WATCH_MOVES, nil, &gErr)
+	if c == nil {
+		defer C.g_error_free(gErr)
+		return nil, errors.New(C.GoString((*C.char)(gErr.message)))
+	}
+	m := &FileMonitor{glib.AssumeOwnership(unsafe.Pointer(c))}
+
+	// The handler doesn't need any of the signal's arguments
+	m.Connect("changed", onChange)
+	return m, nil
+}
+
+// Cancel stops watching the file
+func (m *FileMonitor) Cancel() {
+	C.g_file_monitor_cancel((*C.GFileMonitor)(unsafe.Pointer(m.GObject)))
+}
@@ -0,0 +1,32 @@
+/*
+ *   Copyright 2026 Dmitry Kann
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+// #cgo pkg-config: glib-2.0
+// #include <stdlib.h>
+// #include <glib.h>
+import "C"
+
+import "unsafe"
+
+// LocalN localizes a string with a count using gettext, picking the plural form matching n. It's a wrapper around
+// g_dngettext(), which gotk3 lacks
+func LocalN(singular, plural string, n int) string {
+	cSingular, cPlural := (*C.gchar)(C.CString(singular)), (*C.gchar)(C.CString(plural))
+	defer C.free(unsafe.Pointer(cSingular))
+	defer C.free(unsafe.Pointer(cPlural))
+	return C.GoString((*C.char)(C.g_dngettext(nil, cSingular, cPlural, C.gulong(n))))
+}
@@ -17,6 +17,7 @@ package util
 
 import (
 	"fmt"
+	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/gotk3/gotk3/pango"
 	"html"
@@ -78,6 +79,26 @@ func NewBoxToggleButton(box *gtk.Box, label, name, icon string, active bool, onC
 	return btn
 }
 
+// NewPixbufScaled decodes the given image data and scales it down so that it fits into a square of the given size,
+// keeping the aspect ratio
+func NewPixbufScaled(data []byte, size int) (*gdk.Pixbuf, error) {
+	px, err := gdk.PixbufNewFromBytesOnly(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the required dimensions, keeping the aspect ratio
+	aspect, iw, ih := float64(px.GetWidth())/float64(px.GetHeight()), float64(size), float64(size)
+	if aspect > 1 {
+		ih /= aspect
+	} else {
+		iw *= aspect
+	}
+
+	// Rescale the image
+	return px.ScaleSimple(int(iw), int(ih), gdk.INTERP_BILINEAR)
+}
+
 // NewLabel instantiates and returns a new label
 func NewLabel(label string) *gtk.Label {
 	lbl, err := gtk.LabelNew(label)
@@ -167,6 +188,76 @@ func ConfirmDialog(parent gtk.IWindow, title, text string) bool {
 	return dlg.Run() == gtk.RESPONSE_OK
 }
 
+// FileFilterSpec describes a file type filter of a file chooser dialog
+type FileFilterSpec struct {
+	Name      string   // Filter's display name
+	Patterns  []string // Glob patterns of the file names to display, such as "*.txt"
+	MimeTypes []string // MIME types of the files to display, such as "image/*"
+}
+
+// newFileChooserDialog creates a file chooser dialog with the given filters
+func newFileChooserDialog(parent gtk.IWindow, title string, action gtk.FileChooserAction, okButton string, filters []FileFilterSpec) (*gtk.FileChooserDialog, error) {
+	dlg, err := gtk.FileChooserDialogNewWith2Buttons(title, parent, action, "Cancel", gtk.RESPONSE_CANCEL, okButton, gtk.RESPONSE_ACCEPT)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range filters {
+		filter, err := gtk.FileFilterNew()
+		if err != nil {
+			dlg.Destroy()
+			return nil, err
+		}
+		filter.SetName(spec.Name)
+		for _, p := range spec.Patterns {
+			filter.AddPattern(p)
+		}
+		for _, t := range spec.MimeTypes {
+			filter.AddMimeType(t)
+		}
+		dlg.AddFilter(filter)
+	}
+	dlg.SetDefaultResponse(gtk.RESPONSE_ACCEPT)
+	return dlg, nil
+}
+
+// OpenFilesDialog shows a dialog for picking one or more existing files, and returns their names
+func OpenFilesDialog(parent gtk.IWindow, title, okButton string, filters []FileFilterSpec) ([]string, bool) {
+	dlg, err := newFileChooserDialog(parent, title, gtk.FILE_CHOOSER_ACTION_OPEN, okButton, filters)
+	if errCheck(err, "newFileChooserDialog() failed") {
+		return nil, false
+	}
+	defer dlg.Destroy()
+	dlg.SetSelectMultiple(true)
+
+	// Run the dialog and check the response
+	if dlg.Run() != gtk.RESPONSE_ACCEPT {
+		return nil, false
+	}
+	names, err := dlg.GetFilenames()
+	if errCheck(err, "GetFilenames() failed") {
+		return nil, false
+	}
+	return names, len(names) > 0
+}
+
+// SaveFileDialog shows a dialog for picking a file to save to, and returns its name
+func SaveFileDialog(parent gtk.IWindow, title, name, okButton string, filters []FileFilterSpec) (string, bool) {
+	dlg, err := newFileChooserDialog(parent, title, gtk.FILE_CHOOSER_ACTION_SAVE, okButton, filters)
+	if errCheck(err, "newFileChooserDialog() failed") {
+		return "", false
+	}
+	defer dlg.Destroy()
+	dlg.SetCurrentName(name)
+	dlg.SetDoOverwriteConfirmation(true)
+
+	// Run the dialog and check the response
+	if dlg.Run() != gtk.RESPONSE_ACCEPT {
+		return "", false
+	}
+	fileName := dlg.GetFilename()
+	return fileName, fileName != ""
+}
+
 // GetTextBufferText returns the entire text stored in a text buffer
 func GetTextBufferText(buf *gtk.TextBuffer) (string, error) {
 	start, end := buf.GetBounds()
@@ -249,3 +340,179 @@ func ErrorDialog(parent gtk.IWindow, text string) {
 	defer dlg.Destroy()
 	dlg.Run()
 }
+
+// InfoDialog shows an information message dialog
+func InfoDialog(parent gtk.IWindow, text string) {
+	dlg := gtk.MessageDialogNew(parent, gtk.DIALOG_MODAL, gtk.MESSAGE_INFO, gtk.BUTTONS_OK, text)
+	defer dlg.Destroy()
+	dlg.Run()
+}
+
+// PasswordDialog shows a dialog asking for a password, with the given explanatory text
+func PasswordDialog(parent gtk.IWindow, title, text, okButton string) (string, bool) {
+	// Create a dialog
+	dlg, err := gtk.DialogNewWithButtons(
+		title,
+		parent,
+		gtk.DIALOG_MODAL,
+		[]interface{}{okButton, gtk.RESPONSE_OK},
+		[]interface{}{"Cancel", gtk.RESPONSE_CANCEL})
+	if errCheck(err, "DialogNewWithButtons() failed") {
+		return "", false
+	}
+	defer dlg.Destroy()
+
+	// Obtain the dialog's content area
+	bx, err := dlg.GetContentArea()
+	if errCheck(err, "GetContentArea() failed") {
+		return "", false
+	}
+	bx.SetSpacing(6)
+
+	// Add a label with the text
+	label, err := gtk.LabelNew(text)
+	if errCheck(err, "LabelNew() failed") {
+		return "", false
+	}
+	label.SetLineWrap(true)
+	label.SetMaxWidthChars(50)
+	label.SetXAlign(0)
+	label.SetMarginStart(12)
+	label.SetMarginEnd(12)
+	label.SetMarginTop(12)
+	bx.Add(label)
+
+	// Add a masked entry activating the OK button
+	entry, err := gtk.EntryNew()
+	if errCheck(err, "EntryNew() failed") {
+		return "", false
+	}
+	entry.SetSizeRequest(400, -1)
+	entry.SetVisibility(false)
+	entry.SetInputPurpose(gtk.INPUT_PURPOSE_PASSWORD)
+	entry.SetActivatesDefault(true)
+	entry.SetMarginStart(12)
+	entry.SetMarginEnd(12)
+	entry.SetMarginBottom(12)
+	entry.GrabFocus()
+	bx.Add(entry)
+
+	bx.ShowAll()
+	dlg.SetDefaultResponse(gtk.RESPONSE_OK)
+
+	// Run the dialog
+	response := dlg.Run()
+	value, err := entry.GetText()
+	if errCheck(err, "entry.GetText() failed") {
+		return "", false
+	}
+
+	// Check the response
+	if response == gtk.RESPONSE_OK {
+		return value, true
+	}
+	return "", false
+}
+
+// SelectDialog shows a dialog allowing to pick one of the given options from a drop-down list
+func SelectDialog(parent gtk.IWindow, title, text string, options []string, okButton string) (string, bool) {
+	// Create a dialog
+	dlg, err := gtk.DialogNewWithButtons(
+		title,
+		parent,
+		gtk.DIALOG_MODAL,
+		[]interface{}{okButton, gtk.RESPONSE_OK},
+		[]interface{}{"Cancel", gtk.RESPONSE_CANCEL})
+	if errCheck(err, "DialogNewWithButtons() failed") {
+		return "", false
+	}
+	defer dlg.Destroy()
+
+	// Obtain the dialog's content area
+	bx, err := dlg.GetContentArea()
+	if errCheck(err, "GetContentArea() failed") {
+		return "", false
+	}
+	bx.SetSpacing(6)
+
+	// Add a label and a combo box to the dialog
+	lbl := NewLabel(text)
+	lbl.SetMarginStart(12)
+	lbl.SetMarginEnd(12)
+	lbl.SetMarginTop(12)
+	bx.Add(lbl)
+	combo, err := gtk.ComboBoxTextNew()
+	if errCheck(err, "ComboBoxTextNew() failed") {
+		return "", false
+	}
+	for _, s := range options {
+		combo.AppendText(s)
+	}
+	combo.SetActive(0)
+	combo.SetSizeRequest(400, -1)
+	combo.SetMarginStart(12)
+	combo.SetMarginEnd(12)
+	combo.SetMarginBottom(12)
+	bx.Add(combo)
+
+	bx.ShowAll()
+	dlg.SetDefaultResponse(gtk.RESPONSE_OK)
+
+	// Run the dialog and check the response
+	if dlg.Run() != gtk.RESPONSE_OK || len(options) == 0 {
+		return "", false
+	}
+	return combo.GetActiveText(), true
+}
+
+// TextDialog shows a dialog displaying the given (possibly long) read-only text
+func TextDialog(parent gtk.IWindow, title, text string) {
+	// Create a dialog
+	dlg, err := gtk.DialogNewWithButtons(title, parent, gtk.DIALOG_MODAL, []interface{}{"Close", gtk.RESPONSE_CLOSE})
+	if errCheck(err, "DialogNewWithButtons() failed") {
+		return
+	}
+	defer dlg.Destroy()
+
+	// Obtain the dialog's content area
+	bx, err := dlg.GetContentArea()
+	if errCheck(err, "GetContentArea() failed") {
+		return
+	}
+
+	// Add a scrollable text view to the dialog
+	sw, err := gtk.ScrolledWindowNew(nil, nil)
+	if errCheck(err, "ScrolledWindowNew() failed") {
+		return
+	}
+	sw.SetSizeRequest(600, 400)
+	sw.SetVExpand(true)
+	sw.SetShadowType(gtk.SHADOW_IN)
+	sw.SetMarginStart(12)
+	sw.SetMarginEnd(12)
+	sw.SetMarginTop(12)
+	sw.SetMarginBottom(12)
+	tv, err := gtk.TextViewNew()
+	if errCheck(err, "TextViewNew() failed") {
+		return
+	}
+	tv.SetEditable(false)
+	tv.SetCursorVisible(false)
+	tv.SetLeftMargin(6)
+	tv.SetRightMargin(6)
+	if buf, err := tv.GetBuffer(); !errCheck(err, "GetBuffer() failed") {
+		buf.SetText(text)
+	}
+	sw.Add(tv)
+	bx.Add(sw)
+
+	bx.ShowAll()
+	dlg.Run()
+}
+
+// WarningDialog shows a warning message dialog
+func WarningDialog(parent gtk.IWindow, text string) {
+	dlg := gtk.MessageDialogNew(parent, gtk.DIALOG_MODAL, gtk.MESSAGE_WARNING, gtk.BUTTONS_OK, "%s", text)
+	defer dlg.Destroy()
+	dlg.Run()
+}
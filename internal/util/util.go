@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -70,6 +71,12 @@ func FormatSeconds(seconds float64) string {
 	}
 }
 
+// FormatHoursMinutes formats a number of seconds as hours and minutes ("HH:MM"), which suits total durations
+func FormatHoursMinutes(seconds float64) string {
+	minutes := int(seconds) / 60
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
 // FormatSecondsStr formats a number seconds as a string given string input
 func FormatSecondsStr(seconds string) string {
 	if f := ParseFloatDef(seconds, -1); f >= 0 {
@@ -78,6 +85,15 @@ func FormatSecondsStr(seconds string) string {
 	return ""
 }
 
+// SecondsToDuration converts a string holding a (fractional) number of seconds into a duration, returning 0 if
+// conversion failed or the number is negative
+func SecondsToDuration(seconds string) time.Duration {
+	if f, err := strconv.ParseFloat(seconds, 64); err == nil && f > 0 {
+		return time.Duration(f * float64(time.Second))
+	}
+	return 0
+}
+
 // Default returns a default value if no value is set
 func Default(def string, value interface{}) string {
 	if set, ok := template.IsTrue(value); ok && set {
@@ -86,6 +102,18 @@ func Default(def string, value interface{}) string {
 	return def
 }
 
+// IndexLetter returns the upper-case Latin letter the given string starts with (ignoring leading spaces), or an empty
+// string if it starts with anything else
+func IndexLetter(s string) string {
+	if s = strings.TrimLeft(s, " "); s == "" {
+		return ""
+	}
+	if c := s[0] &^ 0x20; c >= 'A' && c <= 'Z' {
+		return string(c)
+	}
+	return ""
+}
+
 // IsStreamURI returns whether the given URI refers to an Internet stream
 func IsStreamURI(uri string) bool {
 	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
@@ -100,6 +128,29 @@ func MapAttrsToSlice(attrs []mpd.Attrs, attr string) []string {
 	return r
 }
 
+// SplitList splits a comma-separated list, trimming the items and dropping empty and repeated ones
+func SplitList(s string) []string {
+	var r []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" && !seen[item] {
+			seen[item] = true
+			r = append(r, item)
+		}
+	}
+	return r
+}
+
+// VersionAtLeast returns whether the given dotted version string (such as "0.23.5") is at least major.minor
+func VersionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	vMajor, vMinor := AtoiDef(parts[0], -1), AtoiDef(parts[1], -1)
+	return vMajor > major || vMajor == major && vMinor >= minor
+}
+
 func MaxInt(a, b int) int {
 	if a < b {
 		return b
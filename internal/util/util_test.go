@@ -21,6 +21,7 @@ import (
 	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestAtoiDef(t *testing.T) {
@@ -75,6 +76,30 @@ func TestFormatSeconds(t *testing.T) {
 	}
 }
 
+func TestFormatHoursMinutes(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{-1, "00:00"},
+		{0, "00:00"},
+		{59.9, "00:00"},
+		{60, "00:01"},
+		{3599, "00:59"},
+		{3600, "01:00"},
+		{7530, "02:05"},
+		{90061, "25:01"},
+		{360000, "100:00"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v", tt.seconds), func(t *testing.T) {
+			if got := FormatHoursMinutes(tt.seconds); got != tt.want {
+				t.Errorf("FormatHoursMinutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatSecondsStr(t *testing.T) {
 	type args struct {
 		seconds string
@@ -130,6 +155,27 @@ func TestParseFloatDef(t *testing.T) {
 	}
 }
 
+func TestSecondsToDuration(t *testing.T) {
+	tests := []struct {
+		seconds string
+		want    time.Duration
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"-3", 0},
+		{"0", 0},
+		{"42", 42 * time.Second},
+		{"215.125", 215125 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.seconds, func(t *testing.T) {
+			if got := SecondsToDuration(tt.seconds); got != tt.want {
+				t.Errorf("SecondsToDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDefault(t *testing.T) {
 	type args struct {
 		def   string
@@ -163,6 +209,30 @@ func TestDefault(t *testing.T) {
 	}
 }
 
+func TestIndexLetter(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", ""},
+		{"upper case", "Zappa", "Z"},
+		{"lower case", "abba", "A"},
+		{"leading spaces", "  beatles", "B"},
+		{"digit", "10cc", ""},
+		{"punctuation", "(Hed) P.E.", ""},
+		{"non-Latin", "Ария", ""},
+		{"accented", "Édith Piaf", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IndexLetter(tt.s); got != tt.want {
+				t.Errorf("IndexLetter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsStreamURI(t *testing.T) {
 	tests := []struct {
 		name string
@@ -250,3 +320,49 @@ func TestMaxInt(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{" , ,", nil},
+		{"jazz", []string{"jazz"}},
+		{" jazz ,news,, talk ", []string{"jazz", "news", "talk"}},
+		{"jazz, news, jazz", []string{"jazz", "news"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := SplitList(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		major   int
+		minor   int
+		want    bool
+	}{
+		{"", 0, 24, false},
+		{"foo", 0, 24, false},
+		{"0", 0, 0, false},
+		{"0.23.5", 0, 24, false},
+		{"0.24", 0, 24, true},
+		{"0.24.0", 0, 24, true},
+		{"0.25.1", 0, 24, true},
+		{"1.0.0", 0, 24, true},
+		{"0.x.0", 0, 24, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q vs %d.%d", tt.version, tt.major, tt.minor), func(t *testing.T) {
+			if got := VersionAtLeast(tt.version, tt.major, tt.minor); got != tt.want {
+				t.Errorf("VersionAtLeast() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}